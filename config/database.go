@@ -37,6 +37,13 @@ func ConnectDatabase() {
 	// Set dulu DB sebelum digunakan
 	DB = database
 
+	// Tanggal pembayaran lama masih berupa teks
+	for _, column := range []string{"date", "due_date"} {
+		if err := migrateDateColumn("payments", column); err != nil {
+			log.Fatal("❌ Failed to migrate payments.", column, ": ", err)
+		}
+	}
+
 	// Sekarang AutoMigrate aman
 	err = DB.AutoMigrate(
 		&models.User{},
@@ -48,10 +55,108 @@ func ConnectDatabase() {
 		&models.Training{},
 		&models.Match{},
 		&models.EventLog{},
+		&models.PaymentStatusHistory{},
+		&models.PaymentCheckout{},
+		&models.InvoiceSequence{},
+		&models.Notification{},
+		&models.PaymentReminder{},
+		&models.BankStatementImport{},
+		&models.BankTransaction{},
+		&models.PaymentTransaction{},
+		&models.AccountCredit{},
+		&models.PricingRule{},
+		&models.Scholarship{},
+		&models.Family{},
+		&models.PromoCode{},
+		&models.PaymentLineItem{},
+		&models.Refund{},
+		&models.CreditNoteSequence{},
+		&models.RosterImport{},
+		&models.RosterImportRow{},
+		&models.UserInvitation{},
+		&models.EventSeries{},
+		&models.EventSeriesException{},
+		&models.CalendarFeed{},
+		&models.CheckInAttempt{},
+		&models.EventChange{},
+		&models.EventClosing{},
+		&models.Venue{},
+		&models.Pitch{},
+		&models.StaffAssignment{},
 	)
 	if err != nil {
 		log.Fatal("❌ AutoMigrate failed: ", err)
 	}
 
+	// Ubah status pembayaran lama ke lifecycle baru
+	DB.Exec(`UPDATE payments SET status = CASE status
+		WHEN 'pending' THEN 'issued'
+		WHEN 'success' THEN 'paid'
+		WHEN 'failed' THEN 'rejected'
+		ELSE 'issued' END
+		WHERE status NOT IN ('draft', 'issued', 'awaiting_verification', 'partially_paid', 'paid', 'rejected', 'refunded', 'cancelled', 'overdue')`)
+
+	// Tagihan lunas lama dicatat sebagai satu pembayaran penuh
+	DB.Exec(`INSERT INTO payment_transactions (created_at, updated_at, payment_id, vendor_id, user_id, amount, method, date, status, note)
+		SELECT NOW(), NOW(), id, vendor_id, user_id, amount, method, date, 'confirmed', 'Migrated from paid payment'
+		FROM payments WHERE status IN ('paid', 'refunded') AND paid_amount = 0 AND amount > 0 AND deleted_at IS NULL`)
+	DB.Exec(`UPDATE payments SET paid_amount = amount WHERE status IN ('paid', 'refunded') AND paid_amount = 0 AND amount > 0`)
+
+	// Tagihan lama belum punya harga dasar
+	DB.Exec(`UPDATE payments SET gross_amount = amount WHERE gross_amount = 0 AND amount > 0`)
+
+	// Pendaftaran event lama dianggap hadir (going)
+	DB.Exec(`UPDATE event_logs SET rsvp = 'going' WHERE rsvp IS NULL OR rsvp = ''`)
+
+	// Mutasi bank lama belum punya sidik jari untuk cek import ganda
+	var bankTxs []models.BankTransaction
+	DB.Unscoped().Where("fingerprint IS NULL OR fingerprint = ''").Find(&bankTxs)
+	for _, t := range bankTxs {
+		DB.Unscoped().Model(&t).UpdateColumn("fingerprint", models.BankTransactionFingerprint(t.Date, t.Amount, t.Description))
+	}
+
+	// Jadwal event dan match lama hanya berupa tanggal dan jam teks
+	migrateSchedules()
+
+	// Satu event per tanggal dalam series dan satu tagihan bulanan per pemain,
+	// vendor, event dan periode. Tanpa indeks ini expand series dan billing
+	// tidak lagi idempoten, jadi duplikat lama harus dibereskan dulu.
+	for _, stmt := range []string{
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_events_series_occurrence
+			ON events (series_id, occurrence_date) WHERE series_id IS NOT NULL`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_billing_period
+			ON payments (user_id, vendor_id, COALESCE(event_id, 0), type, billing_period)
+			WHERE billing_period <> '' AND deleted_at IS NULL`,
+	} {
+		if err := DB.Exec(stmt).Error; err != nil {
+			log.Fatal("❌ Failed to create unique index: ", err)
+		}
+	}
+
+	// Nomor invoice dan nota kredit tidak boleh dobel dalam satu vendor.
+	// Kode akademi di nomor bisa sama antar vendor, jadi indeks lama yang
+	// global diganti.
+	for _, stmt := range []string{
+		`DROP INDEX IF EXISTS idx_payments_invoice`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_vendor_invoice
+			ON payments (vendor_id, invoice) WHERE invoice <> ''`,
+		`DROP INDEX IF EXISTS idx_refunds_credit_note`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_refunds_vendor_credit_note
+			ON refunds (vendor_id, credit_note_number) WHERE credit_note_number <> ''`,
+	} {
+		if err := DB.Exec(stmt).Error; err != nil {
+			log.Fatal("❌ Failed to create invoice index: ", err)
+		}
+	}
+
+	// Laporan keuangan mengagregasi per vendor dan tanggal
+	DB.Exec(`CREATE INDEX IF NOT EXISTS idx_payments_vendor_date ON payments (vendor_id, date)`)
+	DB.Exec(`CREATE INDEX IF NOT EXISTS idx_payment_transactions_vendor_date ON payment_transactions (vendor_id, date)`)
+
+	// Filter vendor_id otomatis untuk tabel milik vendor
+	if err := RegisterTenantCallbacks(DB); err != nil {
+		log.Fatal("❌ Failed to register tenant callbacks: ", err)
+	}
+
 	fmt.Println("✅ Database connected and migrated!")
 }
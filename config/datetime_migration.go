@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"ssb_api/models"
+
+	"gorm.io/gorm"
+)
+
+// migrateDateColumn mengubah kolom tanggal teks lama menjadi DATE. Format
+// lama seperti DD/MM/YYYY diubah ke ISO dulu, nilai yang tidak terbaca
+// dikosongkan. Dijalankan sebelum AutoMigrate.
+func migrateDateColumn(table, column string) error {
+	var dataType string
+	DB.Raw(`SELECT data_type FROM information_schema.columns
+		WHERE table_schema = CURRENT_SCHEMA() AND table_name = ? AND column_name = ?`, table, column).Scan(&dataType)
+	if dataType != "text" {
+		return nil
+	}
+
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var rows []struct {
+			ID    uint
+			Value string
+		}
+		if err := tx.Raw(fmt.Sprintf(`SELECT id, %s AS value FROM %s WHERE %s <> ''`, column, table, column)).
+			Scan(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			date, err := models.ParseDate(row.Value)
+			if err != nil {
+				log.Printf("⚠️ %s.%s id %d: %v, dikosongkan", table, column, row.ID, err)
+			}
+			if date.String() == row.Value {
+				continue
+			}
+			if err := tx.Exec(fmt.Sprintf(`UPDATE %s SET %s = ? WHERE id = ?`, table, column), date.String(), row.ID).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Exec(fmt.Sprintf(`ALTER TABLE %s ALTER COLUMN %s TYPE date USING NULLIF(%s, '')::date`, table, column, column)).Error
+	})
+}
+
+// migrateSchedules mengisi start_at dan end_at event dan match lama dari
+// tanggal dan jam teks di zona waktu vendor. Jam yang tidak terbaca dianggap
+// tengah malam.
+func migrateSchedules() {
+	zones := map[uint]*time.Location{}
+	location := func(vendorID uint) *time.Location {
+		if loc, ok := zones[vendorID]; ok {
+			return loc
+		}
+		var vendor models.Vendor
+		DB.Unscoped().Select("id", "timezone").First(&vendor, vendorID)
+		zones[vendorID] = vendor.Location()
+		return zones[vendorID]
+	}
+
+	var events []models.Event
+	DB.Unscoped().Where("start_at IS NULL AND date <> ''").Find(&events)
+	for _, e := range events {
+		loc := location(e.VendorID)
+		start, err := models.ParseLocalDateTime(e.Date, e.Time, loc)
+		if err != nil {
+			start, err = models.ParseLocalDateTime(e.Date, "", loc)
+		}
+		if err != nil {
+			log.Printf("⚠️ event %d: jadwal %q %q tidak terbaca", e.ID, e.Date, e.Time)
+			continue
+		}
+		e.SetSchedule(start, start.Add(models.DefaultEventDuration), loc)
+		DB.Unscoped().Model(&e).UpdateColumns(map[string]interface{}{
+			"start_at": e.StartAt, "end_at": e.EndAt, "date": e.Date, "time": e.Time,
+		})
+	}
+
+	var matches []models.Match
+	DB.Unscoped().Where("start_at IS NULL AND date <> ''").Find(&matches)
+	for _, m := range matches {
+		loc := location(0)
+		if m.VendorID != nil {
+			loc = location(*m.VendorID)
+		}
+		start, err := models.ParseLocalDateTime(m.Date, "", loc)
+		if err != nil {
+			log.Printf("⚠️ match %d: tanggal %q tidak terbaca", m.ID, m.Date)
+			continue
+		}
+		m.SetSchedule(start, start.Add(models.DefaultEventDuration), loc)
+		DB.Unscoped().Model(&m).UpdateColumns(map[string]interface{}{
+			"start_at": m.StartAt, "end_at": m.EndAt, "date": m.Date,
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrMissingTenant = errors.New("tenant context is required to access vendor-owned data")
+	ErrCrossTenant   = errors.New("cross-vendor access rejected")
+)
+
+// VendorOwnedTables adalah tabel yang wajib difilter vendor_id. Tabel baru
+// yang memiliki kolom vendor_id cukup didaftarkan di sini.
+var VendorOwnedTables = map[string]bool{
+	"payments":                 true,
+	"payment_status_histories": true,
+	"payment_checkouts":        true,
+	"invoice_sequences":        true,
+	"payment_reminders":        true,
+	"bank_statement_imports":   true,
+	"bank_transactions":        true,
+	"payment_transactions":     true,
+	"account_credits":          true,
+	"pricing_rules":            true,
+	"scholarships":             true,
+	"families":                 true,
+	"promo_codes":              true,
+	"payment_line_items":       true,
+	"refunds":                  true,
+	"credit_note_sequences":    true,
+	"roster_imports":           true,
+	"roster_import_rows":       true,
+	"user_invitations":         true,
+	"event_series":             true,
+	"event_series_exceptions":  true,
+	"events":                   true,
+	"event_logs":               true,
+	"check_in_attempts":        true,
+	"event_changes":            true,
+	"event_closings":           true,
+	"venues":                   true,
+	"pitches":                  true,
+	"staff_assignments":        true,
+	"trainings":                true,
+	"matches":                  true,
+	"challenges":               true,
+	"challenge_logs":           true,
+}
+
+type tenantKey struct{}
+
+// Tenant adalah vendor pemilik request. Bypass hanya untuk platform admin
+// dan job internal.
+type Tenant struct {
+	VendorID uint
+	Bypass   bool
+}
+
+// WithTenant mengunci semua query di context ini ke satu vendor.
+func WithTenant(ctx context.Context, vendorID uint) context.Context {
+	return context.WithValue(ctx, tenantKey{}, Tenant{VendorID: vendorID})
+}
+
+// WithPlatformAdmin membebaskan query dari filter vendor.
+func WithPlatformAdmin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tenantKey{}, Tenant{Bypass: true})
+}
+
+// SystemContext dipakai job internal (scheduler, worker) yang memang
+// bekerja lintas vendor.
+func SystemContext() context.Context {
+	return WithPlatformAdmin(context.Background())
+}
+
+func TenantFromContext(ctx context.Context) (Tenant, bool) {
+	if ctx == nil {
+		return Tenant{}, false
+	}
+	t, ok := ctx.Value(tenantKey{}).(Tenant)
+	return t, ok
+}
+
+// RegisterTenantCallbacks memasang filter vendor_id otomatis untuk semua
+// query, update dan delete, serta menolak create ke vendor lain. Query tanpa
+// tenant di context akan gagal dengan ErrMissingTenant.
+func RegisterTenantCallbacks(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Query().Before("gorm:query").Register("tenant:query", tenantFilter); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("tenant:row", tenantFilter); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("tenant:delete", tenantFilter); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("tenant:update", tenantUpdate); err != nil {
+		return err
+	}
+	return cb.Create().Before("gorm:create").Register("tenant:create", tenantCreate)
+}
+
+// tenantOf mengembalikan tenant untuk statement yang menyentuh tabel milik
+// vendor. enforce bernilai false bila tabel bukan milik vendor atau tenant
+// adalah platform admin.
+func tenantOf(db *gorm.DB) (tenant Tenant, enforce bool) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || !VendorOwnedTables[stmt.Schema.Table] {
+		return Tenant{}, false
+	}
+
+	tenant, ok := TenantFromContext(stmt.Context)
+	if !ok {
+		db.AddError(ErrMissingTenant)
+		return Tenant{}, false
+	}
+	return tenant, !tenant.Bypass
+}
+
+func tenantFilter(db *gorm.DB) {
+	tenant, enforce := tenantOf(db)
+	if !enforce {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: "vendor_id"}, Value: tenant.VendorID},
+	}})
+}
+
+func tenantUpdate(db *gorm.DB) {
+	tenant, enforce := tenantOf(db)
+	if !enforce {
+		return
+	}
+
+	// Tolak perubahan vendor_id ke vendor lain
+	if dest, ok := db.Statement.Dest.(map[string]interface{}); ok {
+		for _, key := range []string{"vendor_id", "VendorID"} {
+			if v, exists := dest[key]; exists && !sameVendor(reflect.ValueOf(v), tenant.VendorID) {
+				db.AddError(ErrCrossTenant)
+				return
+			}
+		}
+	} else if vendorID, ok := rowVendorID(db, db.Statement.ReflectValue); ok && vendorID != tenant.VendorID {
+		db.AddError(ErrCrossTenant)
+		return
+	}
+
+	tenantFilter(db)
+}
+
+func tenantCreate(db *gorm.DB) {
+	tenant, enforce := tenantOf(db)
+	if !enforce {
+		return
+	}
+
+	// Save yang gagal update (baris milik vendor lain) akan jatuh ke upsert,
+	// jadi upsert ditolak untuk user biasa.
+	if _, upsert := db.Statement.Clauses["ON CONFLICT"]; upsert {
+		db.AddError(ErrCrossTenant)
+		return
+	}
+
+	rv := db.Statement.ReflectValue
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if vendorID, _ := rowVendorID(db, reflect.Indirect(rv.Index(i))); vendorID != tenant.VendorID {
+				db.AddError(ErrCrossTenant)
+				return
+			}
+		}
+	case reflect.Struct:
+		if vendorID, _ := rowVendorID(db, rv); vendorID != tenant.VendorID {
+			db.AddError(ErrCrossTenant)
+		}
+	}
+}
+
+// rowVendorID membaca kolom vendor_id dari satu baris model. ok bernilai
+// false bila kolom kosong.
+func rowVendorID(db *gorm.DB, rv reflect.Value) (uint, bool) {
+	field := db.Statement.Schema.LookUpField("vendor_id")
+	if field == nil || rv.Kind() != reflect.Struct {
+		return 0, false
+	}
+	value, zero := field.ValueOf(db.Statement.Context, rv)
+	if zero {
+		return 0, false
+	}
+	return vendorIDValue(reflect.ValueOf(value))
+}
+
+func sameVendor(v reflect.Value, vendorID uint) bool {
+	id, ok := vendorIDValue(v)
+	return ok && id == vendorID
+}
+
+func vendorIDValue(v reflect.Value) (uint, bool) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return 0, false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return uint(v.Uint()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint(v.Int()), true
+	}
+	return 0, false
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"ssb_api/models"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+// newTenantTestDB membuat koneksi DryRun: SQL dibangun lewat callback tenant
+// tanpa butuh database. Transaksi bawaan dimatikan karena BEGIN perlu koneksi.
+func newTenantTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=tenant_test"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("open dry-run db: %v", err)
+	}
+	if err := RegisterTenantCallbacks(db); err != nil {
+		t.Fatalf("register tenant callbacks: %v", err)
+	}
+	return db
+}
+
+func vendorPtr(id uint) *uint { return &id }
+
+// assertVendorFiltered memastikan statement dibatasi ke vendor tertentu.
+func assertVendorFiltered(t *testing.T, stmt *gorm.Statement, vendorID uint) {
+	t.Helper()
+	sql := stmt.SQL.String()
+	if !strings.Contains(sql, `"vendor_id" = `) {
+		t.Fatalf("expected vendor_id filter, got %s", sql)
+	}
+	for _, v := range stmt.Vars {
+		if id, ok := v.(uint); ok && id == vendorID {
+			return
+		}
+	}
+	t.Fatalf("expected vendor %d in vars %v for %s", vendorID, stmt.Vars, sql)
+}
+
+func TestTenantFiltersReadsAndWrites(t *testing.T) {
+	db := newTenantTestDB(t)
+	ctx := WithTenant(context.Background(), 7)
+
+	t.Run("query", func(t *testing.T) {
+		var payments []models.Payment
+		tx := db.WithContext(ctx).Where("status = ?", models.PaymentStatusPaid).Find(&payments)
+		if tx.Error != nil {
+			t.Fatal(tx.Error)
+		}
+		assertVendorFiltered(t, tx.Statement, 7)
+	})
+
+	t.Run("row", func(t *testing.T) {
+		tx := db.WithContext(ctx).Model(&models.Payment{}).Select("COUNT(*)")
+		tx.Row()
+		if tx.Error != nil {
+			t.Fatal(tx.Error)
+		}
+		assertVendorFiltered(t, tx.Statement, 7)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		tx := db.WithContext(ctx).Model(&models.Payment{}).Where("id = ?", 1).Update("status", models.PaymentStatusPaid)
+		if tx.Error != nil {
+			t.Fatal(tx.Error)
+		}
+		assertVendorFiltered(t, tx.Statement, 7)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		tx := db.WithContext(ctx).Delete(&models.Payment{}, 1)
+		if tx.Error != nil {
+			t.Fatal(tx.Error)
+		}
+		assertVendorFiltered(t, tx.Statement, 7)
+	})
+}
+
+func TestTenantRejectsCrossVendorWrites(t *testing.T) {
+	db := newTenantTestDB(t)
+	ctx := WithTenant(context.Background(), 7)
+
+	cases := []struct {
+		name string
+		run  func(tx *gorm.DB) error
+	}{
+		{"create other vendor", func(tx *gorm.DB) error {
+			return tx.Create(&models.Payment{VendorID: vendorPtr(8), Amount: 100}).Error
+		}},
+		{"create without vendor", func(tx *gorm.DB) error {
+			return tx.Create(&models.Payment{Amount: 100}).Error
+		}},
+		{"batch create with other vendor", func(tx *gorm.DB) error {
+			return tx.Create(&[]models.Payment{{VendorID: vendorPtr(7)}, {VendorID: vendorPtr(8)}}).Error
+		}},
+		{"upsert", func(tx *gorm.DB) error {
+			return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&models.Payment{VendorID: vendorPtr(7)}).Error
+		}},
+		{"save row of other vendor", func(tx *gorm.DB) error {
+			payment := models.Payment{VendorID: vendorPtr(8)}
+			payment.ID = 1
+			return tx.Save(&payment).Error
+		}},
+		{"move row to other vendor", func(tx *gorm.DB) error {
+			return tx.Model(&models.Payment{}).Where("id = ?", 1).Updates(map[string]interface{}{"vendor_id": uint(8)}).Error
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.run(db.WithContext(ctx)); !errors.Is(err, ErrCrossTenant) {
+				t.Fatalf("expected ErrCrossTenant, got %v", err)
+			}
+		})
+	}
+
+	if err := db.WithContext(ctx).Create(&models.Payment{VendorID: vendorPtr(7), Amount: 100}).Error; err != nil {
+		t.Fatalf("create in own vendor: %v", err)
+	}
+}
+
+func TestTenantRequiresContext(t *testing.T) {
+	db := newTenantTestDB(t)
+
+	var payments []models.Payment
+	if err := db.Find(&payments).Error; !errors.Is(err, ErrMissingTenant) {
+		t.Fatalf("query: expected ErrMissingTenant, got %v", err)
+	}
+	if err := db.Create(&models.Payment{VendorID: vendorPtr(7)}).Error; !errors.Is(err, ErrMissingTenant) {
+		t.Fatalf("create: expected ErrMissingTenant, got %v", err)
+	}
+
+	// Tabel yang bukan milik vendor tidak butuh tenant
+	var vendors []models.Vendor
+	if err := db.Find(&vendors).Error; err != nil {
+		t.Fatalf("vendors: %v", err)
+	}
+}
+
+func TestPlatformAdminBypassesTenant(t *testing.T) {
+	db := newTenantTestDB(t)
+	ctx := WithPlatformAdmin(context.Background())
+
+	var payments []models.Payment
+	tx := db.WithContext(ctx).Find(&payments)
+	if tx.Error != nil {
+		t.Fatal(tx.Error)
+	}
+	if sql := tx.Statement.SQL.String(); strings.Contains(sql, `"vendor_id"`) {
+		t.Fatalf("platform admin query should not be filtered: %s", sql)
+	}
+	if err := db.WithContext(ctx).Create(&models.Payment{VendorID: vendorPtr(8)}).Error; err != nil {
+		t.Fatalf("platform admin create: %v", err)
+	}
+}
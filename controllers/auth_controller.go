@@ -33,6 +33,14 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	// Registrasi publik hanya untuk pemain. Pelatih diundang admin lewat
+	// InviteCoach, admin dibuat manual.
+	if input.Role != "" && input.Role != models.RolePemain {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Public registration is for players only, coaches are invited by an admin")
+		return
+	}
+	input.Role = models.RolePemain
+
 	// Cek apakah email sudah terdaftar
 	var existingUser models.User
 	if err := config.DB.Where("email = ?", input.Email).First(&existingUser).Error; err == nil {
@@ -106,7 +114,7 @@ func Login(c *gin.Context) {
 	}
 
 	// ✅ Generate access + refresh token
-	accessToken, refreshToken, err := utils.GenerateTokens(user.ID, user.Email)
+	accessToken, refreshToken, err := utils.GenerateTokens(user.ID, user.Email, user.Role)
 	if err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to generate token")
 		return
@@ -162,8 +170,15 @@ func RefreshToken(c *gin.Context) {
 		return
 	}
 
+	// ✅ Ambil role terbaru dari database, jangan percaya claim lama
+	var user models.User
+	if err := config.DB.First(&user, uint(claims["user_id"].(float64))).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusUnauthorized, "User not found")
+		return
+	}
+
 	// ✅ Generate access token baru
-	accessToken, _, err := utils.GenerateTokens(uint(claims["user_id"].(float64)), claims["email"].(string))
+	accessToken, _, err := utils.GenerateTokens(user.ID, user.Email, user.Role)
 	if err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to generate access token")
 		return
@@ -242,7 +257,7 @@ func FirebaseLogin(c *gin.Context) {
 	}
 
 	// 🔹 Generate JWT
-	accessToken, refreshToken, err := utils.GenerateTokens(user.ID, user.Email)
+	accessToken, refreshToken, err := utils.GenerateTokens(user.ID, user.Email, user.Role)
 	if err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to create token")
 		return
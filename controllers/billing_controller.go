@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"net/http"
+	"ssb_api/models/response"
+	"ssb_api/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RunBilling menjalankan penagihan bulanan secara manual. Gunakan dry_run
+// untuk melihat tagihan yang akan dibuat tanpa menyimpannya.
+func RunBilling(c *gin.Context) {
+	var input struct {
+		Period   string `json:"period"` // format: YYYY-MM, default bulan ini
+		VendorID *uint  `json:"vendor_id"`
+		DryRun   bool   `json:"dry_run"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	period := time.Now()
+	if input.Period != "" {
+		parsed, err := time.ParseInLocation("2006-01", input.Period, time.Local)
+		if err != nil {
+			response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid period, use YYYY-MM")
+			return
+		}
+		period = parsed
+	}
+
+	if input.VendorID != nil && !requireVendorAccess(c, input.VendorID) {
+		return
+	}
+
+	result, err := utils.RunMonthlyBilling(tenantDB(c), period, input.VendorID, input.DryRun)
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to run billing")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, result)
+}
+
+// RunReminders menjalankan pengingat tagihan dan penandaan overdue secara
+// manual, di luar jadwal scheduler.
+func RunReminders(c *gin.Context) {
+	result, err := utils.RunPaymentReminders(tenantDB(c), time.Now())
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to run payment reminders")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, result)
+}
@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"ssb_api/config"
+	"ssb_api/controllers/middleware"
+	"ssb_api/models"
+	"ssb_api/models/response"
+	"ssb_api/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMyCalendarFeed menampilkan URL feed kalender event yang didaftari user
+// yang sedang login.
+func GetMyCalendarFeed(c *gin.Context) {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		response.JSONErrorResponse(c.Writer, false, http.StatusUnauthorized, "User not found")
+		return
+	}
+	feed, err := utils.UserCalendarFeed(tenantDB(c), user.ID)
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to load calendar feed")
+		return
+	}
+	response.JSONSuccess(c.Writer, true, http.StatusOK, calendarFeedResponse(c, feed))
+}
+
+// RotateMyCalendarFeed mengganti token feed user, URL lama berhenti bekerja.
+func RotateMyCalendarFeed(c *gin.Context) {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		response.JSONErrorResponse(c.Writer, false, http.StatusUnauthorized, "User not found")
+		return
+	}
+	db := tenantDB(c)
+	feed, err := utils.UserCalendarFeed(db, user.ID)
+	if err == nil {
+		err = utils.RotateCalendarFeed(db, &feed)
+	}
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to rotate calendar feed")
+		return
+	}
+	response.JSONSuccess(c.Writer, true, http.StatusOK, calendarFeedResponse(c, feed))
+}
+
+// GetVendorCalendarFeed menampilkan URL feed semua event publik vendor.
+func GetVendorCalendarFeed(c *gin.Context) {
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+	feed, err := utils.VendorCalendarFeed(tenantDB(c), vendorID)
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to load calendar feed")
+		return
+	}
+	response.JSONSuccess(c.Writer, true, http.StatusOK, calendarFeedResponse(c, feed))
+}
+
+// RotateVendorCalendarFeed mengganti token feed vendor.
+func RotateVendorCalendarFeed(c *gin.Context) {
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+	db := tenantDB(c)
+	feed, err := utils.VendorCalendarFeed(db, vendorID)
+	if err == nil {
+		err = utils.RotateCalendarFeed(db, &feed)
+	}
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to rotate calendar feed")
+		return
+	}
+	response.JSONSuccess(c.Writer, true, http.StatusOK, calendarFeedResponse(c, feed))
+}
+
+// GetCalendarFeed menyajikan feed .ics tanpa login. Token di URL adalah
+// satu-satunya kunci akses.
+func GetCalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("file"), ".ics")
+	db := config.DB.WithContext(config.SystemContext())
+
+	var feed models.CalendarFeed
+	if token == "" || db.Where("token = ?", token).First(&feed).Error != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Calendar feed not found")
+		return
+	}
+
+	var cal *utils.Calendar
+	var err error
+	switch {
+	case feed.UserID != nil:
+		var user models.User
+		if err := db.First(&user, *feed.UserID).Error; err != nil {
+			response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Calendar feed not found")
+			return
+		}
+		cal, err = utils.BuildUserCalendar(db, user, time.Now())
+	case feed.OwnerVendorID != nil:
+		var vendor models.Vendor
+		if err := db.First(&vendor, *feed.OwnerVendorID).Error; err != nil {
+			response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Calendar feed not found")
+			return
+		}
+		cal, err = utils.BuildVendorCalendar(db, vendor, time.Now())
+	default:
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Calendar feed not found")
+		return
+	}
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to build calendar feed")
+		return
+	}
+
+	c.Header("Content-Type", "text/calendar; charset=utf-8")
+	c.Header("Content-Disposition", "inline; filename=\"calendar.ics\"")
+	c.Header("Cache-Control", "private, max-age=900")
+	c.Status(http.StatusOK)
+	cal.WriteTo(c.Writer)
+}
+
+// calendarFeedResponse membentuk URL langganan dari host request, webcal://
+// langsung membuka aplikasi kalender.
+func calendarFeedResponse(c *gin.Context, feed models.CalendarFeed) gin.H {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	path := fmt.Sprintf("%s/api/calendar/%s.ics", c.Request.Host, feed.Token)
+	return gin.H{
+		"url":        scheme + "://" + path,
+		"webcal_url": "webcal://" + path,
+	}
+}
@@ -2,30 +2,34 @@ package controllers
 
 import (
 	"net/http"
-	"ssb_api/config"
+	"ssb_api/controllers/middleware"
 	"ssb_api/models"
 	"ssb_api/models/response"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
 // CreateChallenge handles the creation of a new challenge.
 func CreateChallenge(c *gin.Context) {
+	db := tenantDB(c)
 	var input models.Challenge
 	if err := c.ShouldBindJSON(&input); err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request")
 		return
 	}
 
+	if !requireVendorAccess(c, input.VendorID) {
+		return
+	}
+
 	// Optional: Validasi atau pengecekan tambahan jika dibutuhkan
 	// Example: check if Vendor exists
-	if err := config.DB.Where("id = ?", input.VendorID).First(&models.Vendor{}).Error; err != nil {
+	if err := db.Where("id = ?", input.VendorID).First(&models.Vendor{}).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Vendor not found")
 		return
 	}
 
-	if err := config.DB.Create(&input).Error; err != nil {
+	if err := db.Create(&input).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to create challenge")
 		return
 	}
@@ -35,8 +39,18 @@ func CreateChallenge(c *gin.Context) {
 
 // GetChallenges retrieves all the challenges.
 func GetChallenges(c *gin.Context) {
+	db := tenantDB(c)
+	vendorID, ok := vendorFilter(c)
+	if !ok {
+		return
+	}
+
 	var challenges []models.Challenge
-	if err := config.DB.Find(&challenges).Error; err != nil {
+	query := db.Model(&models.Challenge{})
+	if vendorID != nil {
+		query = query.Where("vendor_id = ?", *vendorID)
+	}
+	if err := query.Find(&challenges).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to fetch challenges")
 		return
 	}
@@ -44,6 +58,7 @@ func GetChallenges(c *gin.Context) {
 }
 
 func CreateChallengeLog(c *gin.Context) {
+	db := tenantDB(c)
 	var input models.ChallengeLog
 	if err := c.ShouldBindJSON(&input); err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request")
@@ -52,20 +67,25 @@ func CreateChallengeLog(c *gin.Context) {
 
 	// Cek user
 	var user models.User
-	if err := config.DB.First(&user, input.UserID).Error; err != nil {
+	if err := db.First(&user, input.UserID).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "User not found")
 		return
 	}
 
 	// Cek challenge
 	var challenge models.Challenge
-	if err := config.DB.First(&challenge, input.ChallengeID).Error; err != nil {
+	if err := db.First(&challenge, input.ChallengeID).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Challenge not found")
 		return
 	}
 
+	// Pelatih hanya boleh mencatat challenge di vendor miliknya
+	if !requireVendorAccess(c, challenge.VendorID) {
+		return
+	}
+
 	// Optional: validasi vendor cocok
-	if user.VendorID != challenge.VendorID {
+	if user.VendorID == nil || challenge.VendorID == nil || *user.VendorID != *challenge.VendorID {
 		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "User and challenge are not in the same vendor")
 		return
 	}
@@ -73,7 +93,7 @@ func CreateChallengeLog(c *gin.Context) {
 	// Set vendor ID ke log (jika model ChallengeLog punya field VendorID)
 	input.VendorID = challenge.VendorID
 
-	if err := config.DB.Create(&input).Error; err != nil {
+	if err := db.Create(&input).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to create challenge log")
 		return
 	}
@@ -84,7 +104,7 @@ func CreateChallengeLog(c *gin.Context) {
 // GetChallengeLogs retrieves all the challenge logs.
 // func GetChallengeLogs(c *gin.Context) {
 // 	var challengeLogs []models.ChallengeLog
-// 	if err := config.DB.Find(&challengeLogs).Error; err != nil {
+// 	if err := db.Find(&challengeLogs).Error; err != nil {
 // 		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to fetch challenge logs")
 // 		return
 // 	}
@@ -92,20 +112,14 @@ func CreateChallengeLog(c *gin.Context) {
 // }
 
 func GetChallengesByVendor(c *gin.Context) {
-	vendorIDStr := c.Query("vendor_id")
-	if vendorIDStr == "" {
-		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Vendor ID is required")
-		return
-	}
-
-	vendorID, err := strconv.ParseUint(vendorIDStr, 10, 64)
-	if err != nil {
-		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid vendor ID")
+	db := tenantDB(c)
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
 		return
 	}
 
 	var challenges []models.Challenge
-	if err := config.DB.Where("vendor_id = ?", vendorID).Find(&challenges).Error; err != nil {
+	if err := db.Where("vendor_id = ?", vendorID).Find(&challenges).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to fetch challenges")
 		return
 	}
@@ -114,28 +128,24 @@ func GetChallengesByVendor(c *gin.Context) {
 }
 
 func GetChallengeLogs(c *gin.Context) {
-	userIDRaw, _ := c.Get("user_id")
-	userID := uint(userIDRaw.(float64))
-
-	var user models.User
-	if err := config.DB.First(&user, userID).Error; err != nil {
+	db := tenantDB(c)
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
 		response.JSONErrorResponse(c.Writer, false, http.StatusUnauthorized, "User not found")
 		return
 	}
 
 	var challengeLogs []models.ChallengeLog
-	query := config.DB.Model(&models.ChallengeLog{})
-
-	switch user.Role {
-	case "member":
+	query := db.Model(&models.ChallengeLog{})
+
+	switch {
+	case middleware.Can(c, middleware.PermAllVendors):
+		// platform admin melihat semua log
+	case middleware.Can(c, middleware.PermChallengeManage):
+		// pelatih melihat semua log di vendor yang sama
+		query = query.Where("vendor_id = ?", user.VendorID)
+	default:
 		query = query.Where("user_id = ?", user.ID)
-	case "pelatih":
-		// ambil semua user di vendor yang sama
-		var userIDs []uint
-		config.DB.Model(&models.User{}).
-			Where("vendor_id = ?", user.VendorID).
-			Pluck("id", &userIDs)
-		query = query.Where("user_id IN ?", userIDs)
 	}
 
 	if err := query.Find(&challengeLogs).Error; err != nil {
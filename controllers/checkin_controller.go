@@ -0,0 +1,370 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"ssb_api/controllers/middleware"
+	"ssb_api/models"
+	"ssb_api/models/response"
+	"ssb_api/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetEventCheckInCode membuat isi QR check-in untuk ditampilkan pelatih.
+// Kode berlaku singkat, aplikasi mengambil kode baru setiap refresh_in detik.
+func GetEventCheckInCode(c *gin.Context) {
+	db := tenantDB(c)
+	var event models.Event
+	if err := db.First(&event, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Event not found")
+		return
+	}
+	if !requireVendorAccess(c, &event.VendorID) {
+		return
+	}
+
+	now := time.Now()
+	if err := utils.CheckCheckInOpen(event, now); err != nil {
+		respondCheckInError(c, err, "Failed to create check-in code")
+		return
+	}
+	code, expiresAt, err := utils.NewCheckInCode(event, now)
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to create check-in code")
+		return
+	}
+	opens, closes, _ := utils.CheckInWindow(event)
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
+		"event_id":   event.ID,
+		"code":       code,
+		"expires_at": expiresAt,
+		"refresh_in": int(utils.CheckInCodeTTL.Seconds()),
+		"opens_at":   opens,
+		"closes_at":  closes,
+	})
+}
+
+// CheckInEvent mencatat kehadiran user yang sedang login dari kode QR yang
+// dipindai.
+func CheckInEvent(c *gin.Context) {
+	db := tenantDB(c)
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Code is required")
+		return
+	}
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		response.JSONErrorResponse(c.Writer, false, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	now := time.Now()
+	eventID, err := utils.VerifyCheckInCode(input.Code, now)
+	if err != nil {
+		respondCheckInError(c, err, "Failed to check in")
+		return
+	}
+	var event models.Event
+	if err := db.First(&event, eventID).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Event not found")
+		return
+	}
+	if !requireVendorAccess(c, &event.VendorID) {
+		return
+	}
+
+	eventLog, already, err := utils.CheckIn(db, event.ID, user, utils.CheckInRecord{Method: models.CheckInQR}, now)
+	if err != nil {
+		respondCheckInError(c, err, "Failed to check in")
+		return
+	}
+
+	message := "Checked in successfully"
+	if already {
+		message = "Already checked in"
+	}
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
+		"message":   message,
+		"event_log": eventLog,
+	})
+}
+
+// GeoCheckInEvent mencatat kehadiran dari koordinat GPS pemain. Check-in
+// hanya diterima di dalam radius titik lokasi event; yang di luar radius
+// disimpan sebagai percobaan untuk ditinjau pelatih.
+func GeoCheckInEvent(c *gin.Context) {
+	db := tenantDB(c)
+	var input struct {
+		Latitude  *float64 `json:"latitude" binding:"required"`
+		Longitude *float64 `json:"longitude" binding:"required"`
+		Accuracy  *float64 `json:"accuracy"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Latitude and longitude are required")
+		return
+	}
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		response.JSONErrorResponse(c.Writer, false, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	var event models.Event
+	if err := db.First(&event, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Event not found")
+		return
+	}
+	if !requireVendorAccess(c, &event.VendorID) {
+		return
+	}
+
+	now := time.Now()
+	if err := utils.CheckCheckInOpen(event, now); err != nil {
+		respondCheckInError(c, err, "Failed to check in")
+		return
+	}
+	distance, err := utils.DistanceToEvent(event, *input.Latitude, *input.Longitude)
+	if err != nil {
+		respondCheckInError(c, err, "Failed to check in")
+		return
+	}
+
+	radius := utils.CheckInRadius(event)
+	if distance > float64(radius) {
+		attempt := models.CheckInAttempt{
+			VendorID:  event.VendorID,
+			EventID:   event.ID,
+			UserID:    user.ID,
+			UserName:  user.Name,
+			Latitude:  *input.Latitude,
+			Longitude: *input.Longitude,
+			Distance:  distance,
+			Radius:    radius,
+			Status:    models.AttemptPending,
+		}
+		if input.Accuracy != nil {
+			attempt.Accuracy = *input.Accuracy
+		}
+		if err := db.Create(&attempt).Error; err != nil {
+			response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to record check-in attempt")
+			return
+		}
+		response.JSONErrorResponse(c.Writer, false, http.StatusForbidden,
+			fmt.Sprintf("You are %.0f m from the event location, check-in radius is %d m", distance, radius))
+		return
+	}
+
+	eventLog, already, err := utils.CheckIn(db, event.ID, user, utils.CheckInRecord{
+		Method:    models.CheckInGeo,
+		Latitude:  input.Latitude,
+		Longitude: input.Longitude,
+		Accuracy:  input.Accuracy,
+		Distance:  &distance,
+	}, now)
+	if err != nil {
+		respondCheckInError(c, err, "Failed to check in")
+		return
+	}
+
+	message := "Checked in successfully"
+	if already {
+		message = "Already checked in"
+	}
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
+		"message":   message,
+		"event_log": eventLog,
+	})
+}
+
+// GetCheckInAttempts menampilkan check-in lokasi yang ditolak untuk sebuah
+// event. Query status: pending, approved atau dismissed.
+func GetCheckInAttempts(c *gin.Context) {
+	db := tenantDB(c)
+	var event models.Event
+	if err := db.First(&event, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Event not found")
+		return
+	}
+	if !requireVendorAccess(c, &event.VendorID) {
+		return
+	}
+
+	query := db.Where("event_id = ?", event.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var attempts []models.CheckInAttempt
+	if err := query.Order("created_at DESC").Find(&attempts).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to fetch check-in attempts")
+		return
+	}
+	response.JSONSuccess(c.Writer, true, http.StatusOK, attempts)
+}
+
+// ReviewCheckInAttempt menyetujui (pemain dicatat hadir) atau mengabaikan
+// percobaan check-in yang ditolak.
+func ReviewCheckInAttempt(c *gin.Context) {
+	db := tenantDB(c)
+	var input struct {
+		Action string `json:"action"` // approve atau dismiss
+		Note   string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || (input.Action != "approve" && input.Action != "dismiss") {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Action must be approve or dismiss")
+		return
+	}
+	coach, _ := middleware.CurrentUser(c)
+
+	var attempt models.CheckInAttempt
+	if err := db.First(&attempt, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Check-in attempt not found")
+		return
+	}
+	if !requireVendorAccess(c, &attempt.VendorID) {
+		return
+	}
+
+	var eventLog *models.EventLog
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&attempt, attempt.ID).Error; err != nil {
+			return err
+		}
+		if attempt.Status != models.AttemptPending {
+			return errAttemptReviewed
+		}
+		now := time.Now()
+		attempt.Status = models.AttemptDismissed
+		if input.Action == "approve" {
+			var user models.User
+			if err := tx.First(&user, attempt.UserID).Error; err != nil {
+				return err
+			}
+			log, _, err := utils.CheckIn(tx, attempt.EventID, user, utils.CheckInRecord{
+				Method:    models.CheckInCoach,
+				Latitude:  &attempt.Latitude,
+				Longitude: &attempt.Longitude,
+				Accuracy:  &attempt.Accuracy,
+				Distance:  &attempt.Distance,
+				By:        &coach.ID,
+				Note:      input.Note,
+				AnyTime:   true,
+			}, now)
+			if err != nil {
+				return err
+			}
+			eventLog = &log
+			attempt.Status = models.AttemptApproved
+		}
+		attempt.ReviewedBy = &coach.ID
+		attempt.ReviewedAt = &now
+		attempt.ReviewNote = input.Note
+		return tx.Save(&attempt).Error
+	})
+	if errors.Is(err, errAttemptReviewed) {
+		response.JSONErrorResponse(c.Writer, false, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		respondCheckInError(c, err, "Failed to review check-in attempt")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
+		"attempt":   attempt,
+		"event_log": eventLog,
+	})
+}
+
+// OverrideCheckIn dipakai pelatih untuk mencatat hadir pemain secara manual,
+// misal HP pemain mati atau GPS meleset. Percobaan yang masih menunggu
+// tinjauan ikut disetujui.
+func OverrideCheckIn(c *gin.Context) {
+	db := tenantDB(c)
+	var input struct {
+		UserID uint   `json:"user_id" binding:"required"`
+		Note   string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "User ID is required")
+		return
+	}
+	coach, _ := middleware.CurrentUser(c)
+
+	var event models.Event
+	if err := db.First(&event, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Event not found")
+		return
+	}
+	if !requireVendorAccess(c, &event.VendorID) {
+		return
+	}
+	var user models.User
+	if err := db.First(&user, input.UserID).Error; err != nil || user.VendorID == nil || *user.VendorID != event.VendorID {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "User not found in this vendor")
+		return
+	}
+
+	var eventLog models.EventLog
+	var already bool
+	err := db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		var err error
+		eventLog, already, err = utils.CheckIn(tx, event.ID, user, utils.CheckInRecord{
+			Method:  models.CheckInCoach,
+			By:      &coach.ID,
+			Note:    input.Note,
+			AnyTime: true,
+		}, now)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.CheckInAttempt{}).
+			Where("event_id = ? AND user_id = ? AND status = ?", event.ID, user.ID, models.AttemptPending).
+			Updates(map[string]interface{}{
+				"status":      models.AttemptApproved,
+				"reviewed_by": coach.ID,
+				"reviewed_at": now,
+				"review_note": input.Note,
+			}).Error
+	})
+	if err != nil {
+		respondCheckInError(c, err, "Failed to check in player")
+		return
+	}
+
+	message := "Player checked in"
+	if already {
+		message = "Player was already checked in"
+	}
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
+		"message":   message,
+		"event_log": eventLog,
+	})
+}
+
+var errAttemptReviewed = errors.New("check-in attempt has already been reviewed")
+
+func respondCheckInError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, utils.ErrInvalidCheckInCode), errors.Is(err, utils.ErrInvalidCoordinates):
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+	case errors.Is(err, utils.ErrCheckInNotOpen), errors.Is(err, utils.ErrCheckInClosed),
+		errors.Is(err, utils.ErrEventFinished), errors.Is(err, utils.ErrEventCancelled),
+		errors.Is(err, utils.ErrEventUnscheduled), errors.Is(err, utils.ErrNoLocationPoint),
+		errors.Is(err, utils.ErrCheckInFull):
+		response.JSONErrorResponse(c.Writer, false, http.StatusConflict, err.Error())
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Event not found")
+	default:
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"net/http"
+	"ssb_api/models"
+	"ssb_api/models/response"
+	"ssb_api/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// dateRangeParams membaca start_date dan end_date. Format lama DD/MM/YYYY
+// masih diterima, hasilnya selalu YYYY-MM-DD.
+func dateRangeParams(c *gin.Context) (models.Date, models.Date, bool) {
+	start, errStart := models.ParseDate(c.Query("start_date"))
+	end, errEnd := models.ParseDate(c.Query("end_date"))
+	if errStart != nil || errEnd != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid start_date or end_date, use YYYY-MM-DD")
+		return "", "", false
+	}
+	return start, end, true
+}
+
+// localizeEvents menampilkan jadwal event dalam zona waktu vendornya.
+func localizeEvents(db *gorm.DB, events []models.Event) []models.Event {
+	zones := map[uint]*time.Location{}
+	for i := range events {
+		loc, ok := zones[events[i].VendorID]
+		if !ok {
+			loc = utils.VendorLocation(db, events[i].VendorID)
+			zones[events[i].VendorID] = loc
+		}
+		events[i].InLocation(loc)
+	}
+	return events
+}
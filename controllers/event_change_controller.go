@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"ssb_api/controllers/middleware"
+	"ssb_api/models"
+	"ssb_api/models/response"
+	"ssb_api/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CancelEvent membatalkan satu event lalu memberi tahu pemain terdaftar dan
+// walinya. payment_action void membatalkan tagihan event yang belum dibayar,
+// refund juga mengajukan refund untuk tagihan yang sudah lunas. Kirim
+// cancelled=false untuk memulihkan event.
+func CancelEvent(c *gin.Context) {
+	db := tenantDB(c)
+	var input struct {
+		Reason        string `json:"reason"`
+		Cancelled     *bool  `json:"cancelled"`
+		PaymentAction string `json:"payment_action"` // kosong, void atau refund
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	cancelled := input.Cancelled == nil || *input.Cancelled
+	if !validPaymentAction(input.PaymentAction) || (!cancelled && input.PaymentAction != "") {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, utils.ErrInvalidPaymentAction.Error())
+		return
+	}
+	actor, _ := middleware.CurrentUser(c)
+
+	var event models.Event
+	if err := db.First(&event, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Event not found")
+		return
+	}
+	if !requireVendorAccess(c, &event.VendorID) {
+		return
+	}
+	if event.IsFinish {
+		response.JSONErrorResponse(c.Writer, false, http.StatusConflict, "Event is already finished")
+		return
+	}
+	if event.IsCancelled == cancelled {
+		message := "Event is already cancelled"
+		if !cancelled {
+			message = "Event is not cancelled"
+		}
+		response.JSONErrorResponse(c.Writer, false, http.StatusConflict, message)
+		return
+	}
+
+	var change models.EventChange
+	var payments utils.CancelledEventPayments
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Event yang dipulihkan memesan lapangan dan pelatihnya lagi
+		if !cancelled {
+			restored := event
+			restored.IsCancelled = false
+			loc := utils.VendorLocation(tx, event.VendorID)
+			if err := utils.CheckPitchBooking(tx, restored, loc); err != nil {
+				return err
+			}
+			if _, err := utils.CheckEventStaff(tx, restored, loc); err != nil {
+				return err
+			}
+		}
+
+		// Pertemuan series dibatalkan lewat pengecualian series supaya
+		// tanggalnya tidak dibuat ulang
+		if event.SeriesID != nil {
+			var series models.EventSeries
+			if err := tx.First(&series, *event.SeriesID).Error; err != nil {
+				return err
+			}
+			if err := utils.SetSeriesDateCancelled(tx, series, event.OccurrenceDate, input.Reason, cancelled); err != nil {
+				return err
+			}
+		} else {
+			reason := input.Reason
+			if !cancelled {
+				reason = ""
+			}
+			if err := tx.Model(&event).Updates(map[string]interface{}{"is_cancelled": cancelled, "cancel_reason": reason}).Error; err != nil {
+				return err
+			}
+		}
+		event.IsCancelled = cancelled
+		event.CancelReason = input.Reason
+		if !cancelled {
+			event.CancelReason = ""
+		}
+
+		var err error
+		change, payments, err = recordEventCancellation(tx, event, input.Reason, input.PaymentAction, actor)
+		return err
+	})
+	if err != nil {
+		respondEventChangeError(c, err, "Failed to cancel event")
+		return
+	}
+	utils.NotifyEventChange(db, event, change)
+
+	event.InLocation(utils.VendorLocation(db, event.VendorID))
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
+		"event":    event,
+		"change":   change,
+		"payments": payments,
+	})
+}
+
+// GetEventChanges menampilkan riwayat perubahan event beserta diff-nya,
+// terbaru lebih dulu.
+func GetEventChanges(c *gin.Context) {
+	db := tenantDB(c)
+	var event models.Event
+	if err := db.First(&event, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Event not found")
+		return
+	}
+	if !requireVendorAccess(c, &event.VendorID) {
+		return
+	}
+
+	var changes []models.EventChange
+	if err := db.Where("event_id = ?", event.ID).Order("created_at DESC").Find(&changes).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to fetch event changes")
+		return
+	}
+	response.JSONSuccess(c.Writer, true, http.StatusOK, changes)
+}
+
+// recordEventUpdate mencatat perubahan jadwal atau lokasi event. Event yang
+// dibatalkan atau sudah selesai tidak diberitahukan, begitu juga perubahan
+// yang tidak menyentuh jadwal dan lokasi (hasilnya nil).
+func recordEventUpdate(tx *gorm.DB, before, after models.Event, loc *time.Location, actor models.User) (*models.EventChange, error) {
+	if after.IsCancelled || after.IsFinish {
+		return nil, nil
+	}
+	diff := utils.DiffEventSchedule(before, after, loc)
+	if len(diff) == 0 {
+		return nil, nil
+	}
+	change, err := utils.RecordEventChange(tx, after, models.EventChangeUpdated, diff, "", &actor)
+	return &change, err
+}
+
+// recordEventCancellation mencatat pembatalan atau pemulihan event, lalu
+// menangani tagihannya sesuai paymentAction.
+func recordEventCancellation(tx *gorm.DB, event models.Event, reason, paymentAction string, actor models.User) (models.EventChange, utils.CancelledEventPayments, error) {
+	kind := models.EventChangeCancelled
+	if !event.IsCancelled {
+		kind = models.EventChangeRestored
+	}
+	var payments utils.CancelledEventPayments
+	change, err := utils.RecordEventChange(tx, event, kind, nil, reason, &actor)
+	if err != nil || !event.IsCancelled {
+		return change, payments, err
+	}
+	payments, err = utils.SettleCancelledEventPayments(tx, event, paymentAction, actor)
+	return change, payments, err
+}
+
+func validPaymentAction(action string) bool {
+	switch action {
+	case utils.CancelPaymentsKeep, utils.CancelPaymentsVoid, utils.CancelPaymentsRefund:
+		return true
+	}
+	return false
+}
+
+// respondEventChangeError memetakan error lapangan, pelatih, pembatalan
+// tagihan dan series.
+func respondEventChangeError(c *gin.Context, err error, message string) {
+	if respondVenueError(c, err) || respondStaffError(c, err) {
+		return
+	}
+	var transition utils.ErrInvalidPaymentTransition
+	switch {
+	case errors.Is(err, utils.ErrInvalidPaymentAction):
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+	case errors.As(err, &transition), errors.Is(err, utils.ErrRefundExceedsPaid), errors.Is(err, utils.ErrRefundPaymentNotPaid):
+		response.JSONErrorResponse(c.Writer, false, http.StatusConflict, message+": "+err.Error())
+	default:
+		respondSeriesError(c, err, message)
+	}
+}
@@ -1,33 +1,94 @@
 package controllers
 
 import (
+	"errors"
+	"log"
 	"net/http"
-	"ssb_api/config"
+	"sort"
+	"ssb_api/controllers/middleware"
 	"ssb_api/models"
 	"ssb_api/models/response"
+	"ssb_api/utils"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// eventInput adalah body create/update event. Jadwal dikirim sebagai
+// start_at/end_at ISO-8601; date, time dan end_time format lama masih
+// diterima.
+type eventInput struct {
+	models.Event
+	StartAt string `json:"start_at"`
+	EndAt   string `json:"end_at"`
+	EndTime string `json:"end_time"`
+}
+
+func (in eventInput) schedule() utils.ScheduleInput {
+	return utils.ScheduleInput{StartAt: in.StartAt, EndAt: in.EndAt, Date: in.Date, Time: in.Time, EndTime: in.EndTime}
+}
+
 // CreateEvent handles the creation of a new event.
 func CreateEvent(c *gin.Context) {
-	var input models.Event
-	if err := c.ShouldBindJSON(&input); err != nil {
+	db := tenantDB(c)
+	var body eventInput
+	if err := c.ShouldBindJSON(&body); err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request")
 		return
 	}
+	input := body.Event
 
-	if err := config.DB.Where("id = ?", input.VendorID).First(&models.Vendor{}).Error; err != nil {
+	if !requireVendorAccess(c, &input.VendorID) {
+		return
+	}
+	var vendor models.Vendor
+	if err := db.Where("id = ?", input.VendorID).First(&vendor).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Vendor not found")
 		return
 	}
+	if input.Capacity < 0 {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Capacity must not be negative")
+		return
+	}
+	loc := vendor.Location()
+	start, end, err := body.schedule().Resolve(loc)
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+		return
+	}
+	input.SetSchedule(start, end, loc)
 	input.IsFinish = false
-
-	// Menyimpan Event baru
-	if err := config.DB.Create(&input).Error; err != nil {
-		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to create event")
+	input.SeriesID = nil
+	input.OccurrenceDate = ""
+	input.IsException = false
+	input.IsCancelled = false
+
+	// Menyimpan Event baru, lapangan dan pelatih yang dipesan tidak boleh
+	// bentrok
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := utils.ApplyVenue(tx, &input); err != nil {
+			return err
+		}
+		if err := utils.CheckPitchBooking(tx, input, loc); err != nil {
+			return err
+		}
+		if err := tx.Create(&input).Error; err != nil {
+			return err
+		}
+		if input.Staff == nil {
+			return nil
+		}
+		var err error
+		input.Staff, err = utils.SetStaff(tx, utils.EventSession(input), input.Staff, loc)
+		return err
+	})
+	if err != nil {
+		if !respondVenueError(c, err) && !respondStaffError(c, err) {
+			response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to create event")
+		}
 		return
 	}
 
@@ -35,46 +96,207 @@ func CreateEvent(c *gin.Context) {
 }
 
 func UpdateEvent(c *gin.Context) {
+	db := tenantDB(c)
 	// Ambil ID event dari URL param
 	eventID := c.Param("id")
-	var input models.Event
+	var body eventInput
 
 	// Bind JSON body
-	if err := c.ShouldBindJSON(&input); err != nil {
+	if err := c.ShouldBindJSON(&body); err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request")
 		return
 	}
+	input := body.Event
+
+	if input.Capacity < 0 {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Capacity must not be negative")
+		return
+	}
 
 	// Cari event yang akan diupdate
 	var event models.Event
-	if err := config.DB.Where("id = ?", eventID).First(&event).Error; err != nil {
+	if err := db.Where("id = ?", eventID).First(&event).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Event not found")
 		return
 	}
+	if !requireVendorAccess(c, &event.VendorID) {
+		return
+	}
+
+	// Jadwal kosong berarti jadwal lama tetap dipakai
+	loc := utils.VendorLocation(db, event.VendorID)
+	var start, end time.Time
+	reschedule := !body.schedule().Empty()
+	if reschedule {
+		var err error
+		if start, end, err = body.schedule().Resolve(loc); err != nil {
+			response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	// Event berulang: scope=this hanya pertemuan ini, scope=following
+	// pertemuan ini dan sesudahnya
+	switch c.DefaultQuery("scope", "this") {
+	case "this":
+		event.IsException = event.SeriesID != nil
+	case "following":
+		if event.SeriesID == nil {
+			response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Event is not part of a series")
+			return
+		}
+		updateFollowingOccurrences(c, db, event, input, loc, reschedule, start, end)
+		return
+	default:
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Scope must be this or following")
+		return
+	}
 
 	// Update field yang diizinkan
+	before := event
 	event.Title = input.Title
 	event.Description = input.Description
 	event.Location = input.Location
 	event.LocationPoint = input.LocationPoint
 	event.EventType = input.EventType
 	event.IsPaid = input.IsPaid
+	event.IsPrivate = input.IsPrivate
+	event.CheckInRadius = input.CheckInRadius
 	event.Fee = input.Fee
-	event.Date = input.Date
-	event.Time = input.Time
+	if reschedule {
+		event.SetSchedule(start, end, loc)
+	}
+	event.Capacity = input.Capacity
+	event.RegistrationDeadline = input.RegistrationDeadline
+	event.VenueID = input.VenueID
+	event.PitchID = input.PitchID
 
 	// tambah field lain sesuai kebutuhan
 
-	// Simpan perubahan
-	if err := config.DB.Save(&event).Error; err != nil {
-		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to update event")
+	// Simpan perubahan, kuota yang bertambah langsung diisi dari antrean.
+	// Perubahan jadwal atau lokasi dicatat untuk diberitahukan ke pemain.
+	actor, _ := middleware.CurrentUser(c)
+	var promoted []models.EventLog
+	var change *models.EventChange
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := utils.ApplyVenue(tx, &event); err != nil {
+			return err
+		}
+		if err := utils.CheckPitchBooking(tx, event, loc); err != nil {
+			return err
+		}
+		if err := tx.Save(&event).Error; err != nil {
+			return err
+		}
+		var err error
+		if event.Staff, err = syncEventStaff(tx, event, input.Staff, reschedule, loc); err != nil {
+			return err
+		}
+		if promoted, err = utils.PromoteWaitlist(tx, event); err != nil {
+			return err
+		}
+		change, err = recordEventUpdate(tx, before, event, loc, actor)
+		return err
+	})
+	if err != nil {
+		if !respondVenueError(c, err) && !respondStaffError(c, err) {
+			response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to update event")
+		}
 		return
 	}
+	utils.NotifyWaitlistPromotion(db, event, promoted)
+	if change != nil {
+		utils.NotifyEventChange(db, event, *change)
+	}
 
+	event.InLocation(loc)
 	response.JSONSuccess(c.Writer, true, http.StatusOK, event)
 }
 
+// updateFollowingOccurrences menerapkan perubahan ke pertemuan event dan
+// semua pertemuan sesudahnya dalam series. Tanggal tiap pertemuan tetap,
+// hanya jam dan lamanya yang ikut jadwal baru.
+func updateFollowingOccurrences(c *gin.Context, db *gorm.DB, event models.Event, input models.Event, loc *time.Location, reschedule bool, start, end time.Time) {
+	actor, _ := middleware.CurrentUser(c)
+	var series models.EventSeries
+	var events []models.Event
+	promoted := map[uint][]models.EventLog{}
+	changes := map[uint]models.EventChange{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var previous []models.Event
+		if err := tx.Where("series_id = ? AND occurrence_date >= ? AND is_finish = ?", *event.SeriesID, event.OccurrenceDate, false).
+			Find(&previous).Error; err != nil {
+			return err
+		}
+		before := map[uint]models.Event{}
+		for _, e := range previous {
+			before[e.ID] = e
+		}
+
+		var err error
+		series, events, err = utils.SplitSeries(tx, event, loc, func(s *models.EventSeries) {
+			s.Title = input.Title
+			s.Description = input.Description
+			s.Location = input.Location
+			s.LocationPoint = input.LocationPoint
+			s.EventType = input.EventType
+			s.IsPaid = input.IsPaid
+			s.IsPrivate = input.IsPrivate
+			s.CheckInRadius = input.CheckInRadius
+			s.Fee = input.Fee
+			if reschedule {
+				s.Time = start.Format(models.ClockLayout)
+				s.DurationMinutes = int(end.Sub(start).Minutes())
+			}
+			s.Capacity = input.Capacity
+		})
+		if err != nil {
+			return err
+		}
+		for i, e := range events {
+			// Jam baru tetap harus muat di lapangan dan jadwal pelatih
+			if err := utils.CheckPitchBooking(tx, e, loc); err != nil {
+				return err
+			}
+			if events[i].Staff, err = syncEventStaff(tx, e, input.Staff, reschedule, loc); err != nil {
+				return err
+			}
+			logs, err := utils.PromoteWaitlist(tx, e)
+			if err != nil {
+				return err
+			}
+			promoted[e.ID] = logs
+			change, err := recordEventUpdate(tx, before[e.ID], e, loc, actor)
+			if err != nil {
+				return err
+			}
+			if change != nil {
+				changes[e.ID] = *change
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if !respondVenueError(c, err) && !respondStaffError(c, err) {
+			respondSeriesError(c, err, "Failed to update event series")
+		}
+		return
+	}
+	for _, e := range events {
+		utils.NotifyWaitlistPromotion(db, e, promoted[e.ID])
+		if change, ok := changes[e.ID]; ok {
+			utils.NotifyEventChange(db, e, change)
+		}
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
+		"series":      series,
+		"occurrences": localizeEvents(db, events),
+	})
+}
+
 func GetEvents(c *gin.Context) {
+	db := tenantDB(c)
 	page := c.DefaultQuery("page", "1")
 	limit := c.DefaultQuery("limit", "10")
 	eventType := c.DefaultQuery("event_type", "")
@@ -92,7 +314,7 @@ func GetEvents(c *gin.Context) {
 	userID := uint(userIDRaw.(float64))
 
 	var user models.User
-	if err := config.DB.First(&user, userID).Error; err != nil {
+	if err := db.First(&user, userID).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusUnauthorized, "User not found")
 		return
 	}
@@ -100,7 +322,36 @@ func GetEvents(c *gin.Context) {
 	var events []models.Event
 	var totalEvents int64
 
-	query := config.DB.Model(&models.Event{}).Preload("Users")
+	loc := models.Vendor{}.Location()
+	if user.VendorID != nil {
+		loc = utils.VendorLocation(db, *user.VendorID)
+	}
+
+	// Rentang tanggal (YYYY-MM-DD) sekaligus membuat pertemuan series yang
+	// jatuh di rentang tersebut
+	startDate, endDate, ok := dateRangeParams(c)
+	if !ok {
+		return
+	}
+	if startDate != "" || endDate != "" {
+		from, okFrom := startDate.Time(time.UTC)
+		to, okTo := endDate.Time(time.UTC)
+		if !okFrom || !okTo || to.Before(from) {
+			response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid start_date or end_date, use YYYY-MM-DD")
+			return
+		}
+		if to.Sub(from) > utils.MaxExpandDays*24*time.Hour {
+			response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Date range must not exceed one year")
+			return
+		}
+		if user.VendorID != nil {
+			if err := utils.ExpandVendorSeries(db, *user.VendorID, from, to); err != nil {
+				log.Printf("expand event series for vendor %d failed: %v", *user.VendorID, err)
+			}
+		}
+	}
+
+	query := db.Model(&models.Event{})
 
 	// Filter by Vendor
 	query = query.Where("vendor_id = ?", user.VendorID)
@@ -132,9 +383,20 @@ func GetEvents(c *gin.Context) {
 		query = query.Where("payment_type = ?", paymentType)
 	}
 
-	// Filter by Date (format: YYYY-MM-DD)
+	// Filter by Date (format: YYYY-MM-DD), dihitung per hari di zona waktu vendor
 	if date != "" {
-		query = query.Where("date = ?", date)
+		day, err := models.ParseDate(date)
+		if err != nil {
+			response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid date, use YYYY-MM-DD")
+			return
+		}
+		from, to := utils.LocalDayRange(day, day, loc)
+		query = query.Where("start_at >= ? AND start_at < ?", from, to)
+	}
+
+	if startDate != "" {
+		from, to := utils.LocalDayRange(startDate, endDate, loc)
+		query = query.Where("start_at >= ? AND start_at < ?", from, to).Order("start_at ASC")
 	}
 
 	// Filter by Search: title, description, location
@@ -155,13 +417,21 @@ func GetEvents(c *gin.Context) {
 		"total": totalEvents,
 	}
 
+	for i := range events {
+		events[i].InLocation(loc)
+	}
+
 	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
 		"pagination": pagination,
 		"events":     events,
 	})
 }
 
+// UpdateEventFinishStatus menyelesaikan event (is_finish=true) atau membuka
+// kembali event yang sudah selesai (is_finish=false). Lihat utils.FinishEvent
+// dan utils.ReopenEvent.
 func UpdateEventFinishStatus(c *gin.Context) {
+	db := tenantDB(c)
 	type FinishUpdateInput struct {
 		ID       uint `json:"id"`
 		IsFinish bool `json:"is_finish"`
@@ -174,64 +444,95 @@ func UpdateEventFinishStatus(c *gin.Context) {
 	}
 
 	var event models.Event
-	if err := config.DB.First(&event, input.ID).Error; err != nil {
+	if err := db.First(&event, input.ID).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Event not found")
 		return
 	}
+	if !requireVendorAccess(c, &event.VendorID) {
+		return
+	}
+	actor, _ := middleware.CurrentUser(c)
 
-	event.IsFinish = input.IsFinish
+	if !input.IsFinish {
+		result, err := utils.ReopenEvent(db, event.ID, actor, time.Now())
+		if err != nil {
+			respondFinishError(c, err, "Failed to reopen event")
+			return
+		}
+		event.IsFinish = false
+		event.InLocation(utils.VendorLocation(db, event.VendorID))
+		response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
+			"event":  event,
+			"reopen": result,
+		})
+		return
+	}
 
-	if err := config.DB.Save(&event).Error; err != nil {
-		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to update event status")
+	result, err := utils.FinishEvent(db, event.ID, actor, time.Now())
+	if err != nil {
+		respondFinishError(c, err, "Failed to finish event")
 		return
 	}
+	utils.NotifyEventFinished(db, event, actor, result)
 
-	response.JSONSuccess(c.Writer, true, http.StatusOK, event)
+	event.IsFinish = true
+	event.InLocation(utils.VendorLocation(db, event.VendorID))
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
+		"event":    event,
+		"closing":  result.Closing,
+		"invoices": result.Invoices,
+	})
+}
+
+func respondFinishError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, utils.ErrEventFinished), errors.Is(err, utils.ErrEventNotFinished),
+		errors.Is(err, utils.ErrEventCancelled):
+		response.JSONErrorResponse(c.Writer, false, http.StatusConflict, err.Error())
+	default:
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, message)
+	}
 }
 
 func CreateEventLog(c *gin.Context) {
+	db := tenantDB(c)
 	var input models.EventLog
 	if err := c.ShouldBindJSON(&input); err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request")
 		return
 	}
 
+	// Pemain hanya boleh mendaftarkan dirinya sendiri
+	if !middleware.Can(c, middleware.PermAttendanceManage) {
+		currentUser, _ := middleware.CurrentUser(c)
+		if input.UserID != currentUser.ID {
+			response.JSONErrorResponse(c.Writer, false, http.StatusForbidden, "You can only register yourself")
+			return
+		}
+	}
+
 	// Pastikan Vendor dan Event ada
-	if err := config.DB.First(&models.Vendor{}, input.VendorID).Error; err != nil {
+	if err := db.First(&models.Vendor{}, input.VendorID).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Vendor not found")
 		return
 	}
-	if err := config.DB.First(&models.Event{}, input.EventID).Error; err != nil {
+	var event models.Event
+	if err := db.First(&event, input.EventID).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Event not found")
 		return
 	}
-
-	// Cek apakah EventLog sudah ada
-	var existing models.EventLog
-	if err := config.DB.Where("user_id = ? AND event_id = ?", input.UserID, input.EventID).First(&existing).Error; err == nil {
-		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Event log already exists for this user and event")
+	if event.VendorID != input.VendorID {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Event does not belong to this vendor")
 		return
 	}
-
-	// Buat log baru
-	input.Status = true // otomatis true saat pertama kali dibuat
-	if err := config.DB.Create(&input).Error; err != nil {
-		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to create event log")
+	if !requireVendorAccess(c, &event.VendorID) {
 		return
 	}
 
-	// Update counter user langsung
-	var user models.User
-	if err := config.DB.First(&user, input.UserID).Error; err == nil {
-		switch strings.ToLower(input.EventType) {
-		case "match":
-			user.Match += 1
-		case "training":
-			user.Training += 1
-		case "program":
-			user.Program += 1
-		}
-		config.DB.Save(&user)
+	// Daftar sesuai RSVP, masuk antrean bila kuota penuh
+	if err := utils.RegisterForEvent(db, &input); err != nil {
+		respondRegistrationError(c, err, "Failed to create event log")
+		return
 	}
 
 	response.JSONSuccess(c.Writer, true, http.StatusCreated, gin.H{
@@ -241,6 +542,7 @@ func CreateEventLog(c *gin.Context) {
 }
 
 func UpdateEventLogStatus(c *gin.Context) {
+	db := tenantDB(c)
 	var input struct {
 		ID     uint   `json:"id"`     // ID event log
 		Status bool   `json:"status"` // status baru
@@ -254,46 +556,50 @@ func UpdateEventLogStatus(c *gin.Context) {
 
 	// Cari event log
 	var eventLog models.EventLog
-	if err := config.DB.First(&eventLog, input.ID).Error; err != nil {
+	if err := db.First(&eventLog, input.ID).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Event log not found")
 		return
 	}
+	if !requireVendorAccess(c, &eventLog.VendorID) {
+		return
+	}
+	if eventLog.LockedAt != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusConflict, utils.ErrEventLogLocked.Error())
+		return
+	}
 
 	oldStatus := eventLog.Status
 
 	eventLog.Status = input.Status
 	eventLog.Note = input.Note
-	if err := config.DB.Save(&eventLog).Error; err != nil {
+	// Hadir yang diisi pelatih dicatat sebagai check-in manual supaya tidak
+	// ditandai absen saat event selesai
+	if input.Status && eventLog.CheckedInAt == nil {
+		coach, _ := middleware.CurrentUser(c)
+		now := time.Now()
+		eventLog.CheckedInAt = &now
+		eventLog.CheckInMethod = models.CheckInCoach
+		eventLog.CheckInBy = &coach.ID
+	}
+	// Syarat locked_at menahan perubahan yang berbarengan dengan penutupan event
+	result := db.Model(&eventLog).Where("locked_at IS NULL").
+		Select("status", "note", "checked_in_at", "check_in_method", "check_in_by").Updates(&eventLog)
+	if result.Error != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to update log")
 		return
 	}
+	if result.RowsAffected == 0 {
+		response.JSONErrorResponse(c.Writer, false, http.StatusConflict, utils.ErrEventLogLocked.Error())
+		return
+	}
 
 	// Hanya update counter jika status berubah
 	if oldStatus != input.Status {
-		var user models.User
-		if err := config.DB.First(&user, eventLog.UserID).Error; err == nil {
-			switch strings.ToLower(eventLog.EventType) {
-			case "match":
-				if input.Status {
-					user.Match++
-				} else if user.Match > 0 {
-					user.Match--
-				}
-			case "training":
-				if input.Status {
-					user.Training++
-				} else if user.Training > 0 {
-					user.Training--
-				}
-			case "program":
-				if input.Status {
-					user.Program++
-				} else if user.Program > 0 {
-					user.Program--
-				}
-			}
-			config.DB.Save(&user)
+		delta := 1
+		if !input.Status {
+			delta = -1
 		}
+		utils.AdjustAttendanceCounter(db, eventLog.UserID, eventLog.EventType, delta)
 	}
 
 	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
@@ -303,6 +609,7 @@ func UpdateEventLogStatus(c *gin.Context) {
 }
 
 func GetEventLogs(c *gin.Context) {
+	db := tenantDB(c)
 	page := c.DefaultQuery("page", "1")
 	limit := c.DefaultQuery("limit", "10")
 	eventType := c.DefaultQuery("event_type", "")
@@ -317,7 +624,7 @@ func GetEventLogs(c *gin.Context) {
 
 	// Ambil data user
 	var user models.User
-	if err := config.DB.First(&user, userID).Error; err != nil {
+	if err := db.First(&user, userID).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusUnauthorized, "User not found")
 		return
 	}
@@ -325,7 +632,7 @@ func GetEventLogs(c *gin.Context) {
 	var eventLogs []models.EventLog
 	var totalLogs int64
 
-	query := config.DB.Model(&models.EventLog{}).Where("vendor_id = ?", user.VendorID)
+	query := db.Model(&models.EventLog{}).Where("vendor_id = ?", user.VendorID)
 
 	if eventType != "" {
 		query = query.Where("event_type = ?", eventType)
@@ -352,6 +659,7 @@ func GetEventLogs(c *gin.Context) {
 }
 
 func GetEventLogsByUser(c *gin.Context) {
+	db := tenantDB(c)
 	page := c.DefaultQuery("page", "1")
 	limit := c.DefaultQuery("limit", "10")
 	eventType := c.DefaultQuery("event_type", "")
@@ -366,7 +674,7 @@ func GetEventLogsByUser(c *gin.Context) {
 
 	// Cek apakah user ada
 	var user models.User
-	if err := config.DB.First(&user, userID).Error; err != nil {
+	if err := db.First(&user, userID).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusUnauthorized, "User not found")
 		return
 	}
@@ -374,7 +682,7 @@ func GetEventLogsByUser(c *gin.Context) {
 	var eventLogs []models.EventLog
 	var totalLogs int64
 
-	query := config.DB.Model(&models.EventLog{}).Where("user_id = ?", userID)
+	query := db.Model(&models.EventLog{}).Where("user_id = ?", userID)
 
 	if eventType != "" {
 		query = query.Where("event_type = ?", eventType)
@@ -399,3 +707,155 @@ func GetEventLogsByUser(c *gin.Context) {
 		"event_logs": eventLogs,
 	})
 }
+
+// RespondEventRSVP menyimpan jawaban RSVP (going, maybe, not_going) untuk
+// event. Pemain yang belum terdaftar otomatis didaftarkan. Pelatih boleh
+// mengisi user_id pemain lain.
+func RespondEventRSVP(c *gin.Context) {
+	db := tenantDB(c)
+	var input struct {
+		RSVP   string `json:"rsvp"`
+		UserID uint   `json:"user_id"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || !utils.ValidRSVP(input.RSVP) {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, utils.ErrInvalidRSVP.Error())
+		return
+	}
+
+	currentUser, _ := middleware.CurrentUser(c)
+	userID := currentUser.ID
+	if input.UserID != 0 && input.UserID != currentUser.ID {
+		if !middleware.Can(c, middleware.PermAttendanceManage) {
+			response.JSONErrorResponse(c.Writer, false, http.StatusForbidden, "You can only register yourself")
+			return
+		}
+		userID = input.UserID
+	}
+
+	var event models.Event
+	if err := db.First(&event, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Event not found")
+		return
+	}
+	if !requireVendorAccess(c, &event.VendorID) {
+		return
+	}
+
+	var eventLog models.EventLog
+	if err := db.Where("user_id = ? AND event_id = ?", userID, event.ID).First(&eventLog).Error; err != nil {
+		var user models.User
+		if err := db.First(&user, userID).Error; err != nil {
+			response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "User not found")
+			return
+		}
+		if !requireVendorAccess(c, user.VendorID) {
+			return
+		}
+
+		eventLog = models.EventLog{
+			UserID:    user.ID,
+			EventID:   event.ID,
+			VendorID:  event.VendorID,
+			UserName:  user.Name,
+			EventType: event.EventType,
+			RSVP:      input.RSVP,
+		}
+		if err := utils.RegisterForEvent(db, &eventLog); err != nil {
+			respondRegistrationError(c, err, "Failed to save RSVP")
+			return
+		}
+		response.JSONSuccess(c.Writer, true, http.StatusCreated, eventLog)
+		return
+	}
+
+	promoted, err := utils.UpdateRSVP(db, &eventLog, input.RSVP)
+	if err != nil {
+		respondRegistrationError(c, err, "Failed to save RSVP")
+		return
+	}
+	utils.NotifyWaitlistPromotion(db, event, promoted)
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, eventLog)
+}
+
+// GetEventAttendees menampilkan pemain yang akan datang, mungkin datang,
+// tidak datang dan antrean beserta sisa kuota event.
+func GetEventAttendees(c *gin.Context) {
+	db := tenantDB(c)
+
+	var event models.Event
+	if err := db.First(&event, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Event not found")
+		return
+	}
+	if !requireVendorAccess(c, &event.VendorID) {
+		return
+	}
+
+	var logs []models.EventLog
+	if err := db.Where("event_id = ?", event.ID).Order("created_at ASC, id ASC").Find(&logs).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to fetch attendees")
+		return
+	}
+
+	going := []models.EventLog{}
+	maybe := []models.EventLog{}
+	notGoing := []models.EventLog{}
+	waitlist := []models.EventLog{}
+	for _, l := range logs {
+		switch {
+		case l.RSVP == models.RSVPGoing && l.Waitlisted:
+			waitlist = append(waitlist, l)
+		case l.RSVP == models.RSVPGoing:
+			going = append(going, l)
+		case l.RSVP == models.RSVPMaybe:
+			maybe = append(maybe, l)
+		default:
+			notGoing = append(notGoing, l)
+		}
+	}
+	// Antrean ditampilkan sesuai urutan naik
+	sort.SliceStable(waitlist, func(i, j int) bool {
+		a, b := waitlist[i].WaitlistedAt, waitlist[j].WaitlistedAt
+		return a != nil && b != nil && a.Before(*b)
+	})
+
+	var spotsLeft *int
+	if event.Capacity > 0 {
+		left := event.Capacity - len(going)
+		if left < 0 {
+			left = 0
+		}
+		spotsLeft = &left
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
+		"event_id":              event.ID,
+		"capacity":              event.Capacity,
+		"registration_deadline": event.RegistrationDeadline,
+		"spots_left":            spotsLeft,
+		"counts": gin.H{
+			"going":     len(going),
+			"maybe":     len(maybe),
+			"not_going": len(notGoing),
+			"waitlist":  len(waitlist),
+		},
+		"going":     going,
+		"maybe":     maybe,
+		"not_going": notGoing,
+		"waitlist":  waitlist,
+	})
+}
+
+func respondRegistrationError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, utils.ErrInvalidRSVP), errors.Is(err, utils.ErrAlreadyRegistered):
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+	case errors.Is(err, utils.ErrEventFinished), errors.Is(err, utils.ErrEventCancelled), errors.Is(err, utils.ErrRegistrationClosed):
+		response.JSONErrorResponse(c.Writer, false, http.StatusConflict, err.Error())
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Event not found")
+	default:
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, message)
+	}
+}
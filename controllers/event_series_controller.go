@@ -0,0 +1,221 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"ssb_api/controllers/middleware"
+	"ssb_api/models"
+	"ssb_api/models/response"
+	"ssb_api/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateEventSeries membuat event berulang. Aturan bisa dikirim lewat field
+// frequency, interval, by_day, until, count atau sebagai string rrule,
+// misal "FREQ=WEEKLY;BYDAY=TU,TH;COUNT=12".
+func CreateEventSeries(c *gin.Context) {
+	db := tenantDB(c)
+	var input struct {
+		models.EventSeries
+		RRule string `json:"rrule"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	series := input.EventSeries
+	series.Model = gorm.Model{}
+	series.Exceptions = nil
+	if input.RRule != "" {
+		if err := utils.ParseRRule(input.RRule, &series); err != nil {
+			response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	// Tanggal format lama masih diterima
+	startDate, errStart := models.ParseDate(series.StartDate)
+	until, errUntil := models.ParseDate(series.Until)
+	if errStart != nil || errUntil != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid start_date or until, use YYYY-MM-DD")
+		return
+	}
+	series.StartDate, series.Until = startDate.String(), until.String()
+	if series.Capacity < 0 {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Capacity must not be negative")
+		return
+	}
+
+	if !requireVendorAccess(c, &series.VendorID) {
+		return
+	}
+	var vendor models.Vendor
+	if err := db.First(&vendor, series.VendorID).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Vendor not found")
+		return
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return utils.CreateSeries(tx, &series, vendor.Location())
+	}); err != nil {
+		respondSeriesError(c, err, "Failed to create event series")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusCreated, gin.H{
+		"series": series,
+		"rrule":  seriesRRule(series),
+	})
+}
+
+// GetEventSeries menampilkan series milik vendor.
+func GetEventSeries(c *gin.Context) {
+	db := tenantDB(c)
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+
+	var series []models.EventSeries
+	if err := db.Where("vendor_id = ?", vendorID).Order("start_date DESC").Find(&series).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to fetch event series")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, series)
+}
+
+// GetEventSeriesByID menampilkan series beserta tanggal yang dibatalkan dan
+// pertemuan yang sudah dibuat.
+func GetEventSeriesByID(c *gin.Context) {
+	db := tenantDB(c)
+
+	var series models.EventSeries
+	if err := db.Preload("Exceptions").First(&series, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Event series not found")
+		return
+	}
+	if !requireVendorAccess(c, &series.VendorID) {
+		return
+	}
+
+	var events []models.Event
+	if err := db.Where("series_id = ?", series.ID).Order("start_at ASC").Find(&events).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to fetch occurrences")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
+		"series":      series,
+		"rrule":       seriesRRule(series),
+		"occurrences": localizeEvents(db, events),
+	})
+}
+
+// CancelSeriesDate membatalkan satu tanggal series, misal karena libur.
+// Kirim cancelled=false untuk memulihkannya. Bila pertemuannya sudah dibuat,
+// pemain terdaftar diberi tahu dan tagihannya ditangani seperti CancelEvent.
+func CancelSeriesDate(c *gin.Context) {
+	db := tenantDB(c)
+	var input struct {
+		Date          string `json:"date"`
+		Reason        string `json:"reason"`
+		Cancelled     *bool  `json:"cancelled"`
+		PaymentAction string `json:"payment_action"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || input.Date == "" {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Date is required")
+		return
+	}
+	date, err := models.ParseDate(input.Date)
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+		return
+	}
+	cancelled := input.Cancelled == nil || *input.Cancelled
+	if !validPaymentAction(input.PaymentAction) || (!cancelled && input.PaymentAction != "") {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, utils.ErrInvalidPaymentAction.Error())
+		return
+	}
+	actor, _ := middleware.CurrentUser(c)
+
+	var series models.EventSeries
+	if err := db.First(&series, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Event series not found")
+		return
+	}
+	if !requireVendorAccess(c, &series.VendorID) {
+		return
+	}
+
+	var event models.Event
+	var change *models.EventChange
+	var payments utils.CancelledEventPayments
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("series_id = ? AND occurrence_date = ? AND is_finish = ?", series.ID, date.String(), false).
+			First(&event).Error
+		materialized := err == nil
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err := utils.SetSeriesDateCancelled(tx, series, date.String(), input.Reason, cancelled); err != nil {
+			return err
+		}
+		if !materialized || event.IsCancelled == cancelled {
+			return nil
+		}
+		if !cancelled {
+			restored := event
+			restored.IsCancelled = false
+			loc := utils.VendorLocation(tx, series.VendorID)
+			if err := utils.CheckPitchBooking(tx, restored, loc); err != nil {
+				return err
+			}
+			if _, err := utils.CheckEventStaff(tx, restored, loc); err != nil {
+				return err
+			}
+		}
+
+		event.IsCancelled = cancelled
+		event.CancelReason = input.Reason
+		if !cancelled {
+			event.CancelReason = ""
+		}
+		recorded, result, err := recordEventCancellation(tx, event, input.Reason, input.PaymentAction, actor)
+		change, payments = &recorded, result
+		return err
+	}); err != nil {
+		respondEventChangeError(c, err, "Failed to update series date")
+		return
+	}
+	if change != nil {
+		utils.NotifyEventChange(db, event, *change)
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
+		"series_id": series.ID,
+		"date":      date,
+		"cancelled": cancelled,
+		"change":    change,
+		"payments":  payments,
+	})
+}
+
+func seriesRRule(series models.EventSeries) string {
+	r, err := utils.SeriesRecurrence(series)
+	if err != nil {
+		return ""
+	}
+	return r.String()
+}
+
+func respondSeriesError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, utils.ErrInvalidRecurrence), errors.Is(err, utils.ErrNotOccurrence),
+		errors.Is(err, utils.ErrInvalidSchedule), errors.Is(err, models.ErrInvalidClock):
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+	default:
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"ssb_api/models"
+	"ssb_api/models/response"
+	"ssb_api/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Jumlah baris yang ditulis sebelum output dikirim ke client.
+const exportBatchSize = 500
+
+// ExportPaymentsByVendor mengunduh tagihan vendor dalam CSV atau XLSX dengan
+// filter yang sama seperti GetPaymentsByVendor. Query: format (csv, xlsx).
+func ExportPaymentsByVendor(c *gin.Context) {
+	db := tenantDB(c)
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+	q, ok := vendorPaymentsQuery(c, db, vendorID)
+	if !ok {
+		return
+	}
+
+	header := []string{"No. Invoice", "Tanggal", "Jatuh Tempo", "Pemain", "Tipe", "Metode", "Status",
+		"Harga Dasar", "Jumlah", "Terbayar", "Sisa", "Refund", "Periode", "Event ID", "Catatan"}
+	streamExport(c, "tagihan", "Tagihan", header, func(w utils.SpreadsheetWriter) error {
+		return eachRow(q.Order("date ASC, id ASC"), w, func(rows *sql.Rows) error {
+			var p models.Payment
+			if err := db.ScanRows(rows, &p); err != nil {
+				return err
+			}
+			eventID := ""
+			if p.EventID != nil {
+				eventID = fmt.Sprint(*p.EventID)
+			}
+			return w.WriteRow([]utils.ExportCell{
+				utils.TextCell(p.Invoice),
+				utils.DateCell(p.Date.String()),
+				utils.DateCell(p.DueDate.String()),
+				utils.TextCell(p.UserName),
+				utils.TextCell(p.Type),
+				utils.TextCell(p.Method),
+				utils.TextCell(p.Status),
+				utils.MoneyCell(p.GrossAmount),
+				utils.MoneyCell(p.Amount),
+				utils.MoneyCell(p.PaidAmount),
+				utils.MoneyCell(p.Outstanding()),
+				utils.MoneyCell(p.RefundedAmount),
+				utils.TextCell(p.BillingPeriod),
+				utils.TextCell(eventID),
+				utils.TextCell(p.Note),
+			})
+		})
+	})
+}
+
+// ExportUsersByVendor mengunduh daftar pemain vendor. Query: format, search,
+// foot, sort_by, sort_order seperti GetUsersByVendor.
+func ExportUsersByVendor(c *gin.Context) {
+	db := tenantDB(c)
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+
+	q := db.Model(&models.User{}).Where("vendor_id = ?", vendorID)
+	if search := c.Query("search"); search != "" {
+		like := "%" + search + "%"
+		q = q.Where("name ILIKE ? OR email ILIKE ?", like, like)
+	}
+	if foot := c.Query("foot"); foot != "" {
+		q = q.Where("foot = ?", foot)
+	}
+	sortBy := c.DefaultQuery("sort_by", "name")
+	if !map[string]bool{"created_at": true, "updated_at": true, "name": true, "email": true}[sortBy] {
+		sortBy = "name"
+	}
+	sortOrder := "ASC"
+	if c.Query("sort_order") == "desc" {
+		sortOrder = "DESC"
+	}
+
+	header := []string{"ID", "Nama", "Email", "Telepon", "Role", "Jenis Kelamin", "Tanggal Lahir", "Kategori Usia",
+		"Posisi", "Kaki", "Nomor Punggung", "Status", "Aktif", "Match", "Latihan", "Program", "Alamat", "Terdaftar"}
+	streamExport(c, "pemain", "Pemain", header, func(w utils.SpreadsheetWriter) error {
+		return eachRow(q.Order(sortBy+" "+sortOrder).Order("id ASC"), w, func(rows *sql.Rows) error {
+			var u models.User
+			if err := db.ScanRows(rows, &u); err != nil {
+				return err
+			}
+			return w.WriteRow([]utils.ExportCell{
+				utils.IDCell(int(u.ID)),
+				utils.TextCell(u.Name),
+				utils.TextCell(u.Email),
+				utils.TextCell(u.Phone),
+				utils.TextCell(u.Role),
+				utils.TextCell(u.Gender),
+				utils.DateCell(u.BirthDate),
+				utils.TextCell(u.AgeCategory),
+				utils.TextCell(u.Position),
+				utils.TextCell(u.Foot),
+				utils.IDCell(u.Number),
+				utils.TextCell(u.Status),
+				utils.BoolCell(u.Active),
+				utils.NumberCell(float64(u.Match)),
+				utils.NumberCell(float64(u.Training)),
+				utils.NumberCell(float64(u.Program)),
+				utils.TextCell(u.Address),
+				utils.DateTimeCell(u.CreatedAt),
+			})
+		})
+	})
+}
+
+// ExportEventLogs mengunduh kehadiran event vendor. Query: format,
+// event_type, status, event_id, user_id.
+func ExportEventLogs(c *gin.Context) {
+	db := tenantDB(c)
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+
+	q := db.Model(&models.EventLog{}).
+		Select("event_logs.*, events.title AS event_title, events.date AS event_date").
+		Joins("LEFT JOIN events ON events.id = event_logs.event_id").
+		Where("event_logs.vendor_id = ?", vendorID)
+	if eventType := c.Query("event_type"); eventType != "" {
+		q = q.Where("event_logs.event_type = ?", eventType)
+	}
+	if status := c.Query("status"); status != "" {
+		q = q.Where("event_logs.status = ?", status)
+	}
+	if eventID := c.Query("event_id"); eventID != "" {
+		q = q.Where("event_logs.event_id = ?", eventID)
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		q = q.Where("event_logs.user_id = ?", userID)
+	}
+
+	type attendanceRow struct {
+		models.EventLog
+		EventTitle string
+		EventDate  string
+	}
+
+	header := []string{"Tanggal Event", "Event", "Tipe", "Pemain", "User ID", "Hadir", "Catatan", "Dicatat"}
+	streamExport(c, "kehadiran", "Kehadiran", header, func(w utils.SpreadsheetWriter) error {
+		return eachRow(q.Order("events.date ASC, event_logs.id ASC"), w, func(rows *sql.Rows) error {
+			var l attendanceRow
+			if err := db.ScanRows(rows, &l); err != nil {
+				return err
+			}
+			return w.WriteRow([]utils.ExportCell{
+				utils.DateCell(l.EventDate),
+				utils.TextCell(l.EventTitle),
+				utils.TextCell(l.EventType),
+				utils.TextCell(l.UserName),
+				utils.IDCell(int(l.UserID)),
+				utils.BoolCell(l.Status),
+				utils.TextCell(l.Note),
+				utils.DateTimeCell(l.CreatedAt),
+			})
+		})
+	})
+}
+
+// streamExport menulis header response lalu mengalirkan baris ke client.
+// Setelah baris pertama terkirim status tidak bisa diubah lagi, jadi error
+// di tengah jalan hanya dicatat di log.
+func streamExport(c *gin.Context, name, sheet string, header []string, rows func(w utils.SpreadsheetWriter) error) {
+	format := c.DefaultQuery("format", utils.ExportFormatCSV)
+	if format != utils.ExportFormatCSV && format != utils.ExportFormatXLSX {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, utils.ErrUnknownExportFormat.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("20060102-1504"), format)
+	c.Header("Content-Type", utils.ExportContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	w, err := utils.NewSpreadsheetWriter(c.Writer, format, sheet)
+	if err == nil {
+		err = w.WriteHeader(header)
+	}
+	if err == nil {
+		err = rows(w)
+	}
+	if w != nil {
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		log.Printf("export %s failed: %v", name, err)
+		return
+	}
+	c.Writer.Flush()
+}
+
+// eachRow menjalankan fn untuk setiap baris hasil query tanpa memuat semua
+// baris ke memori, lalu mengirim output ke client setiap exportBatchSize baris.
+func eachRow(q *gorm.DB, w utils.SpreadsheetWriter, fn func(rows *sql.Rows) error) error {
+	rows, err := q.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+		if n++; n%exportBatchSize == 0 {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return rows.Err()
+}
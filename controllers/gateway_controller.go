@@ -0,0 +1,266 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"ssb_api/config"
+	"ssb_api/controllers/middleware"
+	"ssb_api/models"
+	"ssb_api/models/response"
+	"ssb_api/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreatePaymentCheckout membuat sesi pembayaran di payment gateway dan
+// mengembalikan pay URL, nomor VA atau string QRIS.
+func CreatePaymentCheckout(c *gin.Context) {
+	db := tenantDB(c)
+	var input struct {
+		Channel string `json:"channel"` // snap, va, qris
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if input.Channel == "" {
+		input.Channel = utils.ChannelSnap
+	}
+
+	var payment models.Payment
+	if err := db.First(&payment, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Payment not found")
+		return
+	}
+
+	currentUser, _ := middleware.CurrentUser(c)
+	if payment.UserID != currentUser.ID {
+		response.JSONErrorResponse(c.Writer, false, http.StatusForbidden, "You can only pay your own payment")
+		return
+	}
+	if !models.CanTransitionPayment(payment.Status, models.PaymentStatusPaid) {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Payment cannot be paid in status "+payment.Status)
+		return
+	}
+
+	provider, ok := utils.DefaultPaymentProvider()
+	if !ok {
+		response.JSONErrorResponse(c.Writer, false, http.StatusServiceUnavailable, "Payment gateway is not configured")
+		return
+	}
+
+	// Pakai ulang sesi yang masih aktif untuk channel yang sama
+	var existing models.PaymentCheckout
+	if err := db.Where("payment_id = ? AND provider = ? AND channel = ? AND status = ? AND amount = ? AND expires_at > ?",
+		payment.ID, provider.Name(), input.Channel, "pending", payment.Outstanding(), time.Now()).
+		First(&existing).Error; err == nil {
+		response.JSONSuccess(c.Writer, true, http.StatusOK, existing)
+		return
+	}
+
+	result, err := provider.CreateCheckout(utils.CheckoutRequest{
+		Payment:  payment,
+		Channel:  input.Channel,
+		Customer: currentUser,
+	})
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	checkout := models.PaymentCheckout{
+		PaymentID: payment.ID,
+		VendorID:  payment.VendorID,
+		Provider:  provider.Name(),
+		Reference: result.Reference,
+		Channel:   result.Channel,
+		Amount:    payment.Outstanding(),
+		PayURL:    result.PayURL,
+		VANumber:  result.VANumber,
+		QRString:  result.QRString,
+		Status:    "pending",
+		ExpiresAt: result.ExpiresAt,
+	}
+	if err := db.Create(&checkout).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to create checkout")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusCreated, checkout)
+}
+
+// PaymentWebhook menerima callback bertanda tangan HMAC dari payment gateway.
+// Endpoint ini publik, keamanannya bergantung pada verifikasi signature.
+func PaymentWebhook(c *gin.Context) {
+	provider, ok := utils.GetPaymentProvider(c.Param("provider"))
+	if !ok {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Unknown payment provider")
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	event, err := provider.ParseWebhook(c.Request.Header, body)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, utils.ErrInvalidSignature) {
+			status = http.StatusUnauthorized
+		}
+		response.JSONErrorResponse(c.Writer, false, status, err.Error())
+		return
+	}
+
+	status, message := applyGatewayEvent(provider.Name(), event)
+	if status != http.StatusOK {
+		response.JSONErrorResponse(c.Writer, false, status, message)
+		return
+	}
+	response.JSONSuccess(c.Writer, true, http.StatusOK, message)
+}
+
+// SimulateFakePayment menandai checkout fake sebagai lunas dengan mengirim
+// webhook bertanda tangan ke PaymentWebhook. Hanya aktif saat provider fake.
+func SimulateFakePayment(c *gin.Context) {
+	provider, ok := utils.DefaultPaymentProvider()
+	fake, isFake := provider.(*utils.FakeProvider)
+	if !ok || !isFake {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Fake payment provider is not active")
+		return
+	}
+
+	var checkout models.PaymentCheckout
+	db := config.DB.WithContext(config.SystemContext())
+	if err := db.Where("reference = ? AND provider = ?", c.Param("reference"), fake.Name()).First(&checkout).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Checkout not found")
+		return
+	}
+
+	body, signature := fake.SimulatePayment(checkout.Reference, checkout.Amount)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	c.Request.Header.Set("X-Signature", signature)
+	c.Params = append(c.Params, gin.Param{Key: "provider", Value: fake.Name()})
+	PaymentWebhook(c)
+}
+
+// applyGatewayEvent memproses hasil pembayaran dari gateway secara idempoten.
+func applyGatewayEvent(providerName string, event utils.WebhookEvent) (int, string) {
+	db := config.DB.WithContext(config.SystemContext())
+
+	var checkout models.PaymentCheckout
+	if err := db.Where("reference = ? AND provider = ?", event.Reference, providerName).First(&checkout).Error; err != nil {
+		return http.StatusNotFound, "Checkout not found"
+	}
+	if checkout.Status == utils.GatewayStatusPaid {
+		return http.StatusOK, "Already processed"
+	}
+
+	if event.Status != utils.GatewayStatusPaid {
+		// Checkout yang sudah lunas tidak boleh ditimpa event yang datang terlambat
+		if err := db.Model(&checkout).Where("status <> ?", utils.GatewayStatusPaid).
+			Update("status", event.Status).Error; err != nil {
+			return http.StatusInternalServerError, "Failed to update checkout"
+		}
+		return http.StatusOK, "Checkout " + event.Status
+	}
+
+	if math.Abs(event.Amount-checkout.Amount) >= 1 {
+		return http.StatusBadRequest, "Paid amount does not match checkout amount"
+	}
+
+	// Dana yang sudah masuk selalu dicatat dan diakui (200) supaya gateway
+	// tidak mengirim ulang. Tagihan yang tidak bisa lagi dilunasi ditandai
+	// untuk refund atau pemeriksaan manual.
+	var payment models.Payment
+	var review string
+	already := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Gateway bisa mengirim ulang webhook bersamaan; checkout dikunci dan
+		// dibaca ulang supaya hanya satu yang melunasi tagihan
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&checkout, checkout.ID).Error; err != nil {
+			return err
+		}
+		if checkout.Status == utils.GatewayStatusPaid {
+			already = true
+			return nil
+		}
+
+		paidAt := event.PaidAt
+		if paidAt.IsZero() {
+			paidAt = time.Now()
+		}
+		if err := tx.Model(&checkout).Updates(map[string]interface{}{
+			"status":  utils.GatewayStatusPaid,
+			"paid_at": paidAt,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, checkout.PaymentID).Error; err != nil {
+			return err
+		}
+		if !models.CanTransitionPayment(payment.Status, models.PaymentStatusPaid) {
+			review = fmt.Sprintf("Dana %s diterima saat tagihan %s berstatus %s, perlu refund atau pemeriksaan manual",
+				utils.FormatRupiah(event.Amount), payment.Invoice, payment.Status)
+			return tx.Model(&checkout).Updates(map[string]interface{}{
+				"needs_review": true,
+				"review_note":  review,
+			}).Error
+		}
+		if err := tx.Model(&payment).Update("method", providerName+":"+checkout.Channel).Error; err != nil {
+			return err
+		}
+		reason := fmt.Sprintf("Paid via %s (%s)", providerName, checkout.Reference)
+		return utils.TransitionPayment(tx, &payment, models.PaymentStatusPaid, nil, reason)
+	})
+	if err != nil {
+		return http.StatusInternalServerError, "Failed to apply payment: " + err.Error()
+	}
+	if already {
+		return http.StatusOK, "Already processed"
+	}
+	if review != "" {
+		log.Printf("gateway checkout %s flagged for review: %s", checkout.Reference, review)
+		return http.StatusOK, "Payment received, flagged for review"
+	}
+
+	var user models.User
+	if err := db.First(&user, payment.UserID).Error; err == nil && user.FCMToken != "" {
+		body := fmt.Sprintf("Hai %s, pembayaran %s sudah kami terima.", user.Name, payment.Invoice)
+		go utils.CreateNotification(user.ID, user.FCMToken, "Pembayaran Berhasil", body, "status_pembayaran")
+	}
+
+	return http.StatusOK, "Payment marked as paid"
+}
+
+// GetGatewayReviews menampilkan checkout yang dananya sudah masuk tetapi
+// tagihannya tidak bisa dilunasi, untuk direfund atau diperiksa manual.
+func GetGatewayReviews(c *gin.Context) {
+	db := tenantDB(c)
+	vendorID, ok := vendorFilter(c)
+	if !ok {
+		return
+	}
+
+	query := db.Where("needs_review = ?", true)
+	if vendorID != nil {
+		query = query.Where("vendor_id = ?", *vendorID)
+	}
+	var checkouts []models.PaymentCheckout
+	if err := query.Order("paid_at DESC").Find(&checkouts).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to fetch checkouts")
+		return
+	}
+	response.JSONSuccess(c.Writer, true, http.StatusOK, checkouts)
+}
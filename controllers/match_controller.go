@@ -2,59 +2,118 @@ package controllers
 
 import (
 	"net/http"
-	"ssb_api/config"
 	"ssb_api/models"
 	"ssb_api/models/response"
-	"strconv"
+	"ssb_api/utils"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// CreateMatch menerima jadwal start_at/end_at ISO-8601, atau date dan time
+// format lama.
 func CreateMatch(c *gin.Context) {
-	var input models.Match
-	if err := c.ShouldBindJSON(&input); err != nil {
+	db := tenantDB(c)
+	var body struct {
+		models.Match
+		StartAt string `json:"start_at"`
+		EndAt   string `json:"end_at"`
+		Time    string `json:"time"`
+		EndTime string `json:"end_time"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request")
 		return
 	}
-	if err := config.DB.Where("id = ?", input.VendorID).First(&models.Vendor{}).Error; err != nil {
+	input := body.Match
+	if !requireVendorAccess(c, input.VendorID) {
+		return
+	}
+	var vendor models.Vendor
+	if err := db.Where("id = ?", input.VendorID).First(&vendor).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Vendor not found")
 		return
 	}
+	loc := vendor.Location()
+	schedule := utils.ScheduleInput{StartAt: body.StartAt, EndAt: body.EndAt, Date: input.Date, Time: body.Time, EndTime: body.EndTime}
+	start, end, err := schedule.Resolve(loc)
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+		return
+	}
+	input.SetSchedule(start, end, loc)
 
-	if err := config.DB.Create(&input).Error; err != nil {
-		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to create training")
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&input).Error; err != nil {
+			return err
+		}
+		if input.Staff == nil {
+			return nil
+		}
+		var err error
+		input.Staff, err = utils.SetStaff(tx, utils.MatchSession(input), input.Staff, loc)
+		return err
+	})
+	if err != nil {
+		if !respondStaffError(c, err) {
+			response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to create training")
+		}
 		return
 	}
 	response.JSONSuccess(c.Writer, true, http.StatusCreated, input)
 }
 
 func GetMatchs(c *gin.Context) {
+	db := tenantDB(c)
+	vendorID, ok := vendorFilter(c)
+	if !ok {
+		return
+	}
+
 	var trainings []models.Match
-	if err := config.DB.Find(&trainings).Error; err != nil {
+	query := db.Model(&models.Match{})
+	if vendorID != nil {
+		query = query.Where("vendor_id = ?", *vendorID)
+	}
+	if err := query.Order("start_at DESC").Find(&trainings).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to fetch trainings")
 		return
 	}
+	localizeMatches(db, trainings)
 	response.JSONSuccess(c.Writer, true, http.StatusOK, trainings)
 }
 
 func GetMatchsByVendor(c *gin.Context) {
-	vendorIDStr := c.Query("vendor_id")
-	if vendorIDStr == "" {
-		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Vendor ID is required")
-		return
-	}
-
-	vendorID, err := strconv.ParseUint(vendorIDStr, 10, 64)
-	if err != nil {
-		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid vendor ID")
+	db := tenantDB(c)
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
 		return
 	}
 
 	var trainings []models.Match
-	if err := config.DB.Where("vendor_id = ?", vendorID).Find(&trainings).Error; err != nil {
+	if err := db.Where("vendor_id = ?", vendorID).Order("start_at DESC").Find(&trainings).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to fetch matchs by vendor")
 		return
 	}
+	localizeMatches(db, trainings)
 
 	response.JSONSuccess(c.Writer, true, http.StatusOK, trainings)
 }
+
+// localizeMatches menampilkan jadwal match dalam zona waktu vendornya.
+func localizeMatches(db *gorm.DB, matches []models.Match) {
+	zones := map[uint]*time.Location{}
+	for i := range matches {
+		if matches[i].VendorID == nil {
+			continue
+		}
+		vendorID := *matches[i].VendorID
+		loc, ok := zones[vendorID]
+		if !ok {
+			loc = utils.VendorLocation(db, vendorID)
+			zones[vendorID] = loc
+		}
+		matches[i].InLocation(loc)
+	}
+}
@@ -0,0 +1,225 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"ssb_api/config"
+	"ssb_api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Permission string
+
+const (
+	PermUserRead     Permission = "user:read"
+	PermUserManage   Permission = "user:manage"
+	PermRoleAssign   Permission = "role:assign"
+	PermVendorManage Permission = "vendor:manage"
+
+	PermPaymentReadOwn Permission = "payment:read_own"
+	PermPaymentRead    Permission = "payment:read"
+	PermPaymentCreate  Permission = "payment:create"
+	PermPaymentManage  Permission = "payment:manage"
+	PermPaymentPay     Permission = "payment:pay"
+	PermBillingRun     Permission = "billing:run"
+	PermPricingManage  Permission = "pricing:manage"
+	PermRefundRequest  Permission = "refund:request"
+	PermRefundApprove  Permission = "refund:approve" // hanya admin
+	PermReportRead     Permission = "report:read"
+
+	PermTrainingRead    Permission = "training:read"
+	PermTrainingManage  Permission = "training:manage"
+	PermMatchRead       Permission = "match:read"
+	PermMatchManage     Permission = "match:manage"
+	PermChallengeRead   Permission = "challenge:read"
+	PermChallengeManage Permission = "challenge:manage"
+
+	PermEventRead        Permission = "event:read"
+	PermEventManage      Permission = "event:manage"
+	PermEventJoin        Permission = "event:join"
+	PermAttendanceManage Permission = "attendance:manage"
+
+	// PermAllVendors membebaskan user dari vendor scoping (platform admin).
+	PermAllVendors Permission = "vendor:all"
+	PermAll        Permission = "*"
+)
+
+// RolePermissions adalah tabel permission per role. Role baru cukup
+// ditambahkan di sini.
+var RolePermissions = map[string][]Permission{
+	models.RolePemain: {
+		PermUserRead,
+		PermPaymentReadOwn,
+		PermPaymentCreate,
+		PermPaymentPay,
+		PermTrainingRead,
+		PermMatchRead,
+		PermChallengeRead,
+		PermEventRead,
+		PermEventJoin,
+	},
+	models.RolePelatih: {
+		PermUserRead,
+		PermUserManage,
+		PermVendorManage,
+		PermPaymentReadOwn,
+		PermPaymentRead,
+		PermPaymentCreate,
+		PermPaymentManage,
+		PermPaymentPay,
+		PermPricingManage,
+		PermRefundRequest,
+		PermReportRead,
+		PermTrainingRead,
+		PermTrainingManage,
+		PermMatchRead,
+		PermMatchManage,
+		PermChallengeRead,
+		PermChallengeManage,
+		PermEventRead,
+		PermEventManage,
+		PermEventJoin,
+		PermAttendanceManage,
+	},
+	models.RoleAdmin: {
+		PermAll,
+	},
+}
+
+// ErrVendorForbidden dikembalikan ScopedVendorID saat user meminta data vendor lain.
+var ErrVendorForbidden = errors.New("you can only access your own vendor")
+
+// HasPermission cek apakah role memiliki permission tertentu.
+func HasPermission(role string, perm Permission) bool {
+	for _, p := range RolePermissions[role] {
+		if p == PermAll || p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission memuat user dari token lalu memastikan role-nya memiliki
+// semua permission yang diminta. Dipasang setelah JWTAuthMiddleware.
+func RequirePermission(perms ...Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := loadCurrentUser(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			c.Abort()
+			return
+		}
+
+		for _, perm := range perms {
+			if !HasPermission(user.Role, perm) {
+				c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Missing permission: %s", perm)})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// loadCurrentUser mengambil user dari database sekali per request dan
+// menyimpannya di context. Role diambil dari database, bukan dari claim,
+// supaya perubahan role langsung berlaku.
+func loadCurrentUser(c *gin.Context) (models.User, bool) {
+	if cached, exists := c.Get("current_user"); exists {
+		return cached.(models.User), true
+	}
+
+	userIDRaw, exists := c.Get("user_id")
+	if !exists {
+		return models.User{}, false
+	}
+	userID, ok := userIDRaw.(float64)
+	if !ok {
+		return models.User{}, false
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, uint(userID)).Error; err != nil {
+		return models.User{}, false
+	}
+
+	c.Set("current_user", user)
+	c.Set("role", user.Role)
+
+	// Pasang tenant di context request supaya semua query ke tabel milik
+	// vendor otomatis terfilter
+	ctx := c.Request.Context()
+	if HasPermission(user.Role, PermAllVendors) {
+		ctx = config.WithPlatformAdmin(ctx)
+	} else {
+		var vendorID uint
+		if user.VendorID != nil {
+			vendorID = *user.VendorID
+		}
+		ctx = config.WithTenant(ctx, vendorID)
+	}
+	c.Request = c.Request.WithContext(ctx)
+
+	return user, true
+}
+
+// CurrentUser mengembalikan user yang sedang login.
+func CurrentUser(c *gin.Context) (models.User, bool) {
+	return loadCurrentUser(c)
+}
+
+// Can cek permission user yang sedang login.
+func Can(c *gin.Context, perm Permission) bool {
+	user, ok := loadCurrentUser(c)
+	return ok && HasPermission(user.Role, perm)
+}
+
+// CanAccessVendor cek apakah user yang sedang login boleh mengakses data vendor
+// tertentu. Platform admin boleh mengakses semua vendor.
+func CanAccessVendor(c *gin.Context, vendorID *uint) bool {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return false
+	}
+	if HasPermission(user.Role, PermAllVendors) {
+		return true
+	}
+	if vendorID == nil || user.VendorID == nil {
+		return false
+	}
+	return *vendorID == *user.VendorID
+}
+
+// ScopedVendorID menentukan vendor yang boleh diakses request ini. Platform
+// admin boleh memilih vendor lewat parameter requested; user lain selalu
+// dikunci ke vendor miliknya dan ditolak bila meminta vendor lain.
+func ScopedVendorID(c *gin.Context, requested string) (uint, error) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return 0, errors.New("user not found")
+	}
+
+	if HasPermission(user.Role, PermAllVendors) {
+		if requested == "" {
+			return 0, errors.New("vendor_id is required")
+		}
+		id, err := strconv.ParseUint(requested, 10, 64)
+		if err != nil {
+			return 0, errors.New("invalid vendor_id")
+		}
+		return uint(id), nil
+	}
+
+	if user.VendorID == nil {
+		return 0, ErrVendorForbidden
+	}
+	if requested != "" && requested != strconv.FormatUint(uint64(*user.VendorID), 10) {
+		return 0, ErrVendorForbidden
+	}
+	return *user.VendorID, nil
+}
@@ -2,10 +2,11 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"ssb_api/config"
+	"ssb_api/controllers/middleware"
 	"ssb_api/models"
 	"ssb_api/models/response"
 	"ssb_api/utils"
@@ -16,12 +17,33 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-func generateInvoiceNumber(userID uint, vendorID *uint) string {
-	return fmt.Sprintf("INV-%d%d%d", vendorID, userID, time.Now().UnixNano())
+// initialPaymentStatus menentukan status awal pembayaran baru. Hanya draft
+// dan issued yang bebas dipilih; paid hanya untuk pengelola (pembayaran
+// tunai) dan awaiting_verification otomatis bila bukti transfer dilampirkan.
+func initialPaymentStatus(c *gin.Context, requested string, hasProof bool) (string, bool) {
+	status := models.NormalizePaymentStatus(requested)
+	switch {
+	case status == "" && hasProof:
+		return models.PaymentStatusAwaitingVerification, true
+	case status == "":
+		return models.PaymentStatusIssued, true
+	case status == models.PaymentStatusDraft || status == models.PaymentStatusIssued:
+		if hasProof {
+			return models.PaymentStatusAwaitingVerification, true
+		}
+		return status, true
+	case status == models.PaymentStatusAwaitingVerification && hasProof:
+		return status, true
+	case status == models.PaymentStatusPaid && middleware.Can(c, middleware.PermPaymentManage):
+		return status, true
+	}
+	return "", false
 }
 func CreatePayment(c *gin.Context) {
+	db := tenantDB(c)
 	var input models.PaymentRequest
 
 	// Binding form-data ke struct PaymentRequest (tanpa file)
@@ -35,9 +57,19 @@ func CreatePayment(c *gin.Context) {
 	// Log form data untuk debugging
 	fmt.Printf("Received Form Data: %+v\n", input)
 
-	// Get UserID from JWT token
-
+	// Pemain hanya boleh membuat pembayaran untuk dirinya sendiri
 	userID := input.UserID
+	if !middleware.Can(c, middleware.PermPaymentManage) {
+		currentUser, _ := middleware.CurrentUser(c)
+		if userID != currentUser.ID {
+			response.JSONErrorResponse(c.Writer, false, http.StatusForbidden, "You can only create payments for yourself")
+			return
+		}
+	}
+	if !requireVendorAccess(c, input.VendorID) {
+		return
+	}
+
 	var eventID *uint
 	if input.EventID != nil && *input.EventID != 0 {
 		eventID = input.EventID
@@ -45,24 +77,40 @@ func CreatePayment(c *gin.Context) {
 		eventID = nil
 	}
 
+	// Tanggal format lama (DD/MM/YYYY) masih diterima, disimpan sebagai ISO
+	date, errDate := models.ParseDate(input.Date)
+	dueDate, errDue := models.ParseDate(input.DueDate)
+	if errDate != nil || errDue != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid date or due_date, use YYYY-MM-DD")
+		return
+	}
+
+	_, proofErr := c.FormFile("photo")
+	status, ok := initialPaymentStatus(c, input.Status, proofErr == nil)
+	if !ok {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid initial payment status")
+		return
+	}
+
 	// Mengonversi PaymentRequest ke Payment
 	payment := models.Payment{
-		UserID:   userID,
-		UserName: input.UserName,
-		VendorID: input.VendorID,
-		EventID:  eventID,
-		Amount:   input.Amount,
-		Method:   input.Method,
-		Status:   input.Status,
-		Type:     input.Type,
-		Date:     input.Date,
-		Note:     input.Note,
-		Invoice:  generateInvoiceNumber(userID, input.VendorID),
+		UserID:    userID,
+		UserName:  input.UserName,
+		VendorID:  input.VendorID,
+		EventID:   eventID,
+		Amount:    input.Amount,
+		Method:    input.Method,
+		Status:    status,
+		Type:      input.Type,
+		Date:      date,
+		Note:      input.Note,
+		DueDate:   dueDate,
+		PromoCode: input.PromoCode,
 	}
 
 	// Mengambil vendor berdasarkan VendorID
 	var vendor models.Vendor
-	if err := config.DB.First(&vendor, payment.VendorID).Error; err != nil {
+	if err := db.First(&vendor, payment.VendorID).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Vendor not found")
 		return
 	}
@@ -74,7 +122,7 @@ func CreatePayment(c *gin.Context) {
 		fmt.Println("Uploaded Photo Filename:", file.Filename)
 
 		// Buat direktori tujuan
-		dstDir := fmt.Sprintf("./uploads/payment/%d/%s/%d", payment.VendorID, payment.Type, userID)
+		dstDir := fmt.Sprintf("./uploads/payment/%d/%s/%d", payment.VendorID, payment.Type, payment.UserID)
 		if err := os.MkdirAll(dstDir, os.ModePerm); err != nil {
 			response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to create directory for payment photo")
 			return
@@ -107,8 +155,15 @@ func CreatePayment(c *gin.Context) {
 		payment.Photo = ""
 	}
 
-	// Menyimpan record pembayaran ke dalam database
-	if err := config.DB.Create(&payment).Error; err != nil {
+	// Menyimpan record pembayaran beserta riwayat status awalnya
+	currentUser, _ := middleware.CurrentUser(c)
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return utils.CreatePaymentRecord(tx, &payment, &currentUser, "Payment created")
+	}); err != nil {
+		if errors.Is(err, utils.ErrPromoNotFound) || errors.Is(err, utils.ErrPromoInvalid) || errors.Is(err, utils.ErrPromoExhausted) {
+			response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+			return
+		}
 		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to create payment")
 		return
 	}
@@ -117,16 +172,18 @@ func CreatePayment(c *gin.Context) {
 	response.JSONSuccess(c.Writer, true, http.StatusCreated, payment)
 }
 func CreateBulkPaymentByEvent(c *gin.Context) {
+	db := tenantDB(c)
 	// Definisikan struct untuk input langsung dari body
 	var input struct {
-		VendorID uint    `json:"vendor_id"`
-		EventID  uint    `json:"event_id"`
-		Amount   float64 `json:"amount"`
-		Method   string  `json:"method"`
-		Status   string  `json:"status"`
-		Type     string  `json:"type"`
-		Date     string  `json:"date"`
-		Note     string  `json:"note"`
+		VendorID uint        `json:"vendor_id"`
+		EventID  uint        `json:"event_id"`
+		Amount   float64     `json:"amount"`
+		Method   string      `json:"method"`
+		Status   string      `json:"status"`
+		Type     string      `json:"type"`
+		Date     models.Date `json:"date"`
+		DueDate  models.Date `json:"due_date"`
+		Note     string      `json:"note"`
 	}
 
 	// Validasi input request
@@ -135,24 +192,21 @@ func CreateBulkPaymentByEvent(c *gin.Context) {
 		return
 	}
 
-	// Ambil user ID dari JWT
-	userIDRaw, exists := c.Get("user_id")
-	if !exists {
-		response.JSONErrorResponse(c.Writer, false, http.StatusUnauthorized, "User ID not found in token")
+	// Pelatih hanya boleh menagih pemain di vendor miliknya
+	if !requireVendorAccess(c, &input.VendorID) {
 		return
 	}
-	userID := uint(userIDRaw.(float64))
 
-	// Pastikan hanya pelatih yang bisa membuat bulk payment
-	var creator models.User
-	if err := config.DB.First(&creator, userID).Error; err != nil || creator.Role != "pelatih" {
-		response.JSONErrorResponse(c.Writer, false, http.StatusUnauthorized, "Only coaches can perform this action")
+	status, ok := initialPaymentStatus(c, input.Status, false)
+	if !ok {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid initial payment status")
 		return
 	}
+	creator, _ := middleware.CurrentUser(c)
 
 	// Ambil semua user_id dari event_logs yang memiliki status true, berdasarkan vendor dan event
 	var userIDs []uint
-	if err := config.DB.
+	if err := db.
 		Model(&models.EventLog{}).
 		Where("vendor_id = ? AND event_id = ? AND status = ?", input.VendorID, input.EventID, true). // filter status log = true
 		Pluck("user_id", &userIDs).Error; err != nil {
@@ -164,7 +218,7 @@ func CreateBulkPaymentByEvent(c *gin.Context) {
 	var createdPayments []models.Payment
 	for _, uid := range userIDs {
 		var user models.User
-		if err := config.DB.Select("name").First(&user, uid).Error; err != nil {
+		if err := db.Select("name").First(&user, uid).Error; err != nil {
 			continue // skip kalau user tidak ditemukan
 		}
 
@@ -175,13 +229,16 @@ func CreateBulkPaymentByEvent(c *gin.Context) {
 			EventID:  &input.EventID,
 			Amount:   input.Amount,
 			Method:   input.Method,
-			Status:   input.Status,
+			Status:   status,
 			Type:     input.Type,
 			Date:     input.Date,
 			Note:     input.Note,
-			Invoice:  generateInvoiceNumber(uid, &input.VendorID),
+			DueDate:  input.DueDate,
 		}
-		if err := config.DB.Create(&payment).Error; err == nil {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return utils.CreatePaymentRecord(tx, &payment, &creator, "Bulk payment by event")
+		})
+		if err == nil {
 			createdPayments = append(createdPayments, payment)
 			if user.FCMToken != "" {
 				title := "Tagihan Baru"
@@ -200,6 +257,7 @@ func CreateBulkPaymentByEvent(c *gin.Context) {
 }
 
 func UploadPaymentProof(c *gin.Context) {
+	db := tenantDB(c)
 	// Ambil payment_id dari form data
 	paymentID := c.DefaultPostForm("payment_id", "")
 	if paymentID == "" {
@@ -207,27 +265,27 @@ func UploadPaymentProof(c *gin.Context) {
 		return
 	}
 
-	// Ambil user ID dari JWT token
-	userIDRaw, exists := c.Get("user_id")
-	if !exists {
-		response.JSONErrorResponse(c.Writer, false, http.StatusUnauthorized, "User ID not found in token")
-		return
-	}
-	userID := uint(userIDRaw.(float64))
-
 	// Ambil data payment dari database berdasarkan payment_id
 	var payment models.Payment
-	if err := config.DB.First(&payment, paymentID).Error; err != nil {
+	if err := db.First(&payment, paymentID).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Payment not found")
 		return
 	}
 
-	// Cek apakah user yang sedang login adalah pemiliknya
-	if payment.UserID != userID {
+	// Hanya pemilik tagihan atau pengelola pembayaran yang boleh mengunggah bukti
+	currentUser, _ := middleware.CurrentUser(c)
+	if payment.UserID != currentUser.ID && !middleware.Can(c, middleware.PermPaymentManage) {
 		response.JSONErrorResponse(c.Writer, false, http.StatusForbidden, "You are not allowed to upload proof for this payment")
 		return
 	}
 
+	// Bukti hanya bisa diunggah selama tagihan belum lunas atau dibatalkan
+	alreadyAwaiting := payment.Status == models.PaymentStatusAwaitingVerification
+	if !alreadyAwaiting && !models.CanTransitionPayment(payment.Status, models.PaymentStatusAwaitingVerification) {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Proof cannot be uploaded for a "+payment.Status+" payment")
+		return
+	}
+
 	// Ambil file 'photo' dari form data
 	file, err := c.FormFile("photo")
 	if err != nil {
@@ -236,7 +294,7 @@ func UploadPaymentProof(c *gin.Context) {
 	}
 
 	// Tentukan direktori penyimpanan file
-	dstDir := fmt.Sprintf("./uploads/payment/%d/%s/%d", payment.VendorID, payment.Type, userID)
+	dstDir := fmt.Sprintf("./uploads/payment/%d/%s/%d", payment.VendorID, payment.Type, payment.UserID)
 	if err := os.MkdirAll(dstDir, os.ModePerm); err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to create directory")
 		return
@@ -253,12 +311,19 @@ func UploadPaymentProof(c *gin.Context) {
 	}
 
 	// Update path foto di database
-	payment.Photo = dst
-	if err := config.DB.Save(&payment).Error; err != nil {
+	if err := db.Model(&payment).Update("photo", dst).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to update payment record")
 		return
 	}
 
+	// Tagihan menunggu verifikasi pelatih
+	if !alreadyAwaiting {
+		if err := utils.TransitionPayment(db, &payment, models.PaymentStatusAwaitingVerification, &currentUser, "Proof uploaded"); err != nil {
+			response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to update payment status")
+			return
+		}
+	}
+
 	// Kirim respons sukses
 	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
 		"message": "Proof uploaded successfully",
@@ -267,6 +332,7 @@ func UploadPaymentProof(c *gin.Context) {
 }
 
 func GetPaymentsUser(c *gin.Context) {
+	db := tenantDB(c)
 	userIDRaw, exists := c.Get("user_id")
 	if !exists {
 		response.JSONErrorResponse(c.Writer, false, http.StatusUnauthorized, "User ID not found in token")
@@ -287,8 +353,10 @@ func GetPaymentsUser(c *gin.Context) {
 
 	// Filter param
 	status := c.Query("status")
-	startDate := c.Query("start_date") // Format: YYYY-MM-DD
-	endDate := c.Query("end_date")     // Format: YYYY-MM-DD
+	startDate, endDate, ok := dateRangeParams(c)
+	if !ok {
+		return
+	}
 	sortBy := c.DefaultQuery("sort_by", "created_at")
 	sortOrder := strings.ToUpper(c.DefaultQuery("sort_order", "DESC"))
 
@@ -296,7 +364,7 @@ func GetPaymentsUser(c *gin.Context) {
 		sortOrder = "DESC"
 	}
 
-	query := config.DB.Where("user_id = ?", userID)
+	query := db.Where("user_id = ?", userID)
 
 	if search != "" {
 		like := "%" + strings.ToLower(search) + "%"
@@ -304,7 +372,7 @@ func GetPaymentsUser(c *gin.Context) {
 	}
 
 	if status != "" {
-		query = query.Where("status = ?", status)
+		query = query.Where("status = ?", models.NormalizePaymentStatus(status))
 	}
 	if startDate != "" {
 		query = query.Where("date >= ?", startDate)
@@ -336,6 +404,7 @@ func GetPaymentsUser(c *gin.Context) {
 }
 
 func GetPayments(c *gin.Context) {
+	db := tenantDB(c)
 	// Optional: pagination
 	limitStr := c.DefaultQuery("limit", "10")
 	pageStr := c.DefaultQuery("page", "1")
@@ -345,8 +414,10 @@ func GetPayments(c *gin.Context) {
 
 	// Filter param
 	status := c.Query("status")
-	startDate := c.Query("start_date") // Format: YYYY-MM-DD
-	endDate := c.Query("end_date")     // Format: YYYY-MM-DD
+	startDate, endDate, ok := dateRangeParams(c)
+	if !ok {
+		return
+	}
 	sortBy := c.DefaultQuery("sort_by", "created_at")
 	sortOrder := strings.ToUpper(c.DefaultQuery("sort_order", "DESC"))
 
@@ -354,11 +425,19 @@ func GetPayments(c *gin.Context) {
 		sortOrder = "DESC"
 	}
 
+	vendorID, ok := vendorFilter(c)
+	if !ok {
+		return
+	}
+
 	var payments []models.Payment
-	query := config.DB.Model(&models.Payment{})
+	query := db.Model(&models.Payment{})
+	if vendorID != nil {
+		query = query.Where("vendor_id = ?", *vendorID)
+	}
 
 	if status != "" {
-		query = query.Where("status = ?", status)
+		query = query.Where("status = ?", models.NormalizePaymentStatus(status))
 	}
 	if startDate != "" {
 		query = query.Where("date >= ?", startDate)
@@ -389,15 +468,10 @@ func GetPayments(c *gin.Context) {
 }
 
 func GetPaymentsByVendor(c *gin.Context) {
+	db := tenantDB(c)
 	// ======= Parsing query param =======
-	vendorIDStr := c.Query("vendor_id")
-	if vendorIDStr == "" {
-		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "vendor_id is required")
-		return
-	}
-	vendorID, err := strconv.ParseUint(vendorIDStr, 10, 64)
-	if err != nil {
-		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "invalid vendor_id")
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
 		return
 	}
 
@@ -411,33 +485,9 @@ func GetPaymentsByVendor(c *gin.Context) {
 	}
 
 	// ======= Build query with filters =======
-	q := config.DB.Model(&models.Payment{}).Where("vendor_id = ?", vendorID)
-
-	if s := c.Query("search"); s != "" {
-		q = q.Where("LOWER(note) LIKE ?", "%"+strings.ToLower(s)+"%")
-	}
-	if s := c.Query("status"); s != "" {
-		q = q.Where("status = ?", s)
-	}
-	if u := c.Query("user_name"); u != "" {
-		q = q.Where("user_name ILIKE ?", "%"+u+"%")
-	}
-	if s := c.Query("start_date"); s != "" {
-		q = q.Where("date >= ?", s)
-	}
-	if e := c.Query("end_date"); e != "" {
-		q = q.Where("date <= ?", e)
-	}
-	// ✅ Filter baru: event_id
-	// ✅ Filter event_id dengan parsing ke int
-	if eid := c.Query("event_id"); eid != "" {
-		id, err := strconv.Atoi(eid)
-		if err != nil {
-			response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "invalid event_id")
-			return
-		}
-		// hanya ambil baris dengan event_id = id (bukan NULL)
-		q = q.Where("event_id = ?", id)
+	q, ok := vendorPaymentsQuery(c, db, vendorID)
+	if !ok {
+		return
 	}
 
 	// ======= Execute query =======
@@ -461,10 +511,50 @@ func GetPaymentsByVendor(c *gin.Context) {
 	response.JSONSuccess(c.Writer, true, http.StatusOK, payments)
 }
 
+// vendorPaymentsQuery menerapkan filter GetPaymentsByVendor (search, status,
+// user_name, start_date, end_date, event_id). Dipakai juga oleh export.
+func vendorPaymentsQuery(c *gin.Context, db *gorm.DB, vendorID uint) (*gorm.DB, bool) {
+	q := db.Model(&models.Payment{}).Where("vendor_id = ?", vendorID)
+
+	if s := c.Query("search"); s != "" {
+		q = q.Where("LOWER(note) LIKE ?", "%"+strings.ToLower(s)+"%")
+	}
+	if s := c.Query("status"); s != "" {
+		q = q.Where("status = ?", models.NormalizePaymentStatus(s))
+	}
+	if u := c.Query("user_name"); u != "" {
+		q = q.Where("user_name ILIKE ?", "%"+u+"%")
+	}
+	startDate, endDate, ok := dateRangeParams(c)
+	if !ok {
+		return nil, false
+	}
+	if startDate != "" {
+		q = q.Where("date >= ?", startDate)
+	}
+	if endDate != "" {
+		q = q.Where("date <= ?", endDate)
+	}
+	// ✅ Filter baru: event_id
+	// ✅ Filter event_id dengan parsing ke int
+	if eid := c.Query("event_id"); eid != "" {
+		id, err := strconv.Atoi(eid)
+		if err != nil {
+			response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "invalid event_id")
+			return nil, false
+		}
+		// hanya ambil baris dengan event_id = id (bukan NULL)
+		q = q.Where("event_id = ?", id)
+	}
+	return q, true
+}
+
 func UpdatePaymentStatus(c *gin.Context) {
+	db := tenantDB(c)
 	var input struct {
 		PaymentID uint   `json:"payment_id"`
-		Status    string `json:"status"` // "pending", "success", "failed"
+		Status    string `json:"status"` // lihat models.PaymentTransitions
+		Reason    string `json:"reason"`
 	}
 
 	// Binding request body
@@ -473,47 +563,79 @@ func UpdatePaymentStatus(c *gin.Context) {
 		return
 	}
 
-	// Get UserID from JWT token
-	userIDRaw, exists := c.Get("user_id")
-	if !exists {
-		response.JSONErrorResponse(c.Writer, false, http.StatusUnauthorized, "User ID not found in token")
+	// Find the payment record
+	var payment models.Payment
+	if err := db.First(&payment, input.PaymentID).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Payment not found")
 		return
 	}
-	userID := uint(userIDRaw.(float64)) // default JWT parsing returns float64
 
-	// Retrieve user details from database
-	var user models.User
-	if err := config.DB.First(&user, userID).Error; err != nil {
-		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "User not found")
+	// Check if the payment belongs to the same vendor as the logged-in trainer
+	if !requireVendorAccess(c, payment.VendorID) {
 		return
 	}
 
-	// Only allow trainers (pelatih) to update payment status
-	if user.Role != "pelatih" {
-		response.JSONErrorResponse(c.Writer, false, http.StatusForbidden, "Only trainers can update payment status")
+	// Update the payment status following the lifecycle
+	status := models.NormalizePaymentStatus(input.Status)
+	if !models.IsValidPaymentStatus(status) {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Unknown payment status")
+		return
+	}
+	if status == models.PaymentStatusRejected && input.Reason == "" {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Reason is required when rejecting a payment")
+		return
+	}
+	// Refund dan pembatalan tagihan yang sudah dibayar harus lewat alur refund
+	// supaya ada persetujuan admin, nota kredit dan refunded_amount
+	if status == models.PaymentStatusRefunded {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Use the refund request to refund a payment")
+		return
+	}
+	if status == models.PaymentStatusCancelled && payment.PaidAmount > 0 {
+		response.JSONErrorResponse(c.Writer, false, http.StatusConflict, "Payment already has money on it, request a refund instead of cancelling")
 		return
 	}
 
-	// Find the payment record
+	actor, _ := middleware.CurrentUser(c)
+	if err := utils.TransitionPayment(db, &payment, status, &actor, input.Reason); err != nil {
+		var invalid utils.ErrInvalidPaymentTransition
+		if errors.As(err, &invalid) {
+			response.JSONErrorResponse(c.Writer, false, http.StatusConflict, err.Error())
+			return
+		}
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to update payment status")
+		return
+	}
+
+	// Return success response
+	response.JSONSuccess(c.Writer, true, http.StatusOK, "Update payment succesfully")
+}
+
+func GetPaymentHistory(c *gin.Context) {
+	db := tenantDB(c)
+
 	var payment models.Payment
-	if err := config.DB.First(&payment, input.PaymentID).Error; err != nil {
+	if err := db.First(&payment, c.Param("id")).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Payment not found")
 		return
 	}
 
-	// // Check if the payment belongs to the same vendor as the logged-in trainer
-	// if payment.VendorID != user.VendorID {
-	// 	response.JSONErrorResponse(c.Writer, false, http.StatusForbidden, "You can only update payments for your own vendor")
-	// 	return
-	// }
+	// Pemain hanya boleh melihat riwayat tagihannya sendiri
+	currentUser, _ := middleware.CurrentUser(c)
+	if payment.UserID != currentUser.ID && !middleware.Can(c, middleware.PermPaymentRead) {
+		response.JSONErrorResponse(c.Writer, false, http.StatusForbidden, "You are not allowed to view this payment")
+		return
+	}
 
-	// Update the payment status
-	payment.Status = input.Status
-	if err := config.DB.Save(&payment).Error; err != nil {
-		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to update payment status")
+	var histories []models.PaymentStatusHistory
+	if err := db.Where("payment_id = ?", payment.ID).Order("created_at ASC").Find(&histories).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to fetch payment history")
 		return
 	}
 
-	// Return success response
-	response.JSONSuccess(c.Writer, true, http.StatusOK, "Update payment succesfully")
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
+		"payment_id": payment.ID,
+		"status":     payment.Status,
+		"history":    histories,
+	})
 }
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"ssb_api/controllers/middleware"
+	"ssb_api/models"
+	"ssb_api/models/response"
+	"ssb_api/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetPaymentInvoicePDF mengunduh invoice pembayaran dalam format PDF.
+func GetPaymentInvoicePDF(c *gin.Context) {
+	renderPaymentDocument(c, utils.PaymentDocumentInvoice)
+}
+
+// GetPaymentReceiptPDF mengunduh kwitansi untuk pembayaran yang sudah lunas.
+func GetPaymentReceiptPDF(c *gin.Context) {
+	renderPaymentDocument(c, utils.PaymentDocumentReceipt)
+}
+
+func renderPaymentDocument(c *gin.Context, kind string) {
+	db := tenantDB(c)
+
+	var payment models.Payment
+	if err := db.Preload("LineItems", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("id ASC")
+	}).First(&payment, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Payment not found")
+		return
+	}
+
+	// Pemain hanya boleh mengunduh dokumen tagihannya sendiri
+	currentUser, _ := middleware.CurrentUser(c)
+	if payment.UserID != currentUser.ID && !middleware.Can(c, middleware.PermPaymentRead) {
+		response.JSONErrorResponse(c.Writer, false, http.StatusForbidden, "You are not allowed to view this payment")
+		return
+	}
+
+	doc := utils.PaymentDocument{Kind: kind, Payment: payment}
+
+	switch kind {
+	case utils.PaymentDocumentReceipt:
+		if payment.Status != models.PaymentStatusPaid && payment.Status != models.PaymentStatusRefunded {
+			response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Receipt is only available for paid payments")
+			return
+		}
+		var history models.PaymentStatusHistory
+		if err := db.Where("payment_id = ? AND to_status = ?", payment.ID, models.PaymentStatusPaid).
+			Order("created_at DESC").First(&history).Error; err == nil {
+			doc.PaidAt = &history.CreatedAt
+		} else {
+			doc.PaidAt = &payment.UpdatedAt
+		}
+	default:
+		if payment.Status == models.PaymentStatusDraft {
+			response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invoice is not issued yet")
+			return
+		}
+	}
+
+	if payment.VendorID != nil {
+		db.First(&doc.Vendor, *payment.VendorID)
+	}
+	if err := db.First(&doc.Player, payment.UserID).Error; err != nil {
+		doc.Player.Name = payment.UserName
+	}
+	if payment.EventID != nil {
+		var event models.Event
+		if err := db.First(&event, *payment.EventID).Error; err == nil {
+			doc.Event = &event
+		}
+	}
+
+	filename := fmt.Sprintf("%s-%d.pdf", kind, payment.ID)
+	if payment.Invoice != "" {
+		filename = fmt.Sprintf("%s-%s.pdf", kind, strings.NewReplacer("/", "-", "\\", "-", "\"", "").Replace(payment.Invoice))
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", filename))
+	c.Data(http.StatusOK, "application/pdf", utils.RenderPaymentPDF(doc))
+}
@@ -0,0 +1,305 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"ssb_api/controllers/middleware"
+	"ssb_api/models"
+	"ssb_api/models/response"
+	"ssb_api/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AddPaymentTransaction mencatat cicilan untuk sebuah tagihan. Cicilan dari
+// pemain wajib melampirkan bukti dan menunggu verifikasi, cicilan yang
+// dicatat pengelola (misal tunai) langsung dikonfirmasi.
+func AddPaymentTransaction(c *gin.Context) {
+	db := tenantDB(c)
+
+	var payment models.Payment
+	if err := db.First(&payment, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Payment not found")
+		return
+	}
+
+	currentUser, _ := middleware.CurrentUser(c)
+	isManager := middleware.Can(c, middleware.PermPaymentManage)
+	if payment.UserID != currentUser.ID && !isManager {
+		response.JSONErrorResponse(c.Writer, false, http.StatusForbidden, "You can only pay your own payment")
+		return
+	}
+	if payment.Outstanding() <= 0 || !models.CanTransitionPayment(payment.Status, models.PaymentStatusPaid) {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Payment cannot receive transactions in status "+payment.Status)
+		return
+	}
+
+	amount, err := strconv.ParseFloat(c.PostForm("amount"), 64)
+	if err != nil || amount <= 0 {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Amount must be greater than 0")
+		return
+	}
+
+	txn := models.PaymentTransaction{
+		Amount: amount,
+		Method: c.DefaultPostForm("method", "transfer"),
+		Date:   c.DefaultPostForm("date", time.Now().Format("2006-01-02")),
+		Note:   c.PostForm("note"),
+	}
+
+	file, err := c.FormFile("photo")
+	if err == nil {
+		var vendorID uint
+		if payment.VendorID != nil {
+			vendorID = *payment.VendorID
+		}
+		dstDir := fmt.Sprintf("./uploads/payment/%d/%s/%d", vendorID, payment.Type, payment.UserID)
+		if err := os.MkdirAll(dstDir, os.ModePerm); err != nil {
+			response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to create directory")
+			return
+		}
+		filename := fmt.Sprintf("installment_%d_%d%s", payment.ID, time.Now().Unix(), filepath.Ext(file.Filename))
+		dst := fmt.Sprintf("%s/%s", dstDir, filename)
+		if err := c.SaveUploadedFile(file, dst); err != nil {
+			response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to save file")
+			return
+		}
+		txn.Photo = dst
+	} else if !isManager {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Proof of payment is required")
+		return
+	}
+
+	if isManager {
+		if err := utils.ApplyPaymentTransaction(db, &payment, &txn, &currentUser); err != nil {
+			response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to record transaction: "+err.Error())
+			return
+		}
+		response.JSONSuccess(c.Writer, true, http.StatusCreated, gin.H{
+			"transaction": txn,
+			"payment":     payment,
+			"outstanding": payment.Outstanding(),
+		})
+		return
+	}
+
+	// Cicilan dari pemain menunggu verifikasi pelatih
+	err = db.Transaction(func(tx *gorm.DB) error {
+		txn.PaymentID = payment.ID
+		txn.VendorID = payment.VendorID
+		txn.UserID = payment.UserID
+		txn.Status = models.PaymentTransactionPending
+		if err := tx.Create(&txn).Error; err != nil {
+			return err
+		}
+		if payment.Status == models.PaymentStatusAwaitingVerification {
+			return nil
+		}
+		return utils.TransitionPayment(tx, &payment, models.PaymentStatusAwaitingVerification, &currentUser,
+			"Installment submitted "+utils.FormatRupiah(txn.Amount))
+	})
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to record transaction")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusCreated, gin.H{
+		"transaction": txn,
+		"payment":     payment,
+		"outstanding": payment.Outstanding(),
+	})
+}
+
+// VerifyPaymentTransaction menyetujui atau menolak cicilan dari pemain.
+func VerifyPaymentTransaction(c *gin.Context) {
+	db := tenantDB(c)
+	var input struct {
+		Approve bool   `json:"approve"`
+		Reason  string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !input.Approve && input.Reason == "" {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Reason is required when rejecting a transaction")
+		return
+	}
+
+	var txn models.PaymentTransaction
+	if err := db.First(&txn, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Transaction not found")
+		return
+	}
+	if !requireVendorAccess(c, txn.VendorID) {
+		return
+	}
+	if txn.Status != models.PaymentTransactionPending {
+		response.JSONErrorResponse(c.Writer, false, http.StatusConflict, "Transaction is already "+txn.Status)
+		return
+	}
+
+	var payment models.Payment
+	if err := db.First(&payment, txn.PaymentID).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Payment not found")
+		return
+	}
+
+	actor, _ := middleware.CurrentUser(c)
+	var err error
+	if input.Approve {
+		txn.Reason = input.Reason
+		err = utils.ApplyPaymentTransaction(db, &payment, &txn, &actor)
+	} else {
+		err = rejectPaymentTransaction(db, &payment, &txn, &actor, input.Reason)
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		var invalid utils.ErrInvalidPaymentTransition
+		if errors.As(err, &invalid) || errors.Is(err, utils.ErrPaymentNotPayable) {
+			status = http.StatusConflict
+		}
+		response.JSONErrorResponse(c.Writer, false, status, err.Error())
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
+		"transaction": txn,
+		"payment":     payment,
+		"outstanding": payment.Outstanding(),
+	})
+}
+
+// rejectPaymentTransaction menolak cicilan lalu mengembalikan status tagihan:
+// partially_paid bila sudah ada cicilan lain yang masuk, selain itu rejected.
+func rejectPaymentTransaction(db *gorm.DB, payment *models.Payment, txn *models.PaymentTransaction, actor *models.User, reason string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(txn).Updates(map[string]interface{}{
+			"status":         models.PaymentTransactionRejected,
+			"reason":         reason,
+			"verified_by_id": actor.ID,
+		}).Error; err != nil {
+			return err
+		}
+
+		var pending int64
+		if err := tx.Model(&models.PaymentTransaction{}).
+			Where("payment_id = ? AND status = ?", payment.ID, models.PaymentTransactionPending).
+			Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 || payment.Status != models.PaymentStatusAwaitingVerification {
+			return nil
+		}
+
+		next := models.PaymentStatusRejected
+		if payment.PaidAmount > 0 {
+			next = models.PaymentStatusPartiallyPaid
+		}
+		return utils.TransitionPayment(tx, payment, next, actor, reason)
+	})
+}
+
+// GetPaymentTransactions menampilkan semua cicilan sebuah tagihan beserta
+// sisa tagihannya.
+func GetPaymentTransactions(c *gin.Context) {
+	db := tenantDB(c)
+
+	var payment models.Payment
+	if err := db.First(&payment, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Payment not found")
+		return
+	}
+
+	currentUser, _ := middleware.CurrentUser(c)
+	if payment.UserID != currentUser.ID && !middleware.Can(c, middleware.PermPaymentRead) {
+		response.JSONErrorResponse(c.Writer, false, http.StatusForbidden, "You are not allowed to view this payment")
+		return
+	}
+
+	var txns []models.PaymentTransaction
+	if err := db.Where("payment_id = ?", payment.ID).Order("created_at ASC").Find(&txns).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to fetch transactions")
+		return
+	}
+
+	baseURL := utils.DotEnv("BASE_URL_F")
+	for i := range txns {
+		if txns[i].Photo != "" {
+			txns[i].Photo = baseURL + "/" + strings.TrimPrefix(txns[i].Photo, "./")
+		}
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
+		"payment_id":   payment.ID,
+		"amount":       payment.Amount,
+		"paid_amount":  payment.PaidAmount,
+		"outstanding":  payment.Outstanding(),
+		"status":       payment.Status,
+		"transactions": txns,
+	})
+}
+
+// GetAccountStatement menampilkan rekening koran pemain: tagihan, pembayaran
+// dan saldo berjalan. Query: vendor_id, start_date, end_date (YYYY-MM-DD).
+func GetAccountStatement(c *gin.Context) {
+	db := tenantDB(c)
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid user id")
+		return
+	}
+
+	var player models.User
+	if err := db.First(&player, userID).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "User not found")
+		return
+	}
+
+	// Pemain hanya boleh melihat rekeningnya sendiri
+	currentUser, _ := middleware.CurrentUser(c)
+	if player.ID != currentUser.ID {
+		if !middleware.Can(c, middleware.PermPaymentRead) {
+			response.JSONErrorResponse(c.Writer, false, http.StatusForbidden, "You are not allowed to view this statement")
+			return
+		}
+		if !requireVendorAccess(c, player.VendorID) {
+			return
+		}
+	}
+
+	vendorID := player.VendorID
+	if v := c.Query("vendor_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid vendor_id")
+			return
+		}
+		vid := uint(id)
+		if !requireVendorAccess(c, &vid) {
+			return
+		}
+		vendorID = &vid
+	}
+
+	startDate, endDate, ok := dateRangeParams(c)
+	if !ok {
+		return
+	}
+
+	statement, err := utils.BuildAccountStatement(db, player.ID, vendorID, startDate.String(), endDate.String())
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to build account statement")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, statement)
+}
@@ -0,0 +1,405 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"ssb_api/controllers/middleware"
+	"ssb_api/models"
+	"ssb_api/models/response"
+	"ssb_api/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetPricingRules menampilkan aturan harga vendor sesuai urutan penerapannya.
+func GetPricingRules(c *gin.Context) {
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+
+	var rules []models.PricingRule
+	if err := tenantDB(c).Where("vendor_id = ?", vendorID).Order("priority ASC, id ASC").Find(&rules).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to fetch pricing rules")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, rules)
+}
+
+// CreatePricingRule membuat aturan potongan otomatis (general, early bird,
+// saudara).
+func CreatePricingRule(c *gin.Context) {
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+
+	var rule models.PricingRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if rule.Name == "" {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Name is required")
+		return
+	}
+	switch rule.Kind {
+	case models.PricingRuleGeneral, models.PricingRuleSibling:
+	case models.PricingRuleEarlyBird:
+		if rule.ValidUntil == "" {
+			response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "valid_until is required for early bird rules")
+			return
+		}
+	default:
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Kind must be general, early_bird or sibling")
+		return
+	}
+	if err := utils.ValidateDiscount(rule.DiscountType, rule.Value); err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+		return
+	}
+	if rule.Kind == models.PricingRuleSibling && rule.MinSiblings < 2 {
+		rule.MinSiblings = 2
+	}
+
+	rule.ID = 0
+	rule.VendorID = &vendorID
+	rule.Active = true
+	if err := tenantDB(c).Create(&rule).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to create pricing rule")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusCreated, rule)
+}
+
+// DeletePricingRule menghapus aturan harga. Tagihan yang sudah terbit tidak
+// berubah karena rinciannya tersimpan di line item.
+func DeletePricingRule(c *gin.Context) {
+	deleteVendorRecord(c, &models.PricingRule{}, "Pricing rule")
+}
+
+// GetScholarships menampilkan beasiswa pemain di vendor.
+func GetScholarships(c *gin.Context) {
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+
+	query := tenantDB(c).Where("vendor_id = ?", vendorID)
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	var scholarships []models.Scholarship
+	if err := query.Order("id ASC").Find(&scholarships).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to fetch scholarships")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, scholarships)
+}
+
+// CreateScholarship memberi beasiswa penuh (percent 100) atau sebagian ke
+// seorang pemain.
+func CreateScholarship(c *gin.Context) {
+	db := tenantDB(c)
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+
+	var scholarship models.Scholarship
+	if err := c.ShouldBindJSON(&scholarship); err != nil || scholarship.UserID == 0 {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateDiscount(scholarship.DiscountType, scholarship.Value); err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var player models.User
+	if err := db.Where("vendor_id = ?", vendorID).First(&player, scholarship.UserID).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Player not found in this vendor")
+		return
+	}
+	if scholarship.Name == "" {
+		scholarship.Name = player.Name
+	}
+
+	scholarship.ID = 0
+	scholarship.VendorID = &vendorID
+	if err := db.Create(&scholarship).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to create scholarship")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusCreated, scholarship)
+}
+
+// DeleteScholarship mencabut beasiswa pemain.
+func DeleteScholarship(c *gin.Context) {
+	deleteVendorRecord(c, &models.Scholarship{}, "Scholarship")
+}
+
+// GetPromoCodes menampilkan kode promo vendor beserta jumlah pemakaiannya.
+func GetPromoCodes(c *gin.Context) {
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+
+	var promos []models.PromoCode
+	if err := tenantDB(c).Where("vendor_id = ?", vendorID).Order("id DESC").Find(&promos).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to fetch promo codes")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, promos)
+}
+
+// CreatePromoCode membuat kode promo. Kode disimpan dalam huruf besar.
+func CreatePromoCode(c *gin.Context) {
+	db := tenantDB(c)
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+
+	var promo models.PromoCode
+	if err := c.ShouldBindJSON(&promo); err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	promo.Code = strings.ToUpper(strings.TrimSpace(promo.Code))
+	if promo.Code == "" || strings.ContainsAny(promo.Code, " \t") {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Code is required and must not contain spaces")
+		return
+	}
+	if err := utils.ValidateDiscount(promo.DiscountType, promo.Value); err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+		return
+	}
+	if promo.MaxUses < 0 {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "max_uses must not be negative")
+		return
+	}
+
+	var count int64
+	db.Model(&models.PromoCode{}).Where("vendor_id = ? AND code = ?", vendorID, promo.Code).Count(&count)
+	if count > 0 {
+		response.JSONErrorResponse(c.Writer, false, http.StatusConflict, "Promo code already exists")
+		return
+	}
+
+	promo.ID = 0
+	promo.VendorID = &vendorID
+	promo.UsedCount = 0
+	promo.Active = true
+	if err := db.Create(&promo).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to create promo code")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusCreated, promo)
+}
+
+// DeletePromoCode menghapus kode promo.
+func DeletePromoCode(c *gin.Context) {
+	deleteVendorRecord(c, &models.PromoCode{}, "Promo code")
+}
+
+// GetFamilies menampilkan keluarga beserta anggotanya.
+func GetFamilies(c *gin.Context) {
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+
+	var families []models.Family
+	if err := tenantDB(c).Preload("Members", func(tx *gorm.DB) *gorm.DB {
+		return tx.Select("id", "name", "email", "family_id", "vendor_id").Order("id ASC")
+	}).Where("vendor_id = ?", vendorID).Order("id ASC").Find(&families).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to fetch families")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, families)
+}
+
+// CreateFamily membuat keluarga baru dan langsung mengisi anggotanya.
+func CreateFamily(c *gin.Context) {
+	db := tenantDB(c)
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name    string `json:"name"`
+		UserIDs []uint `json:"user_ids"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || input.Name == "" {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Name is required")
+		return
+	}
+
+	family := models.Family{VendorID: &vendorID, Name: input.Name}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&family).Error; err != nil {
+			return err
+		}
+		return setFamilyMembers(tx, &family, input.UserIDs)
+	})
+	if err != nil {
+		respondFamilyError(c, err)
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusCreated, family)
+}
+
+// SetFamilyMembers mengganti seluruh anggota keluarga.
+func SetFamilyMembers(c *gin.Context) {
+	db := tenantDB(c)
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		UserIDs []uint `json:"user_ids"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	var family models.Family
+	if err := db.Where("vendor_id = ?", vendorID).First(&family, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Family not found")
+		return
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return setFamilyMembers(tx, &family, input.UserIDs)
+	}); err != nil {
+		respondFamilyError(c, err)
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, family)
+}
+
+var errFamilyMember = errors.New("all members must be players of this vendor")
+
+func setFamilyMembers(tx *gorm.DB, family *models.Family, userIDs []uint) error {
+	if err := tx.Model(&models.User{}).Where("family_id = ?", family.ID).
+		Update("family_id", nil).Error; err != nil {
+		return err
+	}
+	if len(userIDs) == 0 {
+		family.Members = nil
+		return nil
+	}
+
+	var members []models.User
+	if err := tx.Where("id IN ? AND vendor_id = ?", userIDs, family.VendorID).
+		Order("id ASC").Find(&members).Error; err != nil {
+		return err
+	}
+	if len(members) != len(userIDs) {
+		return errFamilyMember
+	}
+	if err := tx.Model(&models.User{}).Where("id IN ?", userIDs).
+		Update("family_id", family.ID).Error; err != nil {
+		return err
+	}
+	for i := range members {
+		members[i].FamilyID = &family.ID
+	}
+	family.Members = members
+	return nil
+}
+
+func respondFamilyError(c *gin.Context, err error) {
+	if errors.Is(err, errFamilyMember) {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to save family")
+}
+
+// QuotePaymentPrice menghitung harga akhir tagihan tanpa menyimpannya, untuk
+// menampilkan rincian potongan sebelum tagihan dibuat atau promo dipakai.
+func QuotePaymentPrice(c *gin.Context) {
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		UserID    uint    `json:"user_id"`
+		EventID   *uint   `json:"event_id"`
+		Type      string  `json:"type"`
+		Amount    float64 `json:"amount"`
+		PromoCode string  `json:"promo_code"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || input.Amount <= 0 {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Amount must be greater than 0")
+		return
+	}
+
+	currentUser, _ := middleware.CurrentUser(c)
+	if input.UserID == 0 {
+		input.UserID = currentUser.ID
+	}
+	if input.UserID != currentUser.ID && !middleware.Can(c, middleware.PermPaymentManage) {
+		response.JSONErrorResponse(c.Writer, false, http.StatusForbidden, "You can only quote your own payment")
+		return
+	}
+
+	quote, err := utils.QuotePrice(tenantDB(c), utils.PriceInput{
+		VendorID:    vendorID,
+		UserID:      input.UserID,
+		EventID:     input.EventID,
+		PaymentType: input.Type,
+		Fee:         input.Amount,
+		Date:        time.Now(),
+		PromoCode:   input.PromoCode,
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, utils.ErrPromoNotFound) || errors.Is(err, utils.ErrPromoInvalid) || errors.Is(err, utils.ErrPromoExhausted) {
+			status = http.StatusBadRequest
+		}
+		response.JSONErrorResponse(c.Writer, false, status, err.Error())
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, quote)
+}
+
+// deleteVendorRecord menghapus satu baris milik vendor yang sedang dikelola.
+func deleteVendorRecord(c *gin.Context, model interface{}, label string) {
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+
+	result := tenantDB(c).Where("vendor_id = ?", vendorID).Delete(model, c.Param("id"))
+	if result.Error != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to delete "+strings.ToLower(label))
+		return
+	}
+	if result.RowsAffected == 0 {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, label+" not found")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{"message": label + " deleted"})
+}
@@ -0,0 +1,279 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"ssb_api/controllers/middleware"
+	"ssb_api/models"
+	"ssb_api/models/response"
+	"ssb_api/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Status tagihan yang masih bisa dicocokkan dengan mutasi bank.
+var reconcilableStatuses = []string{
+	models.PaymentStatusIssued,
+	models.PaymentStatusOverdue,
+	models.PaymentStatusRejected,
+	models.PaymentStatusAwaitingVerification,
+	models.PaymentStatusPartiallyPaid,
+}
+
+// ImportBankStatement mengunggah mutasi rekening (CSV) lalu mengusulkan
+// pasangan tagihan untuk setiap transaksi kredit. Belum ada tagihan yang
+// diubah sampai usulan dikonfirmasi.
+func ImportBankStatement(c *gin.Context) {
+	db := tenantDB(c)
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultPostForm("format", utils.StatementFormatGeneric)
+	year, err := strconv.Atoi(c.DefaultPostForm("year", strconv.Itoa(time.Now().Year())))
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid year")
+		return
+	}
+	windowDays, err := strconv.Atoi(c.DefaultPostForm("window_days", "7"))
+	if err != nil || windowDays < 0 {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid window_days")
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Statement file is required")
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Failed to open statement file")
+		return
+	}
+	defer file.Close()
+
+	mapping := utils.StatementMapping{
+		DateColumn:        c.PostForm("date_column"),
+		DescriptionColumn: c.PostForm("description_column"),
+		AmountColumn:      c.PostForm("amount_column"),
+		CreditColumn:      c.PostForm("credit_column"),
+		DateLayout:        c.PostForm("date_layout"),
+	}
+	txs, err := utils.ParseBankStatement(file, format, mapping, year)
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Failed to parse statement: "+err.Error())
+		return
+	}
+
+	var payments []models.Payment
+	if err := db.Where("vendor_id = ? AND status IN ?", vendorID, reconcilableStatuses).
+		Order("created_at ASC").Find(&payments).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to fetch open payments")
+		return
+	}
+
+	uploader, _ := middleware.CurrentUser(c)
+	statement := models.BankStatementImport{
+		VendorID:     &vendorID,
+		Format:       format,
+		FileName:     fileHeader.Filename,
+		UploadedByID: uploader.ID,
+		TotalRows:    len(txs),
+	}
+
+	// Mutasi yang sudah dikonfirmasi dari import sebelumnya tidak diusulkan lagi
+	fingerprints := make([]string, len(txs))
+	for i, tx := range txs {
+		fingerprints[i] = models.BankTransactionFingerprint(tx.Date.Format("2006-01-02"), tx.Amount, tx.Description)
+	}
+	var confirmed []string
+	if err := db.Model(&models.BankTransaction{}).
+		Where("vendor_id = ? AND status = ? AND fingerprint IN ?", vendorID, models.BankTransactionConfirmed, fingerprints).
+		Pluck("fingerprint", &confirmed).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to check previous imports")
+		return
+	}
+	recorded := map[string]bool{}
+	for _, f := range confirmed {
+		recorded[f] = true
+	}
+
+	used := map[uint]bool{}
+	for i, tx := range txs {
+		row := models.BankTransaction{
+			VendorID:    &vendorID,
+			Date:        tx.Date.Format("2006-01-02"),
+			Description: tx.Description,
+			Amount:      tx.Amount,
+			Status:      models.BankTransactionUnmatched,
+			Fingerprint: fingerprints[i],
+		}
+		if recorded[row.Fingerprint] {
+			row.Status = models.BankTransactionDuplicate
+			statement.Transactions = append(statement.Transactions, row)
+			continue
+		}
+		match := utils.MatchStatementTransaction(tx, payments, used, windowDays)
+		row.Candidates = match.Candidates
+		if match.PaymentID != nil {
+			used[*match.PaymentID] = true
+			row.PaymentID = match.PaymentID
+			row.MatchReason = match.Reason
+			row.Status = models.BankTransactionProposed
+			statement.Matched++
+		}
+		statement.Transactions = append(statement.Transactions, row)
+	}
+
+	if err := db.Create(&statement).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to save statement import")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusCreated, statement)
+}
+
+// GetBankStatementImport menampilkan hasil import beserta usulan pasangannya.
+func GetBankStatementImport(c *gin.Context) {
+	db := tenantDB(c)
+
+	var statement models.BankStatementImport
+	if err := db.Preload("Transactions", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("date ASC, id ASC")
+	}).First(&statement, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Statement import not found")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, statement)
+}
+
+// ConfirmBankStatementMatches mengonfirmasi usulan pasangan secara massal dan
+// mencatat mutasinya sebagai pembayaran tagihan (lunas atau cicilan).
+// payment_id boleh diisi untuk mengganti usulan atau memasangkan transaksi
+// yang belum cocok.
+func ConfirmBankStatementMatches(c *gin.Context) {
+	db := tenantDB(c)
+	var input struct {
+		Matches []struct {
+			TransactionID uint  `json:"transaction_id"`
+			PaymentID     *uint `json:"payment_id"`
+			Ignore        bool  `json:"ignore"`
+		} `json:"matches"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || len(input.Matches) == 0 {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	var statement models.BankStatementImport
+	if err := db.First(&statement, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Statement import not found")
+		return
+	}
+
+	actor, _ := middleware.CurrentUser(c)
+	type confirmResult struct {
+		TransactionID uint   `json:"transaction_id"`
+		PaymentID     *uint  `json:"payment_id"`
+		Status        string `json:"status"`
+		Error         string `json:"error,omitempty"`
+	}
+	results := make([]confirmResult, 0, len(input.Matches))
+	var paid []models.Payment
+
+	for _, m := range input.Matches {
+		result := confirmResult{TransactionID: m.TransactionID}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var bankTx models.BankTransaction
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("import_id = ?", statement.ID).First(&bankTx, m.TransactionID).Error; err != nil {
+				return fmt.Errorf("transaction not found")
+			}
+			if bankTx.Status == models.BankTransactionConfirmed {
+				return fmt.Errorf("transaction already confirmed")
+			}
+
+			if m.Ignore {
+				result.Status = models.BankTransactionIgnored
+				return tx.Model(&bankTx).Update("status", models.BankTransactionIgnored).Error
+			}
+
+			paymentID := bankTx.PaymentID
+			if m.PaymentID != nil {
+				paymentID = m.PaymentID
+			}
+			if paymentID == nil {
+				return fmt.Errorf("transaction has no matching payment")
+			}
+			result.PaymentID = paymentID
+
+			// Tagihan dikunci dulu supaya konfirmasi mutasi yang sama dari dua
+			// import tidak lolos bersamaan
+			var payment models.Payment
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("vendor_id = ?", statement.VendorID).First(&payment, *paymentID).Error; err != nil {
+				return fmt.Errorf("payment not found")
+			}
+			var recorded int64
+			if err := tx.Model(&models.BankTransaction{}).
+				Where("vendor_id = ? AND status = ? AND fingerprint = ? AND id <> ?",
+					statement.VendorID, models.BankTransactionConfirmed, bankTx.Fingerprint, bankTx.ID).
+				Count(&recorded).Error; err != nil {
+				return err
+			}
+			if recorded > 0 {
+				return fmt.Errorf("transaction was already recorded from another statement import")
+			}
+
+			// Selisih sebesar kode unik tidak dihitung sebagai kelebihan bayar
+			amount := bankTx.Amount
+			if excess := amount - payment.Outstanding(); excess > 0 && excess <= float64(utils.PaymentUniqueCode(payment))+0.5 {
+				amount = payment.Outstanding()
+			}
+			txn := models.PaymentTransaction{
+				Amount: amount,
+				Method: "transfer",
+				Date:   bankTx.Date,
+				Note:   "Mutasi bank: " + bankTx.Description,
+			}
+			if err := utils.ApplyPaymentTransaction(tx, &payment, &txn, &actor); err != nil {
+				return err
+			}
+
+			if err := tx.Model(&bankTx).Updates(map[string]interface{}{
+				"status":     models.BankTransactionConfirmed,
+				"payment_id": *paymentID,
+			}).Error; err != nil {
+				return err
+			}
+			result.Status = models.BankTransactionConfirmed
+			paid = append(paid, payment)
+			return nil
+		})
+		if err != nil {
+			result.Status = "failed"
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+
+	for _, payment := range paid {
+		var user models.User
+		if err := db.First(&user, payment.UserID).Error; err == nil && user.FCMToken != "" {
+			body := fmt.Sprintf("Hai %s, pembayaran %s sudah kami terima.", user.Name, payment.Invoice)
+			go utils.CreateNotification(user.ID, user.FCMToken, "Pembayaran Berhasil", body, "status_pembayaran")
+		}
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
+		"confirmed": len(paid),
+		"results":   results,
+	})
+}
@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"ssb_api/controllers/middleware"
+	"ssb_api/models"
+	"ssb_api/models/response"
+	"ssb_api/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestRefund mengajukan refund atas tagihan yang sudah lunas. Amount
+// kosong berarti refund penuh dari sisa yang bisa direfund.
+func RequestRefund(c *gin.Context) {
+	db := tenantDB(c)
+	var input struct {
+		Amount float64 `json:"amount"`
+		Reason string  `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Reason) == "" {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Reason is required")
+		return
+	}
+	if input.Amount < 0 {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Amount must not be negative")
+		return
+	}
+
+	var payment models.Payment
+	if err := db.First(&payment, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Payment not found")
+		return
+	}
+	if !requireVendorAccess(c, payment.VendorID) {
+		return
+	}
+
+	actor, _ := middleware.CurrentUser(c)
+	refund, err := utils.RequestRefund(db, payment, input.Amount, input.Reason, actor)
+	if err != nil {
+		respondRefundError(c, err)
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusCreated, refund)
+}
+
+// GetRefunds menampilkan refund vendor. Query: status, payment_id, user_id.
+func GetRefunds(c *gin.Context) {
+	db := tenantDB(c)
+	vendorID, ok := vendorFilter(c)
+	if !ok {
+		return
+	}
+
+	query := db.Model(&models.Refund{})
+	if vendorID != nil {
+		query = query.Where("vendor_id = ?", *vendorID)
+	}
+	if s := c.Query("status"); s != "" {
+		query = query.Where("status = ?", s)
+	}
+	if p := c.Query("payment_id"); p != "" {
+		query = query.Where("payment_id = ?", p)
+	}
+	if u := c.Query("user_id"); u != "" {
+		query = query.Where("user_id = ?", u)
+	}
+
+	var refunds []models.Refund
+	if err := query.Order("created_at DESC").Find(&refunds).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to fetch refunds")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, refunds)
+}
+
+// ReviewRefund menyetujui (menerbitkan nota kredit) atau menolak pengajuan
+// refund.
+func ReviewRefund(c *gin.Context) {
+	db := tenantDB(c)
+	var input struct {
+		Approve bool   `json:"approve"`
+		Reason  string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !input.Approve && input.Reason == "" {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Reason is required when rejecting a refund")
+		return
+	}
+
+	var refund models.Refund
+	if err := db.First(&refund, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Refund not found")
+		return
+	}
+
+	actor, _ := middleware.CurrentUser(c)
+	var err error
+	if input.Approve {
+		err = utils.ApproveRefund(db, &refund, actor)
+	} else {
+		err = utils.RejectRefund(db, &refund, actor, input.Reason)
+	}
+	if err != nil {
+		respondRefundError(c, err)
+		return
+	}
+
+	var player models.User
+	if err := db.First(&player, refund.UserID).Error; err == nil && player.FCMToken != "" {
+		title, body := "Refund Disetujui", fmt.Sprintf("Hai %s, refund %s sudah disetujui dengan nota kredit %s.",
+			player.Name, utils.FormatRupiah(refund.Amount), refund.CreditNoteNumber)
+		if !input.Approve {
+			title, body = "Refund Ditolak", fmt.Sprintf("Hai %s, pengajuan refund %s ditolak: %s",
+				player.Name, utils.FormatRupiah(refund.Amount), input.Reason)
+		}
+		go utils.CreateNotification(player.ID, player.FCMToken, title, body, "refund")
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, refund)
+}
+
+// PayoutRefund mengembalikan dana refund yang sudah disetujui. Method:
+// manual (isi reference bukti transfer), provider, atau credit.
+func PayoutRefund(c *gin.Context) {
+	db := tenantDB(c)
+	var input struct {
+		Method    string `json:"method"`
+		Reference string `json:"reference"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	switch input.Method {
+	case "":
+		input.Method = models.RefundPayoutManual
+	case models.RefundPayoutManual, models.RefundPayoutProvider, models.RefundPayoutCredit:
+	default:
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Method must be manual, provider or credit")
+		return
+	}
+
+	var refund models.Refund
+	if err := db.First(&refund, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Refund not found")
+		return
+	}
+
+	if err := utils.PayoutRefund(db, &refund, input.Method, input.Reference); err != nil {
+		respondRefundError(c, err)
+		return
+	}
+
+	if refund.Status == models.RefundStatusPaidOut {
+		var player models.User
+		if err := db.First(&player, refund.UserID).Error; err == nil && player.FCMToken != "" {
+			body := fmt.Sprintf("Hai %s, dana refund %s sudah dikembalikan.", player.Name, utils.FormatRupiah(refund.Amount))
+			if refund.PayoutMethod == models.RefundPayoutCredit {
+				body = fmt.Sprintf("Hai %s, refund %s sudah masuk ke saldo kredit kamu.", player.Name, utils.FormatRupiah(refund.Amount))
+			}
+			go utils.CreateNotification(player.ID, player.FCMToken, "Dana Refund Dikembalikan", body, "refund")
+		}
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, refund)
+}
+
+// GetCreditNotePDF mengunduh nota kredit sebuah refund.
+func GetCreditNotePDF(c *gin.Context) {
+	db := tenantDB(c)
+
+	var refund models.Refund
+	if err := db.First(&refund, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Refund not found")
+		return
+	}
+
+	currentUser, _ := middleware.CurrentUser(c)
+	if refund.UserID != currentUser.ID && !middleware.Can(c, middleware.PermPaymentRead) {
+		response.JSONErrorResponse(c.Writer, false, http.StatusForbidden, "You are not allowed to view this refund")
+		return
+	}
+	if refund.CreditNoteNumber == "" {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Credit note is only available for approved refunds")
+		return
+	}
+
+	doc := utils.CreditNoteDocument{Refund: refund}
+	if err := db.First(&doc.Payment, refund.PaymentID).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Payment not found")
+		return
+	}
+	if refund.VendorID != nil {
+		db.First(&doc.Vendor, *refund.VendorID)
+	}
+	if err := db.First(&doc.Player, refund.UserID).Error; err != nil {
+		doc.Player.Name = doc.Payment.UserName
+	}
+	if doc.Payment.EventID != nil {
+		var event models.Event
+		if err := db.First(&event, *doc.Payment.EventID).Error; err == nil {
+			doc.Event = &event
+		}
+	}
+
+	filename := "credit-note-" + strings.NewReplacer("/", "-", "\\", "-", "\"", "").Replace(refund.CreditNoteNumber) + ".pdf"
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", filename))
+	c.Data(http.StatusOK, "application/pdf", utils.RenderCreditNotePDF(doc))
+}
+
+func respondRefundError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	var invalid utils.ErrInvalidPaymentTransition
+	switch {
+	case errors.Is(err, utils.ErrRefundExceedsPaid), errors.Is(err, utils.ErrRefundProviderUnsupported):
+		status = http.StatusBadRequest
+	case errors.Is(err, utils.ErrRefundPaymentNotPaid), errors.Is(err, utils.ErrRefundNotRequested),
+		errors.Is(err, utils.ErrRefundNotApproved), errors.As(err, &invalid):
+		status = http.StatusConflict
+	}
+	response.JSONErrorResponse(c.Writer, false, status, err.Error())
+}
@@ -0,0 +1,218 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"ssb_api/models"
+	"ssb_api/models/response"
+	"ssb_api/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Semua laporan menerima query vendor_id, start_date dan end_date
+// (YYYY-MM-DD). Pembanding diisi lewat compare=previous|year atau
+// compare_start_date dan compare_end_date.
+
+// GetRevenueReport menampilkan pendapatan vendor per bulan setelah dikurangi
+// refund.
+func GetRevenueReport(c *gin.Context) {
+	vendorID, current, compare, ok := reportParams(c)
+	if !ok {
+		return
+	}
+	db := tenantDB(c)
+
+	report, err := utils.BuildRevenueReport(db, vendorID, current.Start, current.End)
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to build revenue report")
+		return
+	}
+	if compare == nil {
+		response.JSONSuccess(c.Writer, true, http.StatusOK, report)
+		return
+	}
+
+	previous, err := utils.BuildRevenueReport(db, vendorID, compare.Start, compare.End)
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to build revenue report")
+		return
+	}
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
+		"current":  report,
+		"previous": previous,
+		"change": gin.H{
+			"collected": utils.PercentChange(report.Collected, previous.Collected),
+			"refunded":  utils.PercentChange(report.Refunded, previous.Refunded),
+			"net":       utils.PercentChange(report.Net, previous.Net),
+		},
+	})
+}
+
+// GetFinancialSummary menampilkan tagihan terbit, terbayar, sisa tagihan,
+// collection rate dan pendapatan bersih vendor.
+func GetFinancialSummary(c *gin.Context) {
+	vendorID, current, compare, ok := reportParams(c)
+	if !ok {
+		return
+	}
+	db := tenantDB(c)
+
+	summary, err := utils.BuildFinancialSummary(db, vendorID, current)
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to build financial summary")
+		return
+	}
+	if compare == nil {
+		response.JSONSuccess(c.Writer, true, http.StatusOK, summary)
+		return
+	}
+
+	previous, err := utils.BuildFinancialSummary(db, vendorID, *compare)
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to build financial summary")
+		return
+	}
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
+		"current":  summary,
+		"previous": previous,
+		"change": gin.H{
+			"billed":          utils.PercentChange(summary.Billed, previous.Billed),
+			"paid":            utils.PercentChange(summary.Paid, previous.Paid),
+			"outstanding":     utils.PercentChange(summary.Outstanding, previous.Outstanding),
+			"collected":       utils.PercentChange(summary.Collected, previous.Collected),
+			"net_revenue":     utils.PercentChange(summary.NetRevenue, previous.NetRevenue),
+			"collection_rate": summary.CollectionRate - previous.CollectionRate, // selisih poin persen
+		},
+	})
+}
+
+// GetRevenueBreakdown menampilkan rincian pendapatan per bulan, event, tipe
+// tagihan atau metode pembayaran. Query: by (default month).
+func GetRevenueBreakdown(c *gin.Context) {
+	vendorID, current, compare, ok := reportParams(c)
+	if !ok {
+		return
+	}
+	db := tenantDB(c)
+	by := c.DefaultQuery("by", utils.BreakdownMonth)
+
+	rows, err := utils.BuildRevenueBreakdown(db, vendorID, current, by)
+	if err != nil {
+		respondReportError(c, err, "Failed to build revenue breakdown")
+		return
+	}
+	result := gin.H{"by": by, "range": current, "rows": rows}
+	if compare != nil {
+		previous, err := utils.BuildRevenueBreakdown(db, vendorID, *compare, by)
+		if err != nil {
+			respondReportError(c, err, "Failed to build revenue breakdown")
+			return
+		}
+		result["previous_range"] = compare
+		result["previous_rows"] = previous
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, result)
+}
+
+// GetTopDebtors menampilkan pemain dengan sisa tagihan terbesar. Query:
+// limit (default 10, maksimal 100).
+func GetTopDebtors(c *gin.Context) {
+	vendorID, current, _, ok := reportParams(c)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	debtors, err := utils.BuildTopDebtors(tenantDB(c), vendorID, current, limit)
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to build debtor report")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, debtors)
+}
+
+// GetAgingReport menampilkan umur piutang (0-30, 31-60, 61-90, 90+ hari
+// lewat jatuh tempo). Query: as_of (YYYY-MM-DD, default hari ini).
+func GetAgingReport(c *gin.Context) {
+	vendorID, current, compare, ok := reportParams(c)
+	if !ok {
+		return
+	}
+	db := tenantDB(c)
+
+	asOf := time.Now()
+	if s := c.Query("as_of"); s != "" {
+		date, err := models.ParseDate(s)
+		parsed, valid := date.Time(time.Local)
+		if err != nil || !valid {
+			response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid as_of, use YYYY-MM-DD")
+			return
+		}
+		asOf = parsed
+	}
+
+	report, err := utils.BuildAgingReport(db, vendorID, current, asOf)
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to build aging report")
+		return
+	}
+	if compare == nil {
+		response.JSONSuccess(c.Writer, true, http.StatusOK, report)
+		return
+	}
+
+	// Pembanding dihitung pada akhir periode pembanding
+	previousAsOf, _ := time.Parse("2006-01-02", compare.End)
+	previous, err := utils.BuildAgingReport(db, vendorID, *compare, previousAsOf)
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to build aging report")
+		return
+	}
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{"current": report, "previous": previous})
+}
+
+// reportParams membaca vendor, periode laporan dan periode pembanding.
+// compare bernilai nil bila tidak diminta.
+func reportParams(c *gin.Context) (uint, utils.ReportRange, *utils.ReportRange, bool) {
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return 0, utils.ReportRange{}, nil, false
+	}
+
+	current, err := utils.ParseReportRange(c.Query("start_date"), c.Query("end_date"))
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+		return 0, current, nil, false
+	}
+
+	var compare utils.ReportRange
+	switch {
+	case c.Query("compare_start_date") != "" || c.Query("compare_end_date") != "":
+		compare, err = utils.ParseReportRange(c.Query("compare_start_date"), c.Query("compare_end_date"))
+	case c.Query("compare") != utils.CompareNone:
+		compare, err = utils.ComparisonRange(current, c.Query("compare"))
+	default:
+		return vendorID, current, nil, true
+	}
+	if err == nil && compare.End == "" {
+		err = utils.ErrInvalidReportRange
+	}
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+		return 0, current, nil, false
+	}
+	return vendorID, current, &compare, true
+}
+
+func respondReportError(c *gin.Context, err error, message string) {
+	if errors.Is(err, utils.ErrUnknownBreakdown) {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, message)
+}
@@ -0,0 +1,449 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"ssb_api/config"
+	"ssb_api/controllers/middleware"
+	"ssb_api/models"
+	"ssb_api/models/response"
+	"ssb_api/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Ukuran file roster maksimal.
+const maxRosterFileSize = 5 << 20
+
+var errRosterCommitted = errors.New("roster import already committed")
+
+// rosterInvitation adalah tautan undangan yang dibagikan pelatih ke pemain.
+type rosterInvitation struct {
+	UserID    uint      `json:"user_id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Phone     string    `json:"phone"`
+	Link      string    `json:"link"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ImportRosterPreview mengunggah daftar pemain (CSV/XLSX) lalu menampilkan
+// hasil validasi per baris. Belum ada pemain yang dibuat sampai import
+// di-commit.
+func ImportRosterPreview(c *gin.Context) {
+	db := tenantDB(c)
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Roster file is required")
+		return
+	}
+	if fileHeader.Size > maxRosterFileSize {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Roster file must not exceed 5 MB")
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Failed to open roster file")
+		return
+	}
+	defer file.Close()
+	raw, err := io.ReadAll(io.LimitReader(file, maxRosterFileSize))
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Failed to read roster file")
+		return
+	}
+
+	format := c.PostForm("format")
+	if format == "" {
+		format = utils.SpreadsheetFormat(fileHeader.Filename, raw)
+	}
+	rows, err := utils.ParseRoster(raw, format)
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Failed to parse roster: "+err.Error())
+		return
+	}
+	if err := utils.CheckRosterDuplicates(db, vendorID, rows); err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to validate roster")
+		return
+	}
+
+	uploader, _ := middleware.CurrentUser(c)
+	roster := models.RosterImport{
+		VendorID:     &vendorID,
+		FileName:     fileHeader.Filename,
+		UploadedByID: uploader.ID,
+		Status:       models.RosterImportPreview,
+		TotalRows:    len(rows),
+	}
+	for _, row := range rows {
+		row.VendorID = &vendorID
+		if row.Valid() {
+			roster.ValidRows++
+		}
+		roster.Rows = append(roster.Rows, row)
+	}
+
+	if err := db.Create(&roster).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to save roster import")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusCreated, roster)
+}
+
+// GetRosterImport menampilkan preview import beserta hasil validasinya.
+func GetRosterImport(c *gin.Context) {
+	db := tenantDB(c)
+
+	var roster models.RosterImport
+	if err := db.Preload("Rows", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("line ASC")
+	}).First(&roster, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Roster import not found")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, roster)
+}
+
+// CommitRosterImport membuat pemain dari semua baris valid dalam satu
+// transaksi di vendor pengunggah. Duplikat dicek ulang karena data bisa
+// berubah sejak preview. Setiap pemain mendapat tautan undangan untuk
+// membuat password sendiri.
+func CommitRosterImport(c *gin.Context) {
+	db := tenantDB(c)
+
+	var roster models.RosterImport
+	var invitations []rosterInvitation
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&roster, c.Param("id")).Error; err != nil {
+			return err
+		}
+		if roster.Status != models.RosterImportPreview {
+			return errRosterCommitted
+		}
+
+		var rows []models.RosterImportRow
+		if err := tx.Where("import_id = ? AND errors = ''", roster.ID).Order("line ASC").Find(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) > 0 {
+			if err := utils.CheckRosterDuplicates(tx, *roster.VendorID, rows); err != nil {
+				return err
+			}
+		}
+
+		roster.ValidRows = 0
+		for i := range rows {
+			row := &rows[i]
+			if !row.Valid() {
+				if err := tx.Model(row).Update("errors", row.Errors).Error; err != nil {
+					return err
+				}
+				continue
+			}
+
+			// Password kosong tidak bisa dipakai login sampai undangan diterima
+			user := models.User{
+				Name:        row.Name,
+				Email:       row.Email,
+				Phone:       row.Phone,
+				Role:        models.RolePemain,
+				Gender:      row.Gender,
+				BirthDate:   row.BirthDate,
+				Position:    row.Position,
+				Foot:        row.Foot,
+				Number:      row.Number,
+				AgeCategory: row.AgeCategory,
+				Address:     row.Address,
+				VendorID:    roster.VendorID,
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+			invitation, token, err := utils.NewInvitation(user)
+			if err != nil {
+				return err
+			}
+			if err := tx.Create(&invitation).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(row).Update("user_id", user.ID).Error; err != nil {
+				return err
+			}
+
+			roster.ValidRows++
+			invitations = append(invitations, rosterInvitation{
+				UserID:    user.ID,
+				Name:      user.Name,
+				Email:     user.Email,
+				Phone:     user.Phone,
+				Link:      utils.InvitationLink(token),
+				ExpiresAt: invitation.ExpiresAt,
+			})
+		}
+
+		now := time.Now()
+		roster.Status = models.RosterImportCommitted
+		roster.Imported = len(invitations)
+		roster.CommittedAt = &now
+		return tx.Model(&roster).Updates(map[string]interface{}{
+			"status":       roster.Status,
+			"valid_rows":   roster.ValidRows,
+			"imported":     roster.Imported,
+			"committed_at": roster.CommittedAt,
+		}).Error
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Roster import not found")
+		return
+	case errors.Is(err, errRosterCommitted):
+		response.JSONErrorResponse(c.Writer, false, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to import roster")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
+		"import":      roster,
+		"invitations": invitations,
+	})
+}
+
+// ResendInvitation membuat tautan undangan baru untuk pemain yang belum
+// membuat password. Tautan lama otomatis tidak berlaku.
+func ResendInvitation(c *gin.Context) {
+	db := tenantDB(c)
+
+	var user models.User
+	if err := db.First(&user, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "User not found")
+		return
+	}
+	if !requireVendorAccess(c, user.VendorID) {
+		return
+	}
+	if user.Password != "" {
+		response.JSONErrorResponse(c.Writer, false, http.StatusConflict, "User has already set a password")
+		return
+	}
+
+	invitation, token, err := utils.NewInvitation(user)
+	if err == nil {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("user_id = ? AND accepted_at IS NULL", user.ID).Delete(&models.UserInvitation{}).Error; err != nil {
+				return err
+			}
+			return tx.Create(&invitation).Error
+		})
+	}
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to create invitation")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusCreated, rosterInvitation{
+		UserID:    user.ID,
+		Name:      user.Name,
+		Email:     user.Email,
+		Phone:     user.Phone,
+		Link:      utils.InvitationLink(token),
+		ExpiresAt: invitation.ExpiresAt,
+	})
+}
+
+// InviteCoach membuat akun pelatih di vendor lalu mengembalikan tautan
+// undangan untuk membuat password. Registrasi publik tidak bisa membuat
+// pelatih, jadi pelatih selalu lewat sini.
+func InviteCoach(c *gin.Context) {
+	db := tenantDB(c)
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+	var input struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+		Phone string `json:"phone"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || input.Name == "" || input.Email == "" {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Name and email are required")
+		return
+	}
+	input.Email = strings.ToLower(strings.TrimSpace(input.Email))
+
+	var existing models.User
+	if err := db.Where("email = ?", input.Email).First(&existing).Error; err == nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Email sudah terdaftar")
+		return
+	}
+	if input.Phone != "" {
+		if err := db.Where("phone = ?", input.Phone).First(&existing).Error; err == nil {
+			response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Nomor telepon sudah terdaftar")
+			return
+		}
+	}
+
+	// Password kosong tidak bisa dipakai login sampai undangan diterima
+	user := models.User{
+		Name:     input.Name,
+		Email:    input.Email,
+		Phone:    input.Phone,
+		Role:     models.RolePelatih,
+		VendorID: &vendorID,
+	}
+	var invitation models.UserInvitation
+	var token string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		var err error
+		if invitation, token, err = utils.NewInvitation(user); err != nil {
+			return err
+		}
+		return tx.Create(&invitation).Error
+	})
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to invite coach")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusCreated, rosterInvitation{
+		UserID:    user.ID,
+		Name:      user.Name,
+		Email:     user.Email,
+		Phone:     user.Phone,
+		Link:      utils.InvitationLink(token),
+		ExpiresAt: invitation.ExpiresAt,
+	})
+}
+
+// GetInvitation dipakai halaman undangan untuk menampilkan nama pemain dan
+// vendor sebelum password dibuat.
+func GetInvitation(c *gin.Context) {
+	db := config.DB.WithContext(config.SystemContext())
+
+	invitation, ok := findInvitation(c, db)
+	if !ok {
+		return
+	}
+	var user models.User
+	if err := db.Preload("Vendor").First(&user, invitation.UserID).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Invitation not found")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
+		"name":       user.Name,
+		"email":      user.Email,
+		"vendor":     user.Vendor.Name,
+		"expires_at": invitation.ExpiresAt,
+	})
+}
+
+// AcceptInvitation menyimpan password pemain lalu langsung login.
+func AcceptInvitation(c *gin.Context) {
+	db := config.DB.WithContext(config.SystemContext())
+	var input struct {
+		Password string `json:"password"`
+		FCMToken string `json:"fcm_token"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || len(input.Password) < 8 {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Password must be at least 8 characters")
+		return
+	}
+
+	invitation, ok := findInvitation(c, db)
+	if !ok {
+		return
+	}
+
+	hashed, err := utils.HasingPassword(input.Password)
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to hash password")
+		return
+	}
+
+	var user models.User
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Hanya satu request yang boleh memakai undangan
+		result := tx.Model(&models.UserInvitation{}).
+			Where("id = ? AND accepted_at IS NULL", invitation.ID).
+			Update("accepted_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := tx.First(&user, invitation.UserID).Error; err != nil {
+			return err
+		}
+		updates := map[string]interface{}{"password": hashed, "active": true}
+		if input.FCMToken != "" {
+			updates["fcm_token"] = input.FCMToken
+		}
+		return tx.Model(&user).Updates(updates).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.JSONErrorResponse(c.Writer, false, http.StatusGone, "Invitation has already been used")
+		return
+	}
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to accept invitation")
+		return
+	}
+
+	accessToken, refreshToken, err := utils.GenerateTokens(user.ID, user.Email, user.Role)
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
+		"user": gin.H{
+			"id":        user.ID,
+			"name":      user.Name,
+			"email":     user.Email,
+			"role":      user.Role,
+			"phone":     user.Phone,
+			"vendor_id": user.VendorID,
+		},
+		"token":         accessToken,
+		"refresh_token": refreshToken,
+	})
+}
+
+// findInvitation mencari undangan dari token di URL yang belum dipakai dan
+// belum kedaluwarsa.
+func findInvitation(c *gin.Context, db *gorm.DB) (models.UserInvitation, bool) {
+	var invitation models.UserInvitation
+	token := strings.TrimSpace(c.Param("token"))
+	if err := db.Where("token_hash = ?", utils.HashInvitationToken(token)).First(&invitation).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Invitation not found")
+		return invitation, false
+	}
+	if invitation.AcceptedAt != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusGone, "Invitation has already been used")
+		return invitation, false
+	}
+	if time.Now().After(invitation.ExpiresAt) {
+		response.JSONErrorResponse(c.Writer, false, http.StatusGone, "Invitation has expired")
+		return invitation, false
+	}
+	return invitation, true
+}
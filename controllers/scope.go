@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"ssb_api/config"
+	"ssb_api/controllers/middleware"
+	"ssb_api/models/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// tenantDB mengembalikan koneksi database yang membawa tenant user yang
+// sedang login. Semua query ke tabel milik vendor wajib lewat sini.
+func tenantDB(c *gin.Context) *gorm.DB {
+	middleware.CurrentUser(c)
+	return config.DB.WithContext(c.Request.Context())
+}
+
+// scopedVendorID membaca query vendor_id dan menguncinya ke vendor user yang
+// sedang login. Response error sudah dikirim bila ok bernilai false.
+func scopedVendorID(c *gin.Context) (uint, bool) {
+	vendorID, err := middleware.ScopedVendorID(c, c.Query("vendor_id"))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, middleware.ErrVendorForbidden) {
+			status = http.StatusForbidden
+		}
+		response.JSONErrorResponse(c.Writer, false, status, err.Error())
+		return 0, false
+	}
+	return vendorID, true
+}
+
+// requireVendorAccess memastikan user boleh mengakses vendor tertentu.
+func requireVendorAccess(c *gin.Context, vendorID *uint) bool {
+	if !middleware.CanAccessVendor(c, vendorID) {
+		response.JSONErrorResponse(c.Writer, false, http.StatusForbidden, middleware.ErrVendorForbidden.Error())
+		return false
+	}
+	return true
+}
+
+// vendorFilter mengembalikan vendor milik user untuk query list. Nilai nil
+// berarti user adalah platform admin dan boleh melihat semua vendor.
+func vendorFilter(c *gin.Context) (*uint, bool) {
+	if middleware.Can(c, middleware.PermAllVendors) {
+		return nil, true
+	}
+	user, ok := middleware.CurrentUser(c)
+	if !ok || user.VendorID == nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusForbidden, middleware.ErrVendorForbidden.Error())
+		return nil, false
+	}
+	return user.VendorID, true
+}
@@ -0,0 +1,221 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"ssb_api/controllers/middleware"
+	"ssb_api/models"
+	"ssb_api/models/response"
+	"ssb_api/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// staffInput adalah body penggantian pelatih sesi. Daftar kosong menghapus
+// semua pelatih.
+type staffInput struct {
+	Staff []models.StaffAssignment `json:"staff" binding:"required"`
+}
+
+// SetEventStaff mengganti pelatih event.
+func SetEventStaff(c *gin.Context) {
+	db := tenantDB(c)
+	var input staffInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	var event models.Event
+	if err := db.First(&event, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Event not found")
+		return
+	}
+	if !requireVendorAccess(c, &event.VendorID) {
+		return
+	}
+
+	loc := utils.VendorLocation(db, event.VendorID)
+	var staff []models.StaffAssignment
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		staff, err = utils.SetStaff(tx, utils.EventSession(event), input.Staff, loc)
+		return err
+	})
+	if err != nil {
+		if !respondStaffError(c, err) {
+			response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to update event staff")
+		}
+		return
+	}
+	response.JSONSuccess(c.Writer, true, http.StatusOK, staff)
+}
+
+// SetTrainingStaff mengganti pelatih training, jadwalnya dari event yang
+// ditautkan.
+func SetTrainingStaff(c *gin.Context) {
+	db := tenantDB(c)
+	var input staffInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	var training models.Training
+	if err := db.First(&training, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Training not found")
+		return
+	}
+	if !requireVendorAccess(c, training.VendorID) {
+		return
+	}
+
+	var staff []models.StaffAssignment
+	err := db.Transaction(func(tx *gorm.DB) error {
+		ref, err := utils.TrainingSession(tx, training)
+		if err != nil {
+			return err
+		}
+		staff, err = utils.SetStaff(tx, ref, input.Staff, utils.VendorLocation(tx, ref.VendorID))
+		return err
+	})
+	if err != nil {
+		if !respondStaffError(c, err) {
+			response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to update training staff")
+		}
+		return
+	}
+	response.JSONSuccess(c.Writer, true, http.StatusOK, staff)
+}
+
+// SetMatchStaff mengganti pelatih match.
+func SetMatchStaff(c *gin.Context) {
+	db := tenantDB(c)
+	var input staffInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	var match models.Match
+	if err := db.First(&match, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Match not found")
+		return
+	}
+	if !requireVendorAccess(c, match.VendorID) {
+		return
+	}
+
+	ref := utils.MatchSession(match)
+	var staff []models.StaffAssignment
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		staff, err = utils.SetStaff(tx, ref, input.Staff, utils.VendorLocation(tx, ref.VendorID))
+		return err
+	})
+	if err != nil {
+		if !respondStaffError(c, err) {
+			response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to update match staff")
+		}
+		return
+	}
+	response.JSONSuccess(c.Writer, true, http.StatusOK, staff)
+}
+
+// GetCoachAgenda menampilkan sesi event, training dan match seorang pelatih
+// beserta peringatan sesi berurutan yang bentrok atau venuenya terlalu jauh.
+// Bawaannya dari sekarang sampai DefaultAgendaDays hari ke depan,
+// start_date dan end_date mengganti rentangnya. Pelatih boleh melihat
+// agendanya sendiri, agenda pelatih lain butuh izin kelola user.
+func GetCoachAgenda(c *gin.Context) {
+	db := tenantDB(c)
+	current, _ := middleware.CurrentUser(c)
+	var coach models.User
+	if err := db.First(&coach, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Coach not found")
+		return
+	}
+	if coach.ID != current.ID && !middleware.Can(c, middleware.PermUserManage) {
+		response.JSONErrorResponse(c.Writer, false, http.StatusForbidden, "You can only view your own agenda")
+		return
+	}
+	if !requireVendorAccess(c, coach.VendorID) {
+		return
+	}
+
+	from, to, ok := dateRangeParams(c)
+	if !ok {
+		return
+	}
+	loc := time.UTC
+	if coach.VendorID != nil {
+		loc = utils.VendorLocation(db, *coach.VendorID)
+	}
+	now := time.Now().In(loc)
+	upcoming := from == ""
+	if upcoming {
+		from = models.DateOf(now)
+	}
+	if to == "" {
+		to = models.DateOf(now.AddDate(0, 0, utils.DefaultAgendaDays))
+	}
+	rangeStart, rangeEnd := utils.LocalDayRange(from, to, loc)
+	if upcoming {
+		rangeStart = now
+	}
+	if !rangeEnd.After(rangeStart) || rangeEnd.Sub(rangeStart) > utils.MaxAgendaDays*24*time.Hour {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest,
+			"end_date must be on or after start_date and at most "+strconv.Itoa(utils.MaxAgendaDays)+" days later")
+		return
+	}
+
+	sessions, err := utils.StaffSessions(db, []uint{coach.ID}, rangeStart, rangeEnd)
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to fetch agenda")
+		return
+	}
+	for i := range sessions {
+		sessions[i].Start = sessions[i].Start.In(loc)
+		sessions[i].End = sessions[i].End.In(loc)
+	}
+	if sessions == nil {
+		sessions = []utils.StaffSession{}
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
+		"coach":    gin.H{"id": coach.ID, "name": coach.Name},
+		"timezone": loc.String(),
+		"sessions": sessions,
+		"warnings": utils.AgendaWarnings(sessions),
+	})
+}
+
+// syncEventStaff mengganti pelatih event bila staff dikirim, lalu memeriksa
+// ulang pelatih event dan training-nya bila jadwal event berubah.
+func syncEventStaff(tx *gorm.DB, event models.Event, staff []models.StaffAssignment, rescheduled bool, loc *time.Location) ([]models.StaffAssignment, error) {
+	if staff != nil {
+		assigned, err := utils.SetStaff(tx, utils.EventSession(event), staff, loc)
+		if err != nil || !rescheduled {
+			return assigned, err
+		}
+	}
+	if !rescheduled {
+		return utils.SessionStaff(tx, models.SessionEvent, event.ID)
+	}
+	return utils.CheckEventStaff(tx, event, loc)
+}
+
+// respondStaffError memetakan error penugasan pelatih. Hasilnya false bila
+// err bukan error pelatih sehingga response belum dikirim.
+func respondStaffError(c *gin.Context, err error) bool {
+	var conflict utils.StaffConflictError
+	switch {
+	case errors.As(err, &conflict):
+		response.JSONErrorResponse(c.Writer, false, http.StatusConflict, err.Error())
+	case errors.Is(err, utils.ErrInvalidStaff), errors.Is(err, utils.ErrStaffEventMissing):
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+	default:
+		return false
+	}
+	return true
+}
@@ -2,35 +2,66 @@ package controllers
 
 import (
 	"net/http"
-	"ssb_api/config"
 	"ssb_api/models"
 	"ssb_api/models/response"
-	"strconv"
+	"ssb_api/utils"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func CreateTraining(c *gin.Context) {
+	db := tenantDB(c)
 	var input models.Training
 	if err := c.ShouldBindJSON(&input); err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request")
 		return
 	}
-	if err := config.DB.Where("id = ?", input.VendorID).First(&models.Vendor{}).Error; err != nil {
+	if !requireVendorAccess(c, input.VendorID) {
+		return
+	}
+	if err := db.Where("id = ?", input.VendorID).First(&models.Vendor{}).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Vendor not found")
 		return
 	}
 
-	if err := config.DB.Create(&input).Error; err != nil {
-		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to create training")
+	// Pelatih training ikut dicek terhadap jadwal event yang ditautkan
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&input).Error; err != nil {
+			return err
+		}
+		if input.Staff == nil {
+			return nil
+		}
+		ref, err := utils.TrainingSession(tx, input)
+		if err != nil {
+			return err
+		}
+		input.Staff, err = utils.SetStaff(tx, ref, input.Staff, utils.VendorLocation(tx, ref.VendorID))
+		return err
+	})
+	if err != nil {
+		if !respondStaffError(c, err) {
+			response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to create training")
+		}
 		return
 	}
 	response.JSONSuccess(c.Writer, true, http.StatusCreated, input)
 }
 
 func GetTrainings(c *gin.Context) {
+	db := tenantDB(c)
+	vendorID, ok := vendorFilter(c)
+	if !ok {
+		return
+	}
+
 	var trainings []models.Training
-	if err := config.DB.Find(&trainings).Error; err != nil {
+	query := db.Model(&models.Training{})
+	if vendorID != nil {
+		query = query.Where("vendor_id = ?", *vendorID)
+	}
+	if err := query.Find(&trainings).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to fetch trainings")
 		return
 	}
@@ -38,20 +69,14 @@ func GetTrainings(c *gin.Context) {
 }
 
 func GetTrainingsByVendor(c *gin.Context) {
-	vendorIDStr := c.Query("vendor_id")
-	if vendorIDStr == "" {
-		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Vendor ID is required")
-		return
-	}
-
-	vendorID, err := strconv.ParseUint(vendorIDStr, 10, 64)
-	if err != nil {
-		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid vendor ID")
+	db := tenantDB(c)
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
 		return
 	}
 
 	var trainings []models.Training
-	if err := config.DB.Where("vendor_id = ?", vendorID).Find(&trainings).Error; err != nil {
+	if err := db.Where("vendor_id = ?", vendorID).Find(&trainings).Error; err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to fetch trainings by vendor")
 		return
 	}
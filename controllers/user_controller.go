@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"ssb_api/config"
+	"ssb_api/controllers/middleware"
 	"ssb_api/models"
 	"ssb_api/models/response"
 	"ssb_api/utils"
@@ -78,7 +79,15 @@ func SearchUsers(c *gin.Context) {
 
 	offset := (page - 1) * limit
 
+	vendorID, ok := vendorFilter(c)
+	if !ok {
+		return
+	}
+
 	query := config.DB.Model(&models.User{})
+	if vendorID != nil {
+		query = query.Where("vendor_id = ?", *vendorID)
+	}
 
 	// Filter berdasarkan parameter query
 	if name := c.Query("name"); name != "" {
@@ -165,7 +174,15 @@ func GetAllUsers(c *gin.Context) {
 
 	offset := (page - 1) * limit
 
+	vendorID, ok := vendorFilter(c)
+	if !ok {
+		return
+	}
+
 	db := config.DB.Model(&models.User{})
+	if vendorID != nil {
+		db = db.Where("vendor_id = ?", *vendorID)
+	}
 
 	// Hitung total
 	if err := db.Count(&total).Error; err != nil {
@@ -277,7 +294,6 @@ func GetUsersByVendor(c *gin.Context) {
 	// Ambil query param: page & limit
 	pageStr := c.DefaultQuery("page", "1")
 	limitStr := c.DefaultQuery("limit", "10")
-	vendorIDStr := c.DefaultQuery("vendor_id", "")
 
 	// Filter tambahan
 	search := c.Query("search")
@@ -294,14 +310,8 @@ func GetUsersByVendor(c *gin.Context) {
 		return
 	}
 
-	if vendorIDStr == "" {
-		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Vendor ID is required")
-		return
-	}
-
-	vendorID, err := strconv.Atoi(vendorIDStr)
-	if err != nil {
-		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid vendor ID")
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
 		return
 	}
 
@@ -415,6 +425,18 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
+	// User lain hanya boleh diubah oleh pengelola di vendor yang sama
+	currentUser, _ := middleware.CurrentUser(c)
+	if user.ID != currentUser.ID {
+		if !middleware.Can(c, middleware.PermUserManage) {
+			response.JSONErrorResponse(c.Writer, false, http.StatusForbidden, "You can only update your own profile")
+			return
+		}
+		if !requireVendorAccess(c, user.VendorID) {
+			return
+		}
+	}
+
 	// Cek format email jika diubah
 	if input.Email != "" && input.Email != user.Email {
 		emailRegex := `^[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}$`
@@ -455,7 +477,15 @@ func UpdateUser(c *gin.Context) {
 	if input.Name != "" {
 		user.Name = input.Name
 	}
-	if input.Role != "" {
+	if input.Role != "" && input.Role != user.Role {
+		if !middleware.Can(c, middleware.PermRoleAssign) {
+			response.JSONErrorResponse(c.Writer, false, http.StatusForbidden, "You are not allowed to change roles")
+			return
+		}
+		if _, known := middleware.RolePermissions[input.Role]; !known {
+			response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Unknown role")
+			return
+		}
 		user.Role = input.Role
 	}
 	if input.Position != "" {
@@ -474,8 +504,29 @@ func UpdateUser(c *gin.Context) {
 		user.Star = input.Star
 	}
 
-	// Update vendor jika berbeda
-	if input.VendorID != nil && input.VendorID != user.VendorID {
+	// Wali menerima salinan pemberitahuan event anaknya. Kirim 0 untuk
+	// melepas wali.
+	if input.GuardianID != nil {
+		if *input.GuardianID == 0 {
+			user.GuardianID = nil
+		} else {
+			var guardian models.User
+			if *input.GuardianID == user.ID || config.DB.First(&guardian, *input.GuardianID).Error != nil ||
+				guardian.VendorID == nil || user.VendorID == nil || *guardian.VendorID != *user.VendorID {
+				response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Guardian must be another user of the same vendor")
+				return
+			}
+			user.GuardianID = &guardian.ID
+		}
+	}
+
+	// Update vendor jika berbeda. Memasang atau memindah vendor hanya boleh
+	// oleh platform admin, pemain memilih vendor saat registrasi.
+	if input.VendorID != nil && (user.VendorID == nil || *input.VendorID != *user.VendorID) {
+		if !middleware.Can(c, middleware.PermAllVendors) {
+			response.JSONErrorResponse(c.Writer, false, http.StatusForbidden, "Only an admin can assign users to a vendor")
+			return
+		}
 		var vendor models.Vendor
 		if err := config.DB.First(&vendor, input.VendorID).Error; err != nil {
 			response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Vendor not found")
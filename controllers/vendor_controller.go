@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"ssb_api/config"
+	"ssb_api/controllers/middleware"
 	"ssb_api/models"
 	"ssb_api/models/response"
 	"ssb_api/utils"
@@ -112,8 +113,11 @@ func GetVendors(c *gin.Context) {
 }
 
 func UpdateVendorPhoto(c *gin.Context) {
-	// Ambil ID Vendor dari parameter URL
-	vendorID := c.Param("id")
+	// Vendor yang diubah selalu vendor milik user, kecuali platform admin
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
 
 	// Ambil file foto yang diupload
 	file, err := c.FormFile("photo")
@@ -165,27 +169,13 @@ func UpdateVendorBank(c *gin.Context) {
 		return
 	}
 
-	// Get UserID from JWT token
-	userIDRaw, exists := c.Get("user_id")
-	if !exists {
-		response.JSONErrorResponse(c.Writer, false, http.StatusUnauthorized, "User ID not found in token")
-		return
-	}
-	userID := uint(userIDRaw.(float64)) // default JWT parsing returns float64
-
-	// Retrieve user details from database
-	var user models.User
-	if err := config.DB.First(&user, userID).Error; err != nil {
+	// Ambil user yang sedang login (permission sudah dicek di route)
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
 		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "User not found")
 		return
 	}
 
-	// Only allow trainers (pelatih) to update bank information
-	if user.Role != "pelatih" {
-		response.JSONErrorResponse(c.Writer, false, http.StatusForbidden, "Only trainers can update vendor bank information")
-		return
-	}
-
 	// Retrieve vendor associated with the logged-in trainer
 	var vendor models.Vendor
 	if err := config.DB.First(&vendor, user.VendorID).Error; err != nil {
@@ -208,14 +198,22 @@ func UpdateVendorBank(c *gin.Context) {
 }
 func UpdateVendorProfile(c *gin.Context) {
 	var input struct {
-		Name          string `json:"name"`
-		Description   string `json:"description"`
-		Email         string `json:"email"`
-		Phone         string `json:"phone"`
-		Address       string `json:"address"`
-		BankName      string `json:"bank_name"`
-		AccountName   string `json:"account_name"`
-		AccountNumber string `json:"account_number"`
+		Name          string   `json:"name"`
+		Description   string   `json:"description"`
+		Email         string   `json:"email"`
+		Phone         string   `json:"phone"`
+		Address       string   `json:"address"`
+		BankName      string   `json:"bank_name"`
+		AccountName   string   `json:"account_name"`
+		AccountNumber string   `json:"account_number"`
+		MembershipFee *float64 `json:"membership_fee"`
+		InvoiceFormat *string  `json:"invoice_format"`
+		InvoiceCode   *string  `json:"invoice_code"`
+		InvoiceDigits *int     `json:"invoice_digits"`
+		// Jadwal pengingat, contoh "-3,0,3,7,14" atau "off"
+		ReminderSchedule *string `json:"reminder_schedule"`
+		// Zona waktu IANA, contoh Asia/Makassar untuk WITA
+		Timezone *string `json:"timezone"`
 	}
 
 	// Binding request body
@@ -224,27 +222,13 @@ func UpdateVendorProfile(c *gin.Context) {
 		return
 	}
 
-	// Get UserID from JWT token
-	userIDRaw, exists := c.Get("user_id")
-	if !exists {
-		response.JSONErrorResponse(c.Writer, false, http.StatusUnauthorized, "User ID not found in token")
-		return
-	}
-	userID := uint(userIDRaw.(float64))
-
-	// Retrieve user from database
-	var user models.User
-	if err := config.DB.First(&user, userID).Error; err != nil {
+	// Ambil user yang sedang login (permission sudah dicek di route)
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
 		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "User not found")
 		return
 	}
 
-	// Only pelatih can update vendor
-	if user.Role != "pelatih" {
-		response.JSONErrorResponse(c.Writer, false, http.StatusForbidden, "Only trainers can update vendor profile")
-		return
-	}
-
 	// Retrieve vendor
 	var vendor models.Vendor
 	if err := config.DB.First(&vendor, user.VendorID).Error; err != nil {
@@ -261,8 +245,57 @@ func UpdateVendorProfile(c *gin.Context) {
 	vendor.BankName = input.BankName
 	vendor.BankAccount = input.AccountName
 	vendor.BankHolder = input.AccountNumber
+	if input.MembershipFee != nil {
+		if *input.MembershipFee < 0 {
+			response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Membership fee cannot be negative")
+			return
+		}
+		vendor.MembershipFee = *input.MembershipFee
+	}
+	if input.InvoiceFormat != nil {
+		if err := utils.ValidateInvoiceFormat(*input.InvoiceFormat); err != nil {
+			response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+			return
+		}
+		vendor.InvoiceFormat = *input.InvoiceFormat
+	}
+	if input.InvoiceCode != nil {
+		vendor.InvoiceCode = strings.ToUpper(strings.TrimSpace(*input.InvoiceCode))
+	}
+	if input.InvoiceDigits != nil {
+		if *input.InvoiceDigits < 0 || *input.InvoiceDigits > 12 {
+			response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invoice digits must be between 0 and 12")
+			return
+		}
+		vendor.InvoiceDigits = *input.InvoiceDigits
+	}
+	if input.ReminderSchedule != nil {
+		if _, err := utils.ParseReminderSchedule(*input.ReminderSchedule); err != nil {
+			response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+			return
+		}
+		vendor.ReminderSchedule = strings.TrimSpace(*input.ReminderSchedule)
+	}
+	oldLocation := vendor.Location()
+	if input.Timezone != nil {
+		timezone := strings.TrimSpace(*input.Timezone)
+		if err := utils.ValidateTimezone(timezone); err != nil {
+			response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+			return
+		}
+		vendor.Timezone = timezone
+	}
 
-	if err := config.DB.Save(&vendor).Error; err != nil {
+	// Jadwal yang belum selesai tetap di jam lokal yang sama di zona baru
+	if err := tenantDB(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&vendor).Error; err != nil {
+			return err
+		}
+		if oldLocation.String() == vendor.Location().String() {
+			return nil
+		}
+		return utils.ReanchorVendorSchedules(tx, vendor.ID, oldLocation, vendor.Location())
+	}); err != nil {
 		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to update vendor profile")
 		return
 	}
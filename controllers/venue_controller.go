@@ -0,0 +1,297 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"ssb_api/models"
+	"ssb_api/models/response"
+	"ssb_api/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// venueInput adalah body create/update venue. Lapangan hanya dibaca saat
+// venue dibuat, setelahnya lewat endpoint lapangan.
+type venueInput struct {
+	Name          string                `json:"name"`
+	Address       string                `json:"address"`
+	LocationPoint string                `json:"location_point"`
+	OpeningHours  []models.OpeningHours `json:"opening_hours"`
+	Pitches       []models.Pitch        `json:"pitches"`
+}
+
+// apply memeriksa nama, titik lokasi dan jam buka lalu mengisinya ke venue.
+func (in venueInput) apply(venue *models.Venue) error {
+	if in.Name == "" {
+		return errors.New("name is required")
+	}
+	if in.LocationPoint != "" {
+		if _, _, ok := utils.ParseLocationPoint(in.LocationPoint); !ok {
+			return errors.New("location_point must be \"lat,lng\"")
+		}
+	}
+	hours, err := utils.NormalizeOpeningHours(in.OpeningHours)
+	if err != nil {
+		return err
+	}
+	venue.Name = in.Name
+	venue.Address = in.Address
+	venue.LocationPoint = in.LocationPoint
+	venue.OpeningHours = hours
+	return nil
+}
+
+// GetVenues menampilkan venue vendor beserta lapangannya.
+func GetVenues(c *gin.Context) {
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+
+	var venues []models.Venue
+	if err := tenantDB(c).Preload("Pitches", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("name ASC")
+	}).Where("vendor_id = ?", vendorID).Order("name ASC").Find(&venues).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to fetch venues")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, venues)
+}
+
+// CreateVenue membuat venue baru, boleh sekaligus dengan lapangannya.
+func CreateVenue(c *gin.Context) {
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+	var input venueInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	venue := models.Venue{VendorID: vendorID}
+	if err := input.apply(&venue); err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, p := range input.Pitches {
+		if p.Name == "" {
+			response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Pitch name is required")
+			return
+		}
+		venue.Pitches = append(venue.Pitches, models.Pitch{VendorID: vendorID, Name: p.Name, Surface: p.Surface})
+	}
+
+	if err := tenantDB(c).Create(&venue).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to create venue")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusCreated, venue)
+}
+
+// UpdateVenue mengubah data venue. Jam buka baru tidak membatalkan event
+// yang sudah dipesan.
+func UpdateVenue(c *gin.Context) {
+	db := tenantDB(c)
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+	var input venueInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	var venue models.Venue
+	if err := db.Where("vendor_id = ?", vendorID).First(&venue, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Venue not found")
+		return
+	}
+	if err := input.apply(&venue); err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := db.Omit("Pitches").Save(&venue).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to update venue")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, venue)
+}
+
+// DeleteVenue menghapus venue yang tidak punya pesanan mendatang.
+func DeleteVenue(c *gin.Context) {
+	db := tenantDB(c)
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+	var venue models.Venue
+	if err := db.Where("vendor_id = ?", vendorID).First(&venue, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Venue not found")
+		return
+	}
+	if hasUpcomingBookings(c, db.Where("venue_id = ?", venue.ID)) {
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("venue_id = ?", venue.ID).Delete(&models.Pitch{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&venue).Error
+	})
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to delete venue")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{"message": "Venue deleted"})
+}
+
+// CreatePitch menambah lapangan ke venue.
+func CreatePitch(c *gin.Context) {
+	db := tenantDB(c)
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+	var input models.Pitch
+	if err := c.ShouldBindJSON(&input); err != nil || input.Name == "" {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, "Pitch name is required")
+		return
+	}
+
+	var venue models.Venue
+	if err := db.Where("vendor_id = ?", vendorID).First(&venue, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Venue not found")
+		return
+	}
+	pitch := models.Pitch{VendorID: vendorID, VenueID: venue.ID, Name: input.Name, Surface: input.Surface}
+	if err := db.Create(&pitch).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to create pitch")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusCreated, pitch)
+}
+
+// DeletePitch menghapus lapangan yang tidak punya pesanan mendatang.
+func DeletePitch(c *gin.Context) {
+	db := tenantDB(c)
+	vendorID, ok := scopedVendorID(c)
+	if !ok {
+		return
+	}
+	var pitch models.Pitch
+	if err := db.Where("vendor_id = ?", vendorID).First(&pitch, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Pitch not found")
+		return
+	}
+	if hasUpcomingBookings(c, db.Where("pitch_id = ?", pitch.ID)) {
+		return
+	}
+	if err := db.Delete(&pitch).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to delete pitch")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{"message": "Pitch deleted"})
+}
+
+// hasUpcomingBookings mengirim 409 bila masih ada event mendatang yang
+// belum dibatalkan di venue atau lapangan.
+func hasUpcomingBookings(c *gin.Context, query *gorm.DB) bool {
+	var count int64
+	if err := query.Model(&models.Event{}).
+		Where("is_cancelled = ? AND is_finish = ? AND start_at >= ?", false, false, time.Now()).
+		Count(&count).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to check bookings")
+		return true
+	}
+	if count > 0 {
+		response.JSONErrorResponse(c.Writer, false, http.StatusConflict, "There are upcoming events booked here, move or cancel them first")
+		return true
+	}
+	return false
+}
+
+// GetVenueAvailability menampilkan slot kosong tiap lapangan venue untuk
+// start_date sampai end_date (maksimal MaxAvailabilityDays hari). Query
+// pitch_id membatasi ke satu lapangan dan min_minutes menyaring slot yang
+// terlalu pendek.
+func GetVenueAvailability(c *gin.Context) {
+	db := tenantDB(c)
+	var venue models.Venue
+	if err := db.First(&venue, c.Param("id")).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusNotFound, "Venue not found")
+		return
+	}
+	if !requireVendorAccess(c, &venue.VendorID) {
+		return
+	}
+
+	from, to, ok := dateRangeParams(c)
+	if !ok {
+		return
+	}
+	loc := utils.VendorLocation(db, venue.VendorID)
+	if from == "" {
+		from = models.DateOf(time.Now().In(loc))
+	}
+	if to == "" {
+		to = from
+	}
+	start, _ := from.Time(loc)
+	end, _ := to.Time(loc)
+	if end.Before(start) || end.Sub(start) >= utils.MaxAvailabilityDays*24*time.Hour {
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest,
+			"end_date must be on or after start_date and at most "+strconv.Itoa(utils.MaxAvailabilityDays)+" days later")
+		return
+	}
+	minMinutes, _ := strconv.Atoi(c.DefaultQuery("min_minutes", "0"))
+
+	query := db.Where("venue_id = ?", venue.ID)
+	if pitchID := c.Query("pitch_id"); pitchID != "" {
+		query = query.Where("id = ?", pitchID)
+	}
+	var pitches []models.Pitch
+	if err := query.Order("name ASC").Find(&pitches).Error; err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to fetch pitches")
+		return
+	}
+
+	availability, err := utils.VenueAvailability(db, venue, pitches, from, to, loc, time.Duration(minMinutes)*time.Minute)
+	if err != nil {
+		response.JSONErrorResponse(c.Writer, false, http.StatusInternalServerError, "Failed to compute availability")
+		return
+	}
+
+	response.JSONSuccess(c.Writer, true, http.StatusOK, gin.H{
+		"venue":    venue,
+		"timezone": loc.String(),
+		"pitches":  availability,
+	})
+}
+
+// respondVenueError memetakan error venue dan bentrok lapangan. Hasilnya
+// false bila err bukan error venue sehingga response belum dikirim.
+func respondVenueError(c *gin.Context, err error) bool {
+	var conflict utils.PitchConflictError
+	switch {
+	case errors.As(err, &conflict), errors.Is(err, utils.ErrVenueClosed):
+		response.JSONErrorResponse(c.Writer, false, http.StatusConflict, err.Error())
+	case errors.Is(err, utils.ErrVenueNotFound), errors.Is(err, utils.ErrPitchNotFound):
+		response.JSONErrorResponse(c.Writer, false, http.StatusBadRequest, err.Error())
+	default:
+		return false
+	}
+	return true
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"log"
 	"ssb_api/config"
 	"ssb_api/routes"
+	"ssb_api/utils"
+	_ "time/tzdata" // zona waktu vendor tetap terbaca di server tanpa tzdata
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,9 +17,20 @@ func main() {
 	// Menghubungkan ke database
 	config.ConnectDatabase()
 
+	// Payment gateway, provider fake hanya untuk dev/test
+	if err := utils.InitPaymentGateway(); err != nil {
+		log.Fatal("❌ Payment gateway: ", err)
+	}
+
 	// 2️⃣ Inisialisasi Firebase
 	config.InitFirebase()
 
+	// Penagihan bulanan otomatis
+	utils.StartBillingScheduler()
+
+	// Pengingat tagihan dan penandaan overdue
+	utils.StartReminderScheduler()
+
 	// Membuat instance gin router
 	r := gin.Default()
 
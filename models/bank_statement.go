@@ -0,0 +1,55 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Status transaksi mutasi bank hasil rekonsiliasi.
+const (
+	BankTransactionUnmatched = "unmatched"
+	BankTransactionProposed  = "proposed"
+	BankTransactionConfirmed = "confirmed"
+	BankTransactionIgnored   = "ignored"
+	// Mutasi yang sama sudah dikonfirmasi dari import lain
+	BankTransactionDuplicate = "duplicate"
+)
+
+// BankStatementImport adalah satu file mutasi rekening yang diunggah vendor.
+type BankStatementImport struct {
+	gorm.Model
+	VendorID     *uint             `json:"vendor_id" gorm:"index"`
+	Format       string            `json:"format"` // bca, mandiri, generic
+	FileName     string            `json:"file_name"`
+	UploadedByID uint              `json:"uploaded_by_id"`
+	TotalRows    int               `json:"total_rows"`
+	Matched      int               `json:"matched"`
+	Transactions []BankTransaction `json:"transactions,omitempty" gorm:"foreignKey:ImportID"`
+}
+
+// BankTransaction adalah satu mutasi kredit beserta usulan pasangan tagihannya.
+type BankTransaction struct {
+	gorm.Model
+	ImportID    uint    `json:"import_id" gorm:"index"`
+	VendorID    *uint   `json:"vendor_id" gorm:"index"`
+	Date        string  `json:"date"`
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount"`
+	Status      string  `json:"status"`
+	PaymentID   *uint   `json:"payment_id" gorm:"index"`
+	MatchReason string  `json:"match_reason"` // invoice, unique_code, amount_date
+	Candidates  int     `json:"candidates"`   // jumlah tagihan yang cocok bila ambigu
+	Fingerprint string  `json:"fingerprint" gorm:"index"`
+}
+
+// BankTransactionFingerprint mengenali mutasi yang sama walau diunggah di file
+// berbeda: tanggal, nominal dan keterangan tanpa beda spasi atau huruf besar.
+func BankTransactionFingerprint(date string, amount float64, description string) string {
+	desc := strings.Join(strings.Fields(strings.ToUpper(description)), " ")
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%.2f|%s", date, amount, desc)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,14 @@
+package models
+
+import "gorm.io/gorm"
+
+// CalendarFeed adalah token langganan feed iCalendar (.ics). Feed user
+// berisi event yang didaftari user, feed vendor berisi semua event publik
+// vendor. Tepat satu dari UserID dan OwnerVendorID terisi. Token bisa
+// diputar kapan saja sehingga URL lama berhenti bekerja.
+type CalendarFeed struct {
+	gorm.Model
+	UserID        *uint  `json:"user_id" gorm:"uniqueIndex"`
+	OwnerVendorID *uint  `json:"vendor_id" gorm:"uniqueIndex"`
+	Token         string `json:"-" gorm:"uniqueIndex"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Hasil tinjauan pelatih atas percobaan check-in yang ditolak.
+const (
+	AttemptPending   = "pending"
+	AttemptApproved  = "approved"
+	AttemptDismissed = "dismissed"
+)
+
+// CheckInAttempt adalah check-in lokasi yang ditolak karena pemain berada
+// di luar radius event. Disimpan beserta jaraknya untuk ditinjau pelatih.
+type CheckInAttempt struct {
+	gorm.Model
+	VendorID   uint       `json:"vendor_id" gorm:"index"`
+	EventID    uint       `json:"event_id" gorm:"index"`
+	UserID     uint       `json:"user_id" gorm:"index"`
+	UserName   string     `json:"user_name"`
+	Latitude   float64    `json:"latitude"`
+	Longitude  float64    `json:"longitude"`
+	Accuracy   float64    `json:"accuracy"` // meter, dari GPS
+	Distance   float64    `json:"distance"` // meter ke titik lokasi event
+	Radius     int        `json:"radius"`   // radius yang berlaku saat itu
+	Status     string     `json:"status" gorm:"index"`
+	ReviewedBy *uint      `json:"reviewed_by"`
+	ReviewedAt *time.Time `json:"reviewed_at"`
+	ReviewNote string     `json:"review_note"`
+}
@@ -0,0 +1,188 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultTimezone dipakai untuk vendor yang belum mengatur zona waktu (WIB).
+const DefaultTimezone = "Asia/Jakarta"
+
+// DateLayout adalah format ISO-8601 untuk tanggal tanpa jam.
+const DateLayout = "2006-01-02"
+
+var ErrInvalidDate = errors.New("invalid date, use YYYY-MM-DD")
+
+// Format tanggal lama yang masih diterima selama masa transisi.
+var legacyDateLayouts = []string{"02/01/2006", "2/1/2006", "02-01-2006", "2-1-2006"}
+
+// Date adalah tanggal tanpa jam, disimpan di kolom DATE dan dikirim sebagai
+// YYYY-MM-DD. String kosong berarti NULL.
+type Date string
+
+// ParseDate membaca tanggal ISO-8601 (boleh berisi jam) atau format lama
+// DD/MM/YYYY dan DD-MM-YYYY.
+func ParseDate(s string) (Date, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+	if t, err := time.Parse(DateLayout, s); err == nil {
+		return DateOf(t), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return DateOf(t), nil
+	}
+	if len(s) > 10 {
+		// "2025-01-06 15:00:00" dan sejenisnya, cukup ambil tanggalnya
+		if t, err := time.Parse(DateLayout, s[:10]); err == nil {
+			return DateOf(t), nil
+		}
+	}
+	for _, layout := range legacyDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return DateOf(t), nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidDate, s)
+}
+
+// DateOf mengambil tanggal dari t sesuai zona waktu t.
+func DateOf(t time.Time) Date {
+	return Date(t.Format(DateLayout))
+}
+
+// Time mengembalikan tanggal sebagai tengah malam di loc.
+func (d Date) Time(loc *time.Location) (time.Time, bool) {
+	t, err := time.ParseInLocation(DateLayout, string(d), loc)
+	return t, err == nil
+}
+
+func (d Date) String() string {
+	return string(d)
+}
+
+func (Date) GormDataType() string {
+	return "date"
+}
+
+func (d *Date) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*d = ""
+	case time.Time:
+		*d = DateOf(v)
+	case string:
+		parsed, err := ParseDate(v)
+		if err != nil {
+			return err
+		}
+		*d = parsed
+	case []byte:
+		parsed, err := ParseDate(string(v))
+		if err != nil {
+			return err
+		}
+		*d = parsed
+	default:
+		return fmt.Errorf("cannot scan %T into Date", value)
+	}
+	return nil
+}
+
+func (d Date) Value() (driver.Value, error) {
+	if d == "" {
+		return nil, nil
+	}
+	return string(d), nil
+}
+
+// UnmarshalJSON menerima format lama lalu menyimpannya sebagai ISO-8601.
+func (d *Date) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*d = ""
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseDate(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// LoadTimezone memuat zona waktu IANA, kosong berarti DefaultTimezone.
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		name = DefaultTimezone
+	}
+	return time.LoadLocation(name)
+}
+
+// DefaultEventDuration dipakai bila jam selesai tidak diisi.
+const DefaultEventDuration = 2 * time.Hour
+
+// ClockLayout adalah format jam lokal yang disimpan di Event.Time.
+const ClockLayout = "15:04"
+
+var ErrInvalidClock = errors.New("invalid time, use HH:MM")
+
+var clockLayouts = []string{"15:04", "15.04", "15:04:05", "3:04 PM", "3:04PM", "3.04 PM"}
+
+// Format waktu tanpa offset, dianggap jam lokal vendor.
+var localTimestampLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+// ParseClock membaca jam "HH:MM" atau format lama seperti "15.30" dan
+// "3:30 PM". Hasilnya selalu HH:MM.
+func ParseClock(s string) (string, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	for _, layout := range clockLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format(ClockLayout), nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidClock, s)
+}
+
+// ParseLocalDateTime menggabungkan tanggal dan jam lokal di zona loc. Jam
+// kosong berarti tengah malam.
+func ParseLocalDateTime(date, clock string, loc *time.Location) (time.Time, error) {
+	d, err := ParseDate(date)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if d == "" {
+		return time.Time{}, ErrInvalidDate
+	}
+	if strings.TrimSpace(clock) == "" {
+		clock = "00:00"
+	}
+	hm, err := ParseClock(clock)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.ParseInLocation(DateLayout+" "+ClockLayout, string(d)+" "+hm, loc)
+}
+
+// ParseTimestamp membaca waktu ISO-8601. Waktu tanpa offset dianggap jam
+// lokal loc.
+func ParseTimestamp(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range localTimestampLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q, use ISO-8601 such as 2006-01-02T15:04:05+07:00", s)
+}
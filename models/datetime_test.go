@@ -0,0 +1,232 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone %s not available: %v", name, err)
+	}
+	return loc
+}
+
+func TestParseDate(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Date
+		err  bool
+	}{
+		{"2025-01-06", "2025-01-06", false},
+		{"  2025-01-06 ", "2025-01-06", false},
+		{"", "", false},
+		{"2025-01-06T15:00:00+07:00", "2025-01-06", false},
+		// Tanggal diambil di zona waktu nilainya sendiri, bukan UTC
+		{"2025-01-06T23:30:00-05:00", "2025-01-06", false},
+		{"2025-01-06 15:00:00", "2025-01-06", false},
+		{"06/01/2025", "2025-01-06", false},
+		{"6/1/2025", "2025-01-06", false},
+		{"06-01-2025", "2025-01-06", false},
+		{"6-1-2025", "2025-01-06", false},
+		{"31/02/2025", "", true},
+		{"01/13/2025", "", true},
+		{"2025/01/06", "", true},
+		{"kemarin", "", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := ParseDate(tc.in)
+			if tc.err {
+				if !errors.Is(err, ErrInvalidDate) {
+					t.Fatalf("expected ErrInvalidDate, got %q, %v", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Fatalf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDateScanAndJSON(t *testing.T) {
+	jakarta := mustLoad(t, "Asia/Jakarta")
+	scans := []struct {
+		in   interface{}
+		want Date
+	}{
+		{nil, ""},
+		{time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC), "2025-01-06"},
+		{time.Date(2025, 1, 6, 23, 0, 0, 0, jakarta), "2025-01-06"},
+		{"06/01/2025", "2025-01-06"},
+		{[]byte("2025-01-06"), "2025-01-06"},
+	}
+	for _, tc := range scans {
+		var d Date
+		if err := d.Scan(tc.in); err != nil {
+			t.Fatalf("Scan(%v): %v", tc.in, err)
+		}
+		if d != tc.want {
+			t.Errorf("Scan(%v) = %q, want %q", tc.in, d, tc.want)
+		}
+	}
+	var d Date
+	if err := d.Scan(42); err == nil {
+		t.Error("Scan(int) should fail")
+	}
+
+	decodes := []struct {
+		in   string
+		want Date
+		err  bool
+	}{
+		{`"06/01/2025"`, "2025-01-06", false},
+		{`"2025-01-06"`, "2025-01-06", false},
+		{`null`, "", false},
+		{`"bukan tanggal"`, "", true},
+		{`20250106`, "", true},
+	}
+	for _, tc := range decodes {
+		var got Date
+		err := json.Unmarshal([]byte(tc.in), &got)
+		if (err != nil) != tc.err || got != tc.want {
+			t.Errorf("Unmarshal(%s) = %q, %v", tc.in, got, err)
+		}
+	}
+
+	if v, err := Date("").Value(); err != nil || v != nil {
+		t.Errorf("empty date should be NULL, got %v, %v", v, err)
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+		err  bool
+	}{
+		{"15:30", "15:30", false},
+		{"07:05", "07:05", false},
+		{"7:05", "07:05", false},
+		{"15.30", "15:30", false},
+		{"15:30:45", "15:30", false},
+		{"3:30 pm", "15:30", false},
+		{"3:30PM", "15:30", false},
+		{"3.30 PM", "15:30", false},
+		{"12:00 AM", "00:00", false},
+		{"25:00", "", true},
+		{"15:3", "", true},
+		{"", "", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := ParseClock(tc.in)
+			if tc.err {
+				if !errors.Is(err, ErrInvalidClock) {
+					t.Fatalf("expected ErrInvalidClock, got %q, %v", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Fatalf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseLocalDateTime(t *testing.T) {
+	jakarta := mustLoad(t, "Asia/Jakarta")
+	berlin := mustLoad(t, "Europe/Berlin")
+	cases := []struct {
+		name        string
+		date, clock string
+		loc         *time.Location
+		wantUTC     string
+		err         error
+	}{
+		{"wib evening", "2025-01-06", "18:00", jakarta, "2025-01-06T11:00:00Z", nil},
+		{"legacy date and clock", "06/01/2025", "18.00", jakarta, "2025-01-06T11:00:00Z", nil},
+		{"empty clock is midnight", "2025-01-06", "", jakarta, "2025-01-05T17:00:00Z", nil},
+		{"before DST change", "2025-03-29", "18:00", berlin, "2025-03-29T17:00:00Z", nil},
+		{"after DST change", "2025-03-30", "18:00", berlin, "2025-03-30T16:00:00Z", nil},
+		{"empty date", "", "18:00", jakarta, "", ErrInvalidDate},
+		{"bad date", "2025-13-01", "18:00", jakarta, "", ErrInvalidDate},
+		{"bad clock", "2025-01-06", "sore", jakarta, "", ErrInvalidClock},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseLocalDateTime(tc.date, tc.clock, tc.loc)
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("expected %v, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Location() != tc.loc {
+				t.Errorf("location %s, want %s", got.Location(), tc.loc)
+			}
+			if utc := got.UTC().Format(time.RFC3339); utc != tc.wantUTC {
+				t.Fatalf("got %s, want %s", utc, tc.wantUTC)
+			}
+		})
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	jakarta := mustLoad(t, "Asia/Jakarta")
+	cases := []struct {
+		in      string
+		wantUTC string
+		err     bool
+	}{
+		{"2025-01-06T18:00:00+07:00", "2025-01-06T11:00:00Z", false},
+		{"2025-01-06T18:00:00Z", "2025-01-06T18:00:00Z", false},
+		// Tanpa offset dianggap jam lokal vendor
+		{"2025-01-06T18:00:00", "2025-01-06T11:00:00Z", false},
+		{"2025-01-06 18:00", "2025-01-06T11:00:00Z", false},
+		{"06/01/2025 18:00", "", true},
+	}
+	for _, tc := range cases {
+		got, err := ParseTimestamp(tc.in, jakarta)
+		if tc.err {
+			if err == nil {
+				t.Errorf("ParseTimestamp(%q) should fail, got %s", tc.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseTimestamp(%q): %v", tc.in, err)
+			continue
+		}
+		if utc := got.UTC().Format(time.RFC3339); utc != tc.wantUTC {
+			t.Errorf("ParseTimestamp(%q) = %s, want %s", tc.in, utc, tc.wantUTC)
+		}
+	}
+}
+
+func TestLoadTimezoneDefault(t *testing.T) {
+	loc, err := LoadTimezone("")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+	if loc.String() != DefaultTimezone {
+		t.Fatalf("got %s, want %s", loc, DefaultTimezone)
+	}
+	if _, err := LoadTimezone("Mars/Olympus"); err == nil {
+		t.Fatal("unknown timezone should fail")
+	}
+}
@@ -1,22 +1,70 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Event struct {
 	gorm.Model
 	Title         string  `json:"title"`
 	Description   string  `json:"description"`
 	EventType     string  `json:"event_type"` // match, tournament, challenge, etc.
-	Date          string  `json:"date"`
-	Time          string  `json:"time"`
+	Date          string  `json:"date"`       // YYYY-MM-DD lokal, salinan StartAt untuk klien lama
+	Time          string  `json:"time"`       // HH:MM lokal, salinan StartAt untuk klien lama
 	Location      string  `json:"location"`
-	LocationPoint string  `json:"location_point"`
+	LocationPoint string  `json:"location_point"` // "lat,lng"
 	IsPaid        bool    `json:"is_paid"`
 	PaymentType   string  `json:"payment_type"` // daily, monthly
 	Fee           float64 `json:"fee"`
 	VendorID      uint    `json:"vendor_id"`
 	IsFinish      bool    `json:"is_finish"`
+	IsPrivate     bool    `json:"is_private"` // tidak tampil di feed kalender publik vendor
+
+	StartAt  *time.Time `json:"start_at" gorm:"index"`
+	EndAt    *time.Time `json:"end_at"`
+	Timezone string     `json:"timezone" gorm:"-"` // zona waktu vendor, diisi saat response
+
+	CheckInRadius int `json:"check_in_radius"` // meter, 0 berarti DefaultCheckInRadius
+
+	VenueID *uint `json:"venue_id" gorm:"index"`
+	PitchID *uint `json:"pitch_id" gorm:"index"` // lapangan yang dipesan, dicek bentrok
+
+	Staff []StaffAssignment `json:"staff,omitempty" gorm:"-"` // pelatih yang bertugas, tidak dikirim berarti tidak diubah saat update
+
+	Capacity             int        `json:"capacity"`              // 0 berarti tanpa batas
+	RegistrationDeadline *time.Time `json:"registration_deadline"` // kosong berarti sampai event selesai
+
+	SeriesID       *uint  `json:"series_id" gorm:"index"`
+	OccurrenceDate string `json:"occurrence_date"` // tanggal asli dalam series, tetap walau Date dipindah
+	IsException    bool   `json:"is_exception"`    // diubah sendiri, tidak ikut perubahan series
+	IsCancelled    bool   `json:"is_cancelled"`
+	CancelReason   string `json:"cancel_reason,omitempty"`
 
 	// Vendor        Vendor  `gorm:"foreignKey:VendorID"`
 	// Users []User `gorm:"many2many:event_participants"`
 }
+
+// SetSchedule mengisi jadwal event beserta Date dan Time lokal di loc.
+func (e *Event) SetSchedule(start, end time.Time, loc *time.Location) {
+	start, end = start.In(loc), end.In(loc)
+	e.StartAt = &start
+	e.EndAt = &end
+	e.Date = start.Format(DateLayout)
+	e.Time = start.Format(ClockLayout)
+	e.Timezone = loc.String()
+}
+
+// InLocation menampilkan StartAt dan EndAt dalam zona waktu loc.
+func (e *Event) InLocation(loc *time.Location) {
+	if e.StartAt != nil {
+		start := e.StartAt.In(loc)
+		e.StartAt = &start
+	}
+	if e.EndAt != nil {
+		end := e.EndAt.In(loc)
+		e.EndAt = &end
+	}
+	e.Timezone = loc.String()
+}
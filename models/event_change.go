@@ -0,0 +1,30 @@
+package models
+
+import "gorm.io/gorm"
+
+// Jenis perubahan event yang diberitahukan ke pemain.
+const (
+	EventChangeUpdated   = "updated" // jadwal atau lokasi berubah
+	EventChangeCancelled = "cancelled"
+	EventChangeRestored  = "restored" // pembatalan dicabut
+)
+
+// EventFieldChange adalah nilai satu field sebelum dan sesudah diubah.
+type EventFieldChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// EventChange mencatat perubahan event yang dikirim ke pemain terdaftar dan
+// walinya. Dipakai aplikasi untuk menampilkan pemberitahuan beserta diff.
+type EventChange struct {
+	gorm.Model
+	VendorID   uint               `json:"vendor_id" gorm:"index"`
+	EventID    uint               `json:"event_id" gorm:"index"`
+	Kind       string             `json:"kind"`
+	Changes    []EventFieldChange `json:"changes" gorm:"serializer:json;type:jsonb"`
+	Reason     string             `json:"reason"`
+	ChangedBy  *uint              `json:"changed_by"`
+	Recipients int                `json:"recipients"` // jumlah user yang diberi tahu
+}
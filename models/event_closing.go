@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EventClosing mencatat apa saja yang diubah saat event diselesaikan supaya
+// bisa dikembalikan persis saat event dibuka kembali.
+type EventClosing struct {
+	gorm.Model
+	VendorID   uint      `json:"vendor_id" gorm:"index"`
+	EventID    uint      `json:"event_id" gorm:"index"`
+	FinishedBy *uint     `json:"finished_by"`
+	FinishedAt time.Time `json:"finished_at"`
+
+	AbsentLogIDs    []uint `json:"absent_log_ids" gorm:"serializer:json;type:jsonb"`    // log yang ditandai absen
+	UncountedLogIDs []uint `json:"uncounted_log_ids" gorm:"serializer:json;type:jsonb"` // log yang sebelumnya dihitung hadir
+	PaymentIDs      []uint `json:"payment_ids" gorm:"serializer:json;type:jsonb"`       // tagihan yang diterbitkan
+
+	Present        int     `json:"present"`
+	Absent         int     `json:"absent"`
+	InvoicedAmount float64 `json:"invoiced_amount"`
+
+	ReopenedBy *uint      `json:"reopened_by"`
+	ReopenedAt *time.Time `json:"reopened_at"`
+}
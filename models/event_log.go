@@ -1,6 +1,24 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Jawaban RSVP pemain untuk sebuah event.
+const (
+	RSVPGoing    = "going"
+	RSVPMaybe    = "maybe"
+	RSVPNotGoing = "not_going"
+)
+
+// Cara pemain tercatat hadir.
+const (
+	CheckInQR    = "qr"
+	CheckInGeo   = "geo"
+	CheckInCoach = "coach" // diisi manual oleh pelatih
+)
 
 type EventLog struct {
 	gorm.Model
@@ -11,4 +29,20 @@ type EventLog struct {
 	EventType string `json:"event_type"`
 	Note      string `json:"note"`
 	Status    bool   `json:"status"`
+
+	RSVP         string     `json:"rsvp" gorm:"index"`
+	Waitlisted   bool       `json:"waitlisted"`    // RSVP going tapi kuota penuh
+	WaitlistedAt *time.Time `json:"waitlisted_at"` // urutan antrean
+
+	CheckedInAt   *time.Time `json:"checked_in_at"`
+	CheckInMethod string     `json:"check_in_method,omitempty"` // lihat CheckIn*
+	// Koordinat mentah dari HP pemain, disimpan untuk penyelesaian sengketa
+	CheckInLatitude  *float64 `json:"check_in_latitude,omitempty"`
+	CheckInLongitude *float64 `json:"check_in_longitude,omitempty"`
+	CheckInAccuracy  *float64 `json:"check_in_accuracy,omitempty"` // meter, dari GPS
+	CheckInDistance  *float64 `json:"check_in_distance,omitempty"` // meter ke titik lokasi event
+	CheckInBy        *uint    `json:"check_in_by,omitempty"`       // pelatih yang mengisi manual
+
+	Absent   bool       `json:"absent"`    // terdaftar tapi tidak check-in saat event selesai
+	LockedAt *time.Time `json:"locked_at"` // dikunci saat event selesai, dibuka lagi bila event dibuka kembali
 }
@@ -0,0 +1,53 @@
+package models
+
+import "gorm.io/gorm"
+
+// Frekuensi pengulangan event.
+const (
+	FrequencyDaily   = "daily"
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+)
+
+// EventSeries adalah event berulang (misal latihan setiap Selasa dan Kamis).
+// Setiap tanggal dibuat sebagai Event biasa dengan SeriesID supaya bisa
+// didaftari, diabsen dan ditagih seperti event lain.
+type EventSeries struct {
+	gorm.Model
+	VendorID      uint    `json:"vendor_id" gorm:"index"`
+	Title         string  `json:"title"`
+	Description   string  `json:"description"`
+	EventType     string  `json:"event_type"`
+	Time          string  `json:"time"` // HH:MM lokal vendor
+	Location      string  `json:"location"`
+	LocationPoint string  `json:"location_point"`
+	IsPaid        bool    `json:"is_paid"`
+	PaymentType   string  `json:"payment_type"`
+	Fee           float64 `json:"fee"`
+	Capacity      int     `json:"capacity"`
+	IsPrivate     bool    `json:"is_private"`
+	CheckInRadius int     `json:"check_in_radius"`
+	// Lama tiap pertemuan, 0 berarti DefaultEventDuration
+	DurationMinutes int `json:"duration_minutes"`
+
+	// Aturan pengulangan ala RRULE
+	StartDate string `json:"start_date"` // YYYY-MM-DD
+	Frequency string `json:"frequency"`  // daily, weekly, monthly
+	Interval  int    `json:"interval"`   // setiap N hari/minggu/bulan, default 1
+	ByDay     string `json:"by_day"`     // hari untuk weekly, misal "TU,TH"
+	Until     string `json:"until"`      // YYYY-MM-DD, kosong berarti tanpa batas
+	Count     int    `json:"count"`      // jumlah pertemuan, 0 berarti tanpa batas
+
+	Exceptions []EventSeriesException `json:"exceptions,omitempty" gorm:"foreignKey:SeriesID"`
+}
+
+// EventSeriesException adalah tanggal dalam series yang dibatalkan, misal
+// karena libur nasional.
+type EventSeriesException struct {
+	gorm.Model
+	SeriesID  uint   `json:"series_id" gorm:"index"`
+	VendorID  uint   `json:"vendor_id" gorm:"index"`
+	Date      string `json:"date"` // tanggal asli pertemuan, YYYY-MM-DD
+	Cancelled bool   `json:"cancelled"`
+	Reason    string `json:"reason"`
+}
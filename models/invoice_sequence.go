@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// InvoiceSequence menyimpan nomor invoice terakhir per vendor per tahun.
+type InvoiceSequence struct {
+	ID         uint `gorm:"primarykey"`
+	VendorID   uint `json:"vendor_id" gorm:"uniqueIndex:idx_invoice_sequences_vendor_year"`
+	Year       int  `json:"year" gorm:"uniqueIndex:idx_invoice_sequences_vendor_year"`
+	LastNumber int  `json:"last_number"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
@@ -1,15 +1,47 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Match struct {
 	gorm.Model
 	Title       string `json:"title"`
 	Description string `json:"description"`
-	Date        string `json:"date"`
+	Date        string `json:"date"` // YYYY-MM-DD lokal, salinan StartAt untuk klien lama
 	Location    string `json:"location"`
 	VendorID    *uint  `json:"vendor_id"`
 	// Vendor      *Vendor `gorm:"foreignKey:VendorID"`
 	EventID *uint `json:"event_id"`
 	// Event       *Event  `gorm:"foreignKey:EventID"`
+
+	StartAt  *time.Time `json:"start_at" gorm:"index"`
+	EndAt    *time.Time `json:"end_at"`
+	Timezone string     `json:"timezone" gorm:"-"`
+
+	Staff []StaffAssignment `json:"staff,omitempty" gorm:"-"`
+}
+
+// SetSchedule mengisi jadwal match beserta Date lokal di loc.
+func (m *Match) SetSchedule(start, end time.Time, loc *time.Location) {
+	start, end = start.In(loc), end.In(loc)
+	m.StartAt = &start
+	m.EndAt = &end
+	m.Date = start.Format(DateLayout)
+	m.Timezone = loc.String()
+}
+
+// InLocation menampilkan StartAt dan EndAt dalam zona waktu loc.
+func (m *Match) InLocation(loc *time.Location) {
+	if m.StartAt != nil {
+		start := m.StartAt.In(loc)
+		m.StartAt = &start
+	}
+	if m.EndAt != nil {
+		end := m.EndAt.In(loc)
+		m.EndAt = &end
+	}
+	m.Timezone = loc.String()
 }
@@ -4,18 +4,34 @@ import "gorm.io/gorm"
 
 type Payment struct {
 	gorm.Model
-	UserID   uint    `json:"user_id"`
-	VendorID *uint   `json:"vendor_id"`
-	EventID  *uint   `json:"event_id"` // nullable
-	Amount   float64 `json:"amount"`
-	Method   string  `json:"method"` // cash, transfer, e-wallet
-	Status   string  `json:"status"` // pending, success, failed
-	Type     string  `json:"type"`   // general, event
-	Date     string  `json:"date"`
-	Note     string  `json:"note"`
-	Photo    string  `json:"photo,omitempty"`
-	Invoice  string  `json:"invoice"` // ← new column
-	UserName string  `json:"user_name"`
+	UserID         uint    `json:"user_id"`
+	VendorID       *uint   `json:"vendor_id"`
+	EventID        *uint   `json:"event_id"` // nullable
+	Amount         float64 `json:"amount"`
+	Method         string  `json:"method"` // cash, transfer, e-wallet
+	Status         string  `json:"status"` // lihat PaymentStatus*
+	Type           string  `json:"type"`   // general, event, membership
+	Date           Date    `json:"date"`
+	Note           string  `json:"note"`
+	Photo          string  `json:"photo,omitempty"`
+	Invoice        string  `json:"invoice"` // ← new column
+	UserName       string  `json:"user_name"`
+	DueDate        Date    `json:"due_date"`                    // format: YYYY-MM-DD
+	BillingPeriod  string  `json:"billing_period" gorm:"index"` // format: YYYY-MM, untuk tagihan bulanan
+	PaidAmount     float64 `json:"paid_amount"`                 // total cicilan yang sudah dikonfirmasi
+	GrossAmount    float64 `json:"gross_amount"`                // harga sebelum potongan
+	PromoCode      string  `json:"promo_code"`
+	RefundedAmount float64 `json:"refunded_amount"` // total refund yang sudah disetujui
+
+	LineItems []PaymentLineItem `json:"line_items,omitempty" gorm:"foreignKey:PaymentID"`
+}
+
+// Outstanding adalah sisa tagihan yang belum dibayar.
+func (p Payment) Outstanding() float64 {
+	if p.PaidAmount >= p.Amount {
+		return 0
+	}
+	return p.Amount - p.PaidAmount
 }
 
 type PaymentRequest struct {
@@ -30,4 +46,76 @@ type PaymentRequest struct {
 	Note     string  `form:"note"`
 	UserName string  `form:"user_name"`
 	Invoice  string  `form:"invoice"` // ← new field
+	DueDate  string  `form:"due_date"`
+	// Kode promo opsional, harga akhir dihitung lewat aturan harga vendor
+	PromoCode string `form:"promo_code"`
+}
+
+// Status pembayaran. Nilai lama "pending", "success" dan "failed" masih
+// diterima lewat NormalizePaymentStatus.
+const (
+	PaymentStatusDraft                = "draft"
+	PaymentStatusIssued               = "issued"
+	PaymentStatusAwaitingVerification = "awaiting_verification"
+	PaymentStatusPartiallyPaid        = "partially_paid"
+	PaymentStatusPaid                 = "paid"
+	PaymentStatusRejected             = "rejected"
+	PaymentStatusRefunded             = "refunded"
+	PaymentStatusCancelled            = "cancelled"
+	PaymentStatusOverdue              = "overdue"
+)
+
+// PaymentTransitions adalah daftar perpindahan status yang diizinkan.
+var PaymentTransitions = map[string][]string{
+	PaymentStatusDraft:                {PaymentStatusIssued, PaymentStatusCancelled},
+	PaymentStatusIssued:               {PaymentStatusAwaitingVerification, PaymentStatusPartiallyPaid, PaymentStatusPaid, PaymentStatusOverdue, PaymentStatusCancelled},
+	PaymentStatusOverdue:              {PaymentStatusAwaitingVerification, PaymentStatusPartiallyPaid, PaymentStatusPaid, PaymentStatusCancelled},
+	PaymentStatusAwaitingVerification: {PaymentStatusPartiallyPaid, PaymentStatusPaid, PaymentStatusRejected},
+	PaymentStatusRejected:             {PaymentStatusAwaitingVerification, PaymentStatusPartiallyPaid, PaymentStatusPaid, PaymentStatusOverdue, PaymentStatusCancelled},
+	PaymentStatusPartiallyPaid:        {PaymentStatusAwaitingVerification, PaymentStatusPaid, PaymentStatusOverdue, PaymentStatusCancelled},
+	PaymentStatusPaid:                 {PaymentStatusRefunded},
+	PaymentStatusRefunded:             {},
+	PaymentStatusCancelled:            {},
+}
+
+var legacyPaymentStatus = map[string]string{
+	"pending": PaymentStatusIssued,
+	"success": PaymentStatusPaid,
+	"failed":  PaymentStatusRejected,
+}
+
+// NormalizePaymentStatus mengubah status lama ke status baru.
+func NormalizePaymentStatus(status string) string {
+	if s, ok := legacyPaymentStatus[status]; ok {
+		return s
+	}
+	return status
+}
+
+// IsValidPaymentStatus cek apakah status dikenal.
+func IsValidPaymentStatus(status string) bool {
+	_, ok := PaymentTransitions[status]
+	return ok
+}
+
+// CanTransitionPayment cek apakah status boleh berpindah dari from ke to.
+func CanTransitionPayment(from, to string) bool {
+	for _, next := range PaymentTransitions[NormalizePaymentStatus(from)] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// PaymentStatusHistory mencatat setiap perubahan status pembayaran.
+type PaymentStatusHistory struct {
+	gorm.Model
+	PaymentID  uint   `json:"payment_id" gorm:"index"`
+	VendorID   *uint  `json:"vendor_id"`
+	FromStatus string `json:"from_status"`
+	ToStatus   string `json:"to_status"`
+	ActorID    *uint  `json:"actor_id"` // nil jika perubahan oleh sistem
+	ActorName  string `json:"actor_name"`
+	Reason     string `json:"reason"`
 }
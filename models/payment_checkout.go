@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PaymentCheckout adalah sesi pembayaran di payment gateway untuk satu Payment.
+type PaymentCheckout struct {
+	gorm.Model
+	PaymentID uint       `json:"payment_id" gorm:"index"`
+	VendorID  *uint      `json:"vendor_id"`
+	Provider  string     `json:"provider"`
+	Reference string     `json:"reference" gorm:"uniqueIndex"`
+	Channel   string     `json:"channel"` // snap, va, qris
+	Amount    float64    `json:"amount"`
+	PayURL    string     `json:"pay_url,omitempty"`
+	VANumber  string     `json:"va_number,omitempty"`
+	QRString  string     `json:"qr_string,omitempty"`
+	Status    string     `json:"status"` // pending, paid, failed, expired
+	ExpiresAt time.Time  `json:"expires_at"`
+	PaidAt    *time.Time `json:"paid_at"`
+
+	// Dana masuk untuk tagihan yang sudah tidak bisa dilunasi (dibatalkan,
+	// direfund atau sudah lunas lewat jalur lain), perlu refund atau
+	// diperiksa manual
+	NeedsReview bool   `json:"needs_review" gorm:"index"`
+	ReviewNote  string `json:"review_note,omitempty"`
+}
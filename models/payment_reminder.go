@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PaymentReminder mencatat pengingat yang sudah dikirim untuk satu tagihan.
+// DayOffset adalah selisih hari terhadap jatuh tempo (negatif = sebelum).
+type PaymentReminder struct {
+	gorm.Model
+	PaymentID      uint      `json:"payment_id" gorm:"uniqueIndex:idx_payment_reminders_offset"`
+	DayOffset      int       `json:"day_offset" gorm:"uniqueIndex:idx_payment_reminders_offset"`
+	VendorID       *uint     `json:"vendor_id" gorm:"index"`
+	UserID         uint      `json:"user_id"`
+	NotificationID uint      `json:"notification_id"`
+	SentAt         time.Time `json:"sent_at"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "gorm.io/gorm"
+
+// Status cicilan pembayaran.
+const (
+	PaymentTransactionPending   = "pending"
+	PaymentTransactionConfirmed = "confirmed"
+	PaymentTransactionRejected  = "rejected"
+)
+
+// PaymentMethodCredit dipakai untuk cicilan yang dibayar dari saldo kredit.
+const PaymentMethodCredit = "credit"
+
+// PaymentTransaction adalah satu pembayaran (cicilan) untuk sebuah tagihan.
+type PaymentTransaction struct {
+	gorm.Model
+	PaymentID    uint    `json:"payment_id" gorm:"index"`
+	VendorID     *uint   `json:"vendor_id" gorm:"index"`
+	UserID       uint    `json:"user_id" gorm:"index"`
+	Amount       float64 `json:"amount"`
+	Method       string  `json:"method"` // cash, transfer, credit, atau provider gateway
+	Date         string  `json:"date"`   // format: YYYY-MM-DD
+	Photo        string  `json:"photo,omitempty"`
+	Note         string  `json:"note"`
+	Status       string  `json:"status"` // pending, confirmed, rejected
+	VerifiedByID *uint   `json:"verified_by_id"`
+	Reason       string  `json:"reason"`
+}
+
+// AccountCredit adalah mutasi saldo kredit pemain di satu vendor. Nilai
+// positif menambah saldo (kelebihan bayar), negatif memakai saldo.
+type AccountCredit struct {
+	gorm.Model
+	UserID        uint    `json:"user_id" gorm:"index"`
+	VendorID      *uint   `json:"vendor_id" gorm:"index"`
+	Amount        float64 `json:"amount"`
+	PaymentID     *uint   `json:"payment_id"`
+	TransactionID *uint   `json:"transaction_id"`
+	Note          string  `json:"note"`
+}
@@ -0,0 +1,92 @@
+package models
+
+import "gorm.io/gorm"
+
+// Jenis potongan harga.
+const (
+	DiscountPercent = "percent"
+	DiscountFixed   = "fixed"
+)
+
+// Jenis aturan harga.
+const (
+	PricingRuleGeneral   = "general"    // selalu berlaku dalam cakupannya
+	PricingRuleEarlyBird = "early_bird" // berlaku di antara ValidFrom dan ValidUntil
+	PricingRuleSibling   = "sibling"    // anak ke-MinSiblings dst. dalam satu keluarga
+)
+
+// PricingRule adalah aturan potongan harga otomatis milik vendor. EventID dan
+// PaymentType kosong berarti berlaku untuk semua tagihan.
+type PricingRule struct {
+	gorm.Model
+	VendorID     *uint   `json:"vendor_id" gorm:"index"`
+	Name         string  `json:"name"`
+	Kind         string  `json:"kind"`          // general, early_bird, sibling
+	DiscountType string  `json:"discount_type"` // percent, fixed
+	Value        float64 `json:"value"`
+	EventID      *uint   `json:"event_id"`
+	PaymentType  string  `json:"payment_type"` // event, membership, ...
+	MinSiblings  int     `json:"min_siblings"` // untuk sibling, default 2
+	ValidFrom    string  `json:"valid_from"`   // format: YYYY-MM-DD
+	ValidUntil   string  `json:"valid_until"`  // format: YYYY-MM-DD
+	Priority     int     `json:"priority"`     // kecil diterapkan lebih dulu
+	Active       bool    `json:"active" gorm:"default:true"`
+}
+
+// Scholarship adalah beasiswa penuh atau sebagian untuk satu pemain.
+type Scholarship struct {
+	gorm.Model
+	VendorID     *uint   `json:"vendor_id" gorm:"index"`
+	UserID       uint    `json:"user_id" gorm:"index"`
+	Name         string  `json:"name"`
+	DiscountType string  `json:"discount_type"` // percent (100 = penuh), fixed
+	Value        float64 `json:"value"`
+	PaymentType  string  `json:"payment_type"`
+	ValidFrom    string  `json:"valid_from"`
+	ValidUntil   string  `json:"valid_until"`
+	Note         string  `json:"note"`
+}
+
+// Family mengelompokkan pemain bersaudara untuk potongan sibling.
+type Family struct {
+	gorm.Model
+	VendorID *uint  `json:"vendor_id" gorm:"index"`
+	Name     string `json:"name"`
+	Members  []User `json:"members,omitempty" gorm:"foreignKey:FamilyID"`
+}
+
+// PromoCode adalah kode promo dengan masa berlaku dan batas pemakaian.
+type PromoCode struct {
+	gorm.Model
+	VendorID     *uint   `json:"vendor_id" gorm:"uniqueIndex:idx_promo_codes_vendor_code"`
+	Code         string  `json:"code" gorm:"uniqueIndex:idx_promo_codes_vendor_code"`
+	DiscountType string  `json:"discount_type"`
+	Value        float64 `json:"value"`
+	EventID      *uint   `json:"event_id"`
+	PaymentType  string  `json:"payment_type"`
+	ValidFrom    string  `json:"valid_from"`
+	ValidUntil   string  `json:"valid_until"`
+	MaxUses      int     `json:"max_uses"` // 0 berarti tanpa batas
+	UsedCount    int     `json:"used_count"`
+	Active       bool    `json:"active" gorm:"default:true"`
+}
+
+// Jenis baris rincian tagihan.
+const (
+	LineItemBase     = "base"
+	LineItemDiscount = "discount"
+)
+
+// PaymentLineItem adalah rincian tagihan: harga dasar dan setiap potongan.
+// Amount potongan bernilai negatif.
+type PaymentLineItem struct {
+	gorm.Model
+	PaymentID     uint    `json:"payment_id" gorm:"index"`
+	VendorID      *uint   `json:"vendor_id"`
+	Kind          string  `json:"kind"` // base, discount
+	Description   string  `json:"description"`
+	Amount        float64 `json:"amount"`
+	PricingRuleID *uint   `json:"pricing_rule_id,omitempty"`
+	ScholarshipID *uint   `json:"scholarship_id,omitempty"`
+	PromoCodeID   *uint   `json:"promo_code_id,omitempty"`
+}
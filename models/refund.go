@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Status refund.
+const (
+	RefundStatusRequested  = "requested"
+	RefundStatusApproved   = "approved"   // nota kredit terbit, menunggu pengembalian dana
+	RefundStatusProcessing = "processing" // dikirim ke provider, menunggu konfirmasi
+	RefundStatusPaidOut    = "paid_out"
+	RefundStatusRejected   = "rejected"
+)
+
+// Cara pengembalian dana.
+const (
+	RefundPayoutManual   = "manual"   // transfer/tunai oleh pengelola
+	RefundPayoutProvider = "provider" // lewat payment gateway pembayaran asal
+	RefundPayoutCredit   = "credit"   // masuk saldo kredit pemain
+)
+
+// Refund adalah pengembalian dana penuh atau sebagian atas tagihan yang sudah
+// lunas. Diajukan pelatih, disetujui admin, lalu dananya dikembalikan.
+type Refund struct {
+	gorm.Model
+	PaymentID        uint       `json:"payment_id" gorm:"index"`
+	VendorID         *uint      `json:"vendor_id" gorm:"index"`
+	UserID           uint       `json:"user_id" gorm:"index"`
+	Amount           float64    `json:"amount"`
+	Reason           string     `json:"reason"`
+	Status           string     `json:"status"`
+	RequestedByID    uint       `json:"requested_by_id"`
+	ApprovedByID     *uint      `json:"approved_by_id"`
+	ApprovedAt       *time.Time `json:"approved_at"`
+	RejectReason     string     `json:"reject_reason,omitempty"`
+	CreditNoteNumber string     `json:"credit_note_number"`
+	PayoutMethod     string     `json:"payout_method"`
+	PayoutReference  string     `json:"payout_reference"`
+	PaidOutAt        *time.Time `json:"paid_out_at"`
+}
+
+// CreditNoteSequence menyimpan nomor nota kredit terakhir per vendor per tahun.
+type CreditNoteSequence struct {
+	ID         uint `gorm:"primarykey"`
+	VendorID   uint `json:"vendor_id" gorm:"uniqueIndex:idx_credit_note_sequences_vendor_year"`
+	Year       int  `json:"year" gorm:"uniqueIndex:idx_credit_note_sequences_vendor_year"`
+	LastNumber int  `json:"last_number"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
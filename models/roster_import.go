@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Status import roster.
+const (
+	RosterImportPreview   = "preview"
+	RosterImportCommitted = "committed"
+)
+
+// RosterImport adalah satu file daftar pemain (CSV/XLSX) yang diunggah
+// pelatih. Baris disimpan dulu sebagai preview, pemain baru dibuat setelah
+// import di-commit.
+type RosterImport struct {
+	gorm.Model
+	VendorID     *uint             `json:"vendor_id" gorm:"index"`
+	FileName     string            `json:"file_name"`
+	UploadedByID uint              `json:"uploaded_by_id"`
+	Status       string            `json:"status"`
+	TotalRows    int               `json:"total_rows"`
+	ValidRows    int               `json:"valid_rows"`
+	Imported     int               `json:"imported"`
+	CommittedAt  *time.Time        `json:"committed_at"`
+	Rows         []RosterImportRow `json:"rows,omitempty" gorm:"foreignKey:ImportID"`
+}
+
+// RosterImportRow adalah satu baris file beserta hasil validasinya. Errors
+// berisi pesan kesalahan dipisah "; ", kosong berarti baris valid.
+type RosterImportRow struct {
+	gorm.Model
+	ImportID    uint   `json:"import_id" gorm:"index"`
+	VendorID    *uint  `json:"vendor_id" gorm:"index"`
+	Line        int    `json:"line"` // nomor baris di file, header = 1
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	Phone       string `json:"phone"`
+	Gender      string `json:"gender"`
+	BirthDate   string `json:"birth_date"`
+	Position    string `json:"position"`
+	Foot        string `json:"foot"`
+	Number      int    `json:"number"`
+	AgeCategory string `json:"age_category"`
+	Address     string `json:"address"`
+	Errors      string `json:"errors"`
+	UserID      *uint  `json:"user_id"` // terisi setelah pemain dibuat
+}
+
+// Valid menandakan baris bisa dibuat menjadi pemain.
+func (r RosterImportRow) Valid() bool {
+	return r.Errors == ""
+}
+
+// UserInvitation adalah tautan bagi pemain hasil import untuk membuat
+// password sendiri. Yang disimpan hanya hash token.
+type UserInvitation struct {
+	gorm.Model
+	UserID     uint       `json:"user_id" gorm:"index"`
+	VendorID   *uint      `json:"vendor_id" gorm:"index"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "gorm.io/gorm"
+
+// Peran pelatih dalam satu sesi.
+const (
+	StaffHeadCoach = "head_coach"
+	StaffAssistant = "assistant"
+)
+
+// Jenis sesi yang bisa diberi pelatih.
+const (
+	SessionEvent    = "event"
+	SessionTraining = "training"
+	SessionMatch    = "match"
+)
+
+// StaffAssignment menugaskan pelatih ke event, training atau match. Satu
+// sesi punya paling banyak satu head coach dan boleh beberapa asisten.
+type StaffAssignment struct {
+	gorm.Model
+	VendorID    uint   `json:"vendor_id" gorm:"index"`
+	SessionType string `json:"session_type" gorm:"index:idx_staff_assignments_session"`
+	SessionID   uint   `json:"session_id" gorm:"index:idx_staff_assignments_session"`
+	UserID      uint   `json:"user_id" gorm:"index"`
+	UserName    string `json:"user_name"`
+	Role        string `json:"role"` // lihat Staff*
+}
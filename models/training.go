@@ -9,6 +9,8 @@ type Training struct {
 	Notes    string `json:"notes"`
 	VendorID *uint  // tambahkan ini untuk relasi opsional ke vendor
 	// Vendor   *Vendor `gorm:"foreignKey:VendorID"`
-	EventID *uint `json:"event_id"`
+	EventID *uint `json:"event_id"` // jadwal training mengikuti event ini
 	// Event    *Event  `gorm:"foreignKey:EventID"`
+
+	Staff []StaffAssignment `json:"staff,omitempty" gorm:"-"`
 }
@@ -26,4 +26,28 @@ type User struct {
 	Match       int     `json:"match"`
 	Training    int     `json:"training"`
 	Program     int     `json:"program"`
+	FamilyID    *uint   `json:"family_id" gorm:"index"`   // keluarga untuk potongan saudara
+	GuardianID  *uint   `json:"guardian_id" gorm:"index"` // akun orang tua/wali yang ikut menerima pemberitahuan
+}
+
+// Role yang dikenal sistem. Role baru cukup ditambahkan di sini lalu
+// didaftarkan ke tabel permission di controllers/middleware/rbac.go.
+const (
+	RolePemain  = "pemain"
+	RolePelatih = "pelatih"
+	RoleAdmin   = "admin"
+)
+
+// Posisi bermain yang diterima saat import roster.
+var PlayerPositions = []string{
+	"Kiper",
+	"Bek Tengah",
+	"Bek Kanan",
+	"Bek Kiri",
+	"Gelandang Bertahan",
+	"Gelandang Tengah",
+	"Gelandang Serang",
+	"Sayap Kanan",
+	"Sayap Kiri",
+	"Penyerang",
 }
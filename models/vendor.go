@@ -1,6 +1,10 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Vendor struct {
 	gorm.Model
@@ -14,5 +18,27 @@ type Vendor struct {
 	BankAccount string `json:"bank_account"` // Nomor rekening
 	BankHolder  string `json:"bank_holder"`
 	Category    string `json:"category"`
+	// Iuran bulanan akademi, 0 berarti tidak ada iuran keanggotaan
+	MembershipFee float64 `json:"membership_fee"`
+	// Format nomor invoice, placeholder: {CODE}, {YYYY}, {YY}, {SEQ}.
+	// Kosong berarti INV/{CODE}/{YYYY}/{SEQ}
+	InvoiceFormat string `json:"invoice_format"`
+	InvoiceCode   string `json:"invoice_code"`   // kode akademi, contoh: ACADEMY
+	InvoiceDigits int    `json:"invoice_digits"` // panjang {SEQ}, default 6
+	// Jadwal pengingat tagihan dalam hari terhadap jatuh tempo, contoh
+	// "-3,0,3,7,14". Kosong berarti jadwal default, "off" untuk mematikan
+	ReminderSchedule string `json:"reminder_schedule"`
+	// Zona waktu IANA jadwal vendor: Asia/Jakarta (WIB), Asia/Makassar
+	// (WITA) atau Asia/Jayapura (WIT)
+	Timezone string `json:"timezone" gorm:"default:Asia/Jakarta"`
 	// Payments    []Payment `gorm:"foreignKey:VendorID"`
 }
+
+// Location adalah zona waktu vendor, DefaultTimezone bila belum diatur.
+func (v Vendor) Location() *time.Location {
+	if loc, err := LoadTimezone(v.Timezone); err == nil {
+		return loc
+	}
+	loc, _ := LoadTimezone(DefaultTimezone)
+	return loc
+}
@@ -0,0 +1,33 @@
+package models
+
+import "gorm.io/gorm"
+
+// Venue adalah tempat latihan atau pertandingan milik vendor beserta
+// lapangan-lapangannya.
+type Venue struct {
+	gorm.Model
+	VendorID      uint           `json:"vendor_id" gorm:"index"`
+	Name          string         `json:"name"`
+	Address       string         `json:"address"`
+	LocationPoint string         `json:"location_point"`                                  // "lat,lng"
+	OpeningHours  []OpeningHours `json:"opening_hours" gorm:"serializer:json;type:jsonb"` // kosong berarti buka setiap saat
+	Pitches       []Pitch        `json:"pitches,omitempty" gorm:"foreignKey:VenueID"`
+}
+
+// Pitch adalah satu lapangan di venue. Event di lapangan yang sama tidak
+// boleh bentrok jadwalnya.
+type Pitch struct {
+	gorm.Model
+	VendorID uint   `json:"vendor_id" gorm:"index"`
+	VenueID  uint   `json:"venue_id" gorm:"index"`
+	Name     string `json:"name"`
+	Surface  string `json:"surface"` // rumput, sintetis, futsal, dll.
+}
+
+// OpeningHours adalah jam buka venue pada satu hari. Satu hari boleh punya
+// beberapa rentang, misal pagi dan sore.
+type OpeningHours struct {
+	Weekday int    `json:"weekday"` // 0 Minggu sampai 6 Sabtu
+	Open    string `json:"open"`    // HH:MM lokal
+	Close   string `json:"close"`   // HH:MM lokal, setelah Open
+}
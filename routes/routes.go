@@ -3,6 +3,7 @@ package routes
 import (
 	"ssb_api/controllers"
 	"ssb_api/controllers/middleware"
+	"ssb_api/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,60 +18,155 @@ func SetupRoutes(router *gin.Engine) {
 		api.POST("/auth/firebase", controllers.FirebaseLogin)
 
 		api.POST("/refresh-token", controllers.RefreshToken)
+		// Webhook fake hanya dikenali bila providernya didaftarkan, lihat
+		// utils.InitPaymentGateway
+		api.POST("/payment/webhook/:provider", controllers.PaymentWebhook)
+		if utils.FakePaymentEnabled() {
+			api.GET("/payment/gateway/fake/:reference/pay", controllers.SimulateFakePayment)
+		}
 		api.POST("/register", controllers.Register)
+		api.GET("/invitation/:token", controllers.GetInvitation)
+		api.POST("/invitation/:token", controllers.AcceptInvitation)
+		api.GET("/calendar/:file", controllers.GetCalendarFeed)
 		api.GET("/vendor", controllers.GetVendors)
 		api.POST("/vendor/create", controllers.CreateVendor)
-		api.DELETE("/vendor/:id", controllers.DeleteVendorByID)
 
 		// Protected routes with JWT middleware
 		protected := api.Group("", middleware.JWTAuthMiddleware())
 		{
 			// Users
 			protected.GET("/user/profile", controllers.GetUserFromToken)
-			protected.GET("/users", controllers.GetAllUsers)
-			protected.GET("/users/vendor", controllers.GetUsersByVendor)
-			protected.GET("/users/search", controllers.SearchUsers)
+			protected.GET("/users", middleware.RequirePermission(middleware.PermUserRead), controllers.GetAllUsers)
+			protected.GET("/users/vendor", middleware.RequirePermission(middleware.PermUserRead), controllers.GetUsersByVendor)
+			protected.GET("/users/vendor/export", middleware.RequirePermission(middleware.PermUserManage), controllers.ExportUsersByVendor)
+			protected.POST("/users/import", middleware.RequirePermission(middleware.PermUserManage), controllers.ImportRosterPreview)
+			protected.GET("/users/import/:id", middleware.RequirePermission(middleware.PermUserManage), controllers.GetRosterImport)
+			protected.POST("/users/import/:id/commit", middleware.RequirePermission(middleware.PermUserManage), controllers.CommitRosterImport)
+			protected.POST("/users/:id/invitation", middleware.RequirePermission(middleware.PermUserManage), controllers.ResendInvitation)
+			protected.POST("/users/coach/invitation", middleware.RequirePermission(middleware.PermRoleAssign), controllers.InviteCoach)
+			protected.GET("/users/search", middleware.RequirePermission(middleware.PermUserRead), controllers.SearchUsers)
 			protected.PUT("/user/foto", controllers.UpdateUserPhoto)
-			protected.PUT("/vendor/foto", controllers.UpdateVendorPhoto)
-			protected.PUT("/vendor/bank", controllers.UpdateVendorBank)
-			protected.PUT("/vendor/update", controllers.UpdateVendorProfile)
-			protected.PUT("/user/update", controllers.UpdateUser)
+			protected.PUT("/vendor/foto", middleware.RequirePermission(middleware.PermVendorManage), controllers.UpdateVendorPhoto)
+			protected.PUT("/vendor/bank", middleware.RequirePermission(middleware.PermVendorManage), controllers.UpdateVendorBank)
+			protected.PUT("/vendor/update", middleware.RequirePermission(middleware.PermVendorManage), controllers.UpdateVendorProfile)
+			protected.DELETE("/vendor/:id", middleware.RequirePermission(middleware.PermAllVendors), controllers.DeleteVendorByID)
+			protected.PUT("/user/update", middleware.RequirePermission(), controllers.UpdateUser)
 
 			// Payments
-			protected.GET("/payments/user", controllers.GetPaymentsUser)
-			protected.GET("/payments", controllers.GetPayments)
-			protected.POST("/payment/create", controllers.CreatePayment)
-			protected.GET("/payments/vendor", controllers.GetPaymentsByVendor)
-			protected.PUT("/payment/status", controllers.UpdatePaymentStatus)
-			protected.POST("/payment/bulk", controllers.CreateBulkPaymentByEvent)
-			protected.PUT("/payment/proof", controllers.UploadPaymentProof)
+			protected.GET("/payments/user", middleware.RequirePermission(middleware.PermPaymentReadOwn), controllers.GetPaymentsUser)
+			protected.GET("/payments", middleware.RequirePermission(middleware.PermPaymentRead), controllers.GetPayments)
+			protected.POST("/payment/create", middleware.RequirePermission(middleware.PermPaymentCreate), controllers.CreatePayment)
+			protected.GET("/payments/vendor", middleware.RequirePermission(middleware.PermPaymentRead), controllers.GetPaymentsByVendor)
+			protected.GET("/payments/vendor/export", middleware.RequirePermission(middleware.PermPaymentRead), controllers.ExportPaymentsByVendor)
+			protected.PUT("/payment/status", middleware.RequirePermission(middleware.PermPaymentManage), controllers.UpdatePaymentStatus)
+			protected.POST("/payment/bulk", middleware.RequirePermission(middleware.PermPaymentManage), controllers.CreateBulkPaymentByEvent)
+			protected.PUT("/payment/proof", middleware.RequirePermission(middleware.PermPaymentPay), controllers.UploadPaymentProof)
+			protected.GET("/payment/:id/history", middleware.RequirePermission(middleware.PermPaymentReadOwn), controllers.GetPaymentHistory)
+			protected.POST("/payment/:id/checkout", middleware.RequirePermission(middleware.PermPaymentPay), controllers.CreatePaymentCheckout)
+			protected.GET("/payments/gateway/review", middleware.RequirePermission(middleware.PermPaymentManage), controllers.GetGatewayReviews)
+			protected.GET("/payment/:id/invoice.pdf", middleware.RequirePermission(middleware.PermPaymentReadOwn), controllers.GetPaymentInvoicePDF)
+			protected.GET("/payment/:id/receipt.pdf", middleware.RequirePermission(middleware.PermPaymentReadOwn), controllers.GetPaymentReceiptPDF)
+			protected.GET("/payment/:id/transactions", middleware.RequirePermission(middleware.PermPaymentReadOwn), controllers.GetPaymentTransactions)
+			protected.POST("/payment/:id/transactions", middleware.RequirePermission(middleware.PermPaymentPay), controllers.AddPaymentTransaction)
+			protected.PUT("/payment/transactions/:id", middleware.RequirePermission(middleware.PermPaymentManage), controllers.VerifyPaymentTransaction)
+			protected.GET("/users/:id/statement", middleware.RequirePermission(middleware.PermPaymentReadOwn), controllers.GetAccountStatement)
+
+			// Rekonsiliasi mutasi bank
+			protected.POST("/reconciliation/import", middleware.RequirePermission(middleware.PermPaymentManage), controllers.ImportBankStatement)
+			protected.GET("/reconciliation/:id", middleware.RequirePermission(middleware.PermPaymentManage), controllers.GetBankStatementImport)
+			protected.POST("/reconciliation/:id/confirm", middleware.RequirePermission(middleware.PermPaymentManage), controllers.ConfirmBankStatementMatches)
+
+			// Billing
+			protected.POST("/billing/run", middleware.RequirePermission(middleware.PermBillingRun), controllers.RunBilling)
+			protected.POST("/billing/reminders/run", middleware.RequirePermission(middleware.PermBillingRun), controllers.RunReminders)
+
+			// Refund & nota kredit
+			protected.POST("/payment/:id/refunds", middleware.RequirePermission(middleware.PermRefundRequest), controllers.RequestRefund)
+			protected.GET("/refunds", middleware.RequirePermission(middleware.PermPaymentRead), controllers.GetRefunds)
+			protected.PUT("/refunds/:id/review", middleware.RequirePermission(middleware.PermRefundApprove), controllers.ReviewRefund)
+			protected.POST("/refunds/:id/payout", middleware.RequirePermission(middleware.PermRefundApprove), controllers.PayoutRefund)
+			protected.GET("/refunds/:id/credit-note.pdf", middleware.RequirePermission(middleware.PermPaymentReadOwn), controllers.GetCreditNotePDF)
+
+			// Laporan
+			protected.GET("/reports/revenue", middleware.RequirePermission(middleware.PermReportRead), controllers.GetRevenueReport)
+			protected.GET("/reports/summary", middleware.RequirePermission(middleware.PermReportRead), controllers.GetFinancialSummary)
+			protected.GET("/reports/breakdown", middleware.RequirePermission(middleware.PermReportRead), controllers.GetRevenueBreakdown)
+			protected.GET("/reports/debtors", middleware.RequirePermission(middleware.PermReportRead), controllers.GetTopDebtors)
+			protected.GET("/reports/aging", middleware.RequirePermission(middleware.PermReportRead), controllers.GetAgingReport)
+
+			// Pricing
+			protected.GET("/pricing/rules", middleware.RequirePermission(middleware.PermPricingManage), controllers.GetPricingRules)
+			protected.POST("/pricing/rules", middleware.RequirePermission(middleware.PermPricingManage), controllers.CreatePricingRule)
+			protected.DELETE("/pricing/rules/:id", middleware.RequirePermission(middleware.PermPricingManage), controllers.DeletePricingRule)
+			protected.GET("/pricing/scholarships", middleware.RequirePermission(middleware.PermPricingManage), controllers.GetScholarships)
+			protected.POST("/pricing/scholarships", middleware.RequirePermission(middleware.PermPricingManage), controllers.CreateScholarship)
+			protected.DELETE("/pricing/scholarships/:id", middleware.RequirePermission(middleware.PermPricingManage), controllers.DeleteScholarship)
+			protected.GET("/pricing/promos", middleware.RequirePermission(middleware.PermPricingManage), controllers.GetPromoCodes)
+			protected.POST("/pricing/promos", middleware.RequirePermission(middleware.PermPricingManage), controllers.CreatePromoCode)
+			protected.DELETE("/pricing/promos/:id", middleware.RequirePermission(middleware.PermPricingManage), controllers.DeletePromoCode)
+			protected.GET("/pricing/families", middleware.RequirePermission(middleware.PermPricingManage), controllers.GetFamilies)
+			protected.POST("/pricing/families", middleware.RequirePermission(middleware.PermPricingManage), controllers.CreateFamily)
+			protected.PUT("/pricing/families/:id/members", middleware.RequirePermission(middleware.PermPricingManage), controllers.SetFamilyMembers)
+			protected.POST("/pricing/quote", middleware.RequirePermission(middleware.PermPaymentCreate), controllers.QuotePaymentPrice)
 
 			// Trainings
-			protected.GET("/trainings", controllers.GetTrainings)
-			protected.POST("/training/create", controllers.CreateTraining)
-			protected.GET("/trainings/vendor", controllers.GetTrainingsByVendor)
+			protected.GET("/trainings", middleware.RequirePermission(middleware.PermTrainingRead), controllers.GetTrainings)
+			protected.POST("/training/create", middleware.RequirePermission(middleware.PermTrainingManage), controllers.CreateTraining)
+			protected.PUT("/training/:id/staff", middleware.RequirePermission(middleware.PermTrainingManage), controllers.SetTrainingStaff)
+			protected.GET("/trainings/vendor", middleware.RequirePermission(middleware.PermTrainingRead), controllers.GetTrainingsByVendor)
 
 			// Match
-			protected.GET("/matches", controllers.GetMatchs)
-			protected.POST("/match/create", controllers.CreateMatch)
-			protected.GET("/matches/vendor", controllers.GetMatchsByVendor)
+			protected.GET("/matches", middleware.RequirePermission(middleware.PermMatchRead), controllers.GetMatchs)
+			protected.POST("/match/create", middleware.RequirePermission(middleware.PermMatchManage), controllers.CreateMatch)
+			protected.PUT("/match/:id/staff", middleware.RequirePermission(middleware.PermMatchManage), controllers.SetMatchStaff)
+			protected.GET("/matches/vendor", middleware.RequirePermission(middleware.PermMatchRead), controllers.GetMatchsByVendor)
 
 			// Challenges
-			protected.GET("/challenges", controllers.GetChallenges)
-			protected.POST("/challenge/create", controllers.CreateChallenge)
-			protected.GET("/challenge-logs", controllers.GetChallengeLogs)
-			protected.POST("/challenge-log/create", controllers.CreateChallengeLog)
-			protected.GET("/challenges/vendor", controllers.GetChallengesByVendor)
+			protected.GET("/challenges", middleware.RequirePermission(middleware.PermChallengeRead), controllers.GetChallenges)
+			protected.POST("/challenge/create", middleware.RequirePermission(middleware.PermChallengeManage), controllers.CreateChallenge)
+			protected.GET("/challenge-logs", middleware.RequirePermission(middleware.PermChallengeRead), controllers.GetChallengeLogs)
+			protected.POST("/challenge-log/create", middleware.RequirePermission(middleware.PermChallengeManage), controllers.CreateChallengeLog)
+			protected.GET("/challenges/vendor", middleware.RequirePermission(middleware.PermChallengeRead), controllers.GetChallengesByVendor)
 
 			// Events
-			protected.GET("/events", controllers.GetEvents)
-			protected.PUT("/event/update/:id", controllers.UpdateEvent)
-			protected.POST("/event/create", controllers.CreateEvent)
-			protected.GET("/event-logs", controllers.GetEventLogs)
-			protected.POST("/event-log/create", controllers.CreateEventLog)
-			protected.GET("/event-logs/user", controllers.GetEventLogsByUser)
-			protected.PUT("/event-log/status", controllers.UpdateEventLogStatus)
-			protected.PUT("/events/finish", controllers.UpdateEventFinishStatus)
+			protected.GET("/events", middleware.RequirePermission(middleware.PermEventRead), controllers.GetEvents)
+			protected.PUT("/event/update/:id", middleware.RequirePermission(middleware.PermEventManage), controllers.UpdateEvent)
+			protected.POST("/event/create", middleware.RequirePermission(middleware.PermEventManage), controllers.CreateEvent)
+			protected.GET("/event-series", middleware.RequirePermission(middleware.PermEventRead), controllers.GetEventSeries)
+			protected.GET("/event-series/:id", middleware.RequirePermission(middleware.PermEventRead), controllers.GetEventSeriesByID)
+			protected.POST("/event-series/create", middleware.RequirePermission(middleware.PermEventManage), controllers.CreateEventSeries)
+			protected.POST("/event-series/:id/cancel-date", middleware.RequirePermission(middleware.PermEventManage), controllers.CancelSeriesDate)
+			protected.GET("/venues", middleware.RequirePermission(middleware.PermEventRead), controllers.GetVenues)
+			protected.POST("/venues", middleware.RequirePermission(middleware.PermVendorManage), controllers.CreateVenue)
+			protected.PUT("/venues/:id", middleware.RequirePermission(middleware.PermVendorManage), controllers.UpdateVenue)
+			protected.DELETE("/venues/:id", middleware.RequirePermission(middleware.PermVendorManage), controllers.DeleteVenue)
+			protected.POST("/venues/:id/pitches", middleware.RequirePermission(middleware.PermVendorManage), controllers.CreatePitch)
+			protected.DELETE("/pitches/:id", middleware.RequirePermission(middleware.PermVendorManage), controllers.DeletePitch)
+			protected.GET("/venues/:id/availability", middleware.RequirePermission(middleware.PermEventRead), controllers.GetVenueAvailability)
+			protected.POST("/event/:id/cancel", middleware.RequirePermission(middleware.PermEventManage), controllers.CancelEvent)
+			protected.GET("/event/:id/changes", middleware.RequirePermission(middleware.PermEventRead), controllers.GetEventChanges)
+			protected.PUT("/event/:id/staff", middleware.RequirePermission(middleware.PermEventManage), controllers.SetEventStaff)
+			protected.GET("/coaches/:id/agenda", middleware.RequirePermission(middleware.PermEventRead), controllers.GetCoachAgenda)
+			protected.PUT("/event/:id/rsvp", middleware.RequirePermission(middleware.PermEventJoin), controllers.RespondEventRSVP)
+			protected.GET("/event/:id/attendees", middleware.RequirePermission(middleware.PermEventRead), controllers.GetEventAttendees)
+			protected.GET("/event/:id/checkin-code", middleware.RequirePermission(middleware.PermAttendanceManage), controllers.GetEventCheckInCode)
+			protected.POST("/event/checkin", middleware.RequirePermission(middleware.PermEventJoin), controllers.CheckInEvent)
+			protected.POST("/event/:id/checkin/geo", middleware.RequirePermission(middleware.PermEventJoin), controllers.GeoCheckInEvent)
+			protected.POST("/event/:id/checkin/override", middleware.RequirePermission(middleware.PermAttendanceManage), controllers.OverrideCheckIn)
+			protected.GET("/event/:id/checkin-attempts", middleware.RequirePermission(middleware.PermAttendanceManage), controllers.GetCheckInAttempts)
+			protected.PUT("/checkin-attempts/:id/review", middleware.RequirePermission(middleware.PermAttendanceManage), controllers.ReviewCheckInAttempt)
+			protected.GET("/event-logs", middleware.RequirePermission(middleware.PermEventRead), controllers.GetEventLogs)
+			protected.GET("/event-logs/export", middleware.RequirePermission(middleware.PermAttendanceManage), controllers.ExportEventLogs)
+			protected.POST("/event-log/create", middleware.RequirePermission(middleware.PermEventJoin), controllers.CreateEventLog)
+			protected.GET("/event-logs/user", middleware.RequirePermission(middleware.PermEventRead), controllers.GetEventLogsByUser)
+			protected.PUT("/event-log/status", middleware.RequirePermission(middleware.PermAttendanceManage), controllers.UpdateEventLogStatus)
+			protected.PUT("/events/finish", middleware.RequirePermission(middleware.PermEventManage), controllers.UpdateEventFinishStatus)
+
+			// Feed kalender (.ics)
+			protected.GET("/calendar/feed", middleware.RequirePermission(middleware.PermEventRead), controllers.GetMyCalendarFeed)
+			protected.POST("/calendar/feed/rotate", middleware.RequirePermission(middleware.PermEventRead), controllers.RotateMyCalendarFeed)
+			protected.GET("/vendor/calendar/feed", middleware.RequirePermission(middleware.PermEventRead), controllers.GetVendorCalendarFeed)
+			protected.POST("/vendor/calendar/feed/rotate", middleware.RequirePermission(middleware.PermEventManage), controllers.RotateVendorCalendarFeed)
 
 		}
 
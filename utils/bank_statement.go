@@ -0,0 +1,325 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"ssb_api/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format mutasi rekening yang didukung.
+const (
+	StatementFormatBCA     = "bca"
+	StatementFormatMandiri = "mandiri"
+	StatementFormatGeneric = "generic"
+)
+
+var ErrStatementHeader = errors.New("statement header row not found")
+
+// StatementTransaction adalah satu baris mutasi kredit (uang masuk).
+type StatementTransaction struct {
+	Date        time.Time
+	Description string
+	Amount      float64
+}
+
+// StatementMapping menentukan kolom untuk format generic. Kolom diisi nama
+// header (tidak peka huruf besar), beberapa alternatif dipisah "|". Bila
+// CreditColumn diisi, AmountColumn diabaikan dan hanya baris dengan nilai
+// kredit yang dibaca.
+type StatementMapping struct {
+	DateColumn        string
+	DescriptionColumn string
+	AmountColumn      string
+	CreditColumn      string
+	DateLayout        string // layout Go, default mencoba beberapa format umum
+}
+
+// ParseBankStatement membaca CSV mutasi rekening dan mengembalikan transaksi
+// kredit saja. year dipakai untuk tanggal tanpa tahun (format BCA "01/10").
+func ParseBankStatement(r io.Reader, format string, mapping StatementMapping, year int) ([]StatementTransaction, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	raw = bytes.TrimPrefix(raw, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(raw))
+	reader.Comma = detectDelimiter(raw)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case StatementFormatBCA:
+		mapping = StatementMapping{DateColumn: "tanggal|date", DescriptionColumn: "keterangan|description", AmountColumn: "jumlah|amount"}
+	case StatementFormatMandiri:
+		mapping = StatementMapping{DateColumn: "date|tanggal", DescriptionColumn: "description|keterangan|remarks", CreditColumn: "credit|kredit"}
+	case StatementFormatGeneric:
+		if mapping.DateColumn == "" || (mapping.AmountColumn == "" && mapping.CreditColumn == "") {
+			return nil, errors.New("generic format requires date and amount or credit column")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported statement format %q", format)
+	}
+
+	header, cols, ok := findStatementHeader(rows, mapping)
+	if !ok {
+		return nil, ErrStatementHeader
+	}
+
+	var txs []StatementTransaction
+	for _, row := range rows[header+1:] {
+		dateText := cell(row, cols.date)
+		if dateText == "" {
+			continue
+		}
+		date, err := parseStatementDate(dateText, mapping.DateLayout, year)
+		if err != nil {
+			// Baris ringkasan saldo di akhir file tidak punya tanggal valid
+			continue
+		}
+
+		var amount float64
+		if cols.credit >= 0 {
+			amount, err = ParseStatementAmount(cell(row, cols.credit))
+		} else {
+			amount, err = statementAmountWithSign(row, cols.amount)
+		}
+		if err != nil || amount <= 0 {
+			continue
+		}
+
+		// Mandiri memecah keterangan menjadi beberapa kolom berlabel sama
+		var desc []string
+		for _, i := range cols.description {
+			if v := cell(row, i); v != "" {
+				desc = append(desc, v)
+			}
+		}
+
+		txs = append(txs, StatementTransaction{
+			Date:        date,
+			Description: strings.Join(desc, " "),
+			Amount:      amount,
+		})
+	}
+	return txs, nil
+}
+
+type statementColumns struct {
+	date        int
+	description []int
+	amount      int
+	credit      int
+}
+
+func findStatementHeader(rows [][]string, mapping StatementMapping) (int, statementColumns, bool) {
+	for i, row := range rows {
+		cols := statementColumns{date: -1, amount: -1, credit: -1}
+		for j, name := range row {
+			name = strings.ToLower(strings.TrimSpace(name))
+			switch {
+			case cols.date < 0 && matchesColumn(name, mapping.DateColumn):
+				cols.date = j
+			case mapping.DescriptionColumn != "" && matchesColumn(name, mapping.DescriptionColumn):
+				cols.description = append(cols.description, j)
+			case mapping.CreditColumn != "" && cols.credit < 0 && matchesColumn(name, mapping.CreditColumn):
+				cols.credit = j
+			case mapping.AmountColumn != "" && cols.amount < 0 && matchesColumn(name, mapping.AmountColumn):
+				cols.amount = j
+			}
+		}
+		if cols.date >= 0 && (cols.amount >= 0 || cols.credit >= 0) {
+			return i, cols, true
+		}
+	}
+	return 0, statementColumns{}, false
+}
+
+// matchesColumn mencocokkan header persis atau diawali nama kolom, misal
+// "tanggal transaksi" cocok dengan "tanggal".
+func matchesColumn(header, columns string) bool {
+	for _, column := range strings.Split(columns, "|") {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if column != "" && (header == column || strings.HasPrefix(header, column+" ")) {
+			return true
+		}
+	}
+	return false
+}
+
+// statementAmountWithSign membaca kolom jumlah BCA/generic. Debit ditandai
+// "DB" di kolom jumlah atau kolom sesudahnya, atau dengan angka negatif.
+func statementAmountWithSign(row []string, col int) (float64, error) {
+	text := strings.ToUpper(cell(row, col))
+	marker := strings.ToUpper(cell(row, col+1))
+	if strings.HasSuffix(text, "DB") || marker == "DB" || marker == "D" {
+		return 0, nil
+	}
+	return ParseStatementAmount(text)
+}
+
+// ParseStatementAmount membaca angka dengan pemisah ribuan Indonesia
+// (1.500.000,00) maupun internasional (1,500,000.00).
+func ParseStatementAmount(text string) (float64, error) {
+	text = strings.ToUpper(strings.TrimSpace(text))
+	text = strings.TrimSuffix(strings.TrimSuffix(text, "CR"), "DB")
+	text = strings.NewReplacer("RP", "", " ", "", "'", "").Replace(text)
+	if text == "" {
+		return 0, errors.New("empty amount")
+	}
+
+	lastComma, lastDot := strings.LastIndex(text, ","), strings.LastIndex(text, ".")
+	switch {
+	case lastComma >= 0 && lastDot >= 0:
+		if lastComma > lastDot {
+			text = strings.ReplaceAll(text, ".", "")
+			text = strings.Replace(text, ",", ".", 1)
+		} else {
+			text = strings.ReplaceAll(text, ",", "")
+		}
+	case lastComma >= 0:
+		text = normalizeSeparator(text, ",")
+	case lastDot >= 0:
+		text = normalizeSeparator(text, ".")
+	}
+	return strconv.ParseFloat(text, 64)
+}
+
+// normalizeSeparator menganggap pemisah tunggal dengan dua digit di
+// belakangnya sebagai desimal, selain itu sebagai pemisah ribuan.
+func normalizeSeparator(text, sep string) string {
+	idx := strings.LastIndex(text, sep)
+	if strings.Count(text, sep) == 1 && len(text)-idx-1 == 2 {
+		return strings.Replace(text, sep, ".", 1)
+	}
+	return strings.ReplaceAll(text, sep, "")
+}
+
+var statementDateLayouts = []string{"02/01/2006", "02/01/06", "2006-01-02", "02-01-2006", "02-01-06", "2/1/2006", "02 Jan 2006"}
+
+func parseStatementDate(text, layout string, year int) (time.Time, error) {
+	text = strings.TrimPrefix(strings.TrimSpace(text), "'")
+	if layout != "" {
+		return time.ParseInLocation(layout, text, time.Local)
+	}
+	for _, l := range statementDateLayouts {
+		if t, err := time.ParseInLocation(l, text, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	// Format BCA tanpa tahun, contoh "01/10"
+	if t, err := time.ParseInLocation("02/01", text, time.Local); err == nil {
+		return time.Date(year, t.Month(), t.Day(), 0, 0, 0, 0, time.Local), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", text)
+}
+
+func detectDelimiter(raw []byte) rune {
+	firstLines := raw
+	if len(firstLines) > 2048 {
+		firstLines = firstLines[:2048]
+	}
+	if bytes.Count(firstLines, []byte(";")) > bytes.Count(firstLines, []byte(",")) {
+		return ';'
+	}
+	return ','
+}
+
+func cell(row []string, i int) string {
+	if i < 0 || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+// PaymentUniqueCode adalah kode unik 1-999 yang boleh ditambahkan pemain ke
+// nominal transfer supaya mudah dicocokkan, misal Rp 150.000 menjadi 150.123.
+func PaymentUniqueCode(payment models.Payment) int {
+	return int(payment.ID%999) + 1
+}
+
+// Alasan kecocokan mutasi dengan tagihan.
+const (
+	MatchByInvoice    = "invoice"
+	MatchByUniqueCode = "unique_code"
+	MatchByAmountDate = "amount_date"
+)
+
+// StatementMatch adalah usulan pasangan mutasi dan tagihan.
+type StatementMatch struct {
+	PaymentID  *uint
+	Reason     string
+	Candidates int
+}
+
+var nonAlphaNum = regexp.MustCompile(`[^A-Z0-9]`)
+
+// MatchStatementTransaction mencari tagihan terbuka untuk satu mutasi dengan
+// urutan prioritas: nomor invoice di berita transfer, nominal dengan kode
+// unik, lalu nominal sama dalam rentang tanggal tagihan. Tagihan di used
+// tidak akan dipasangkan lagi.
+func MatchStatementTransaction(tx StatementTransaction, payments []models.Payment, used map[uint]bool, windowDays int) StatementMatch {
+	desc := nonAlphaNum.ReplaceAllString(strings.ToUpper(tx.Description), "")
+
+	var byCode, byAmount []uint
+	for _, p := range payments {
+		if used[p.ID] {
+			continue
+		}
+		if p.Invoice != "" && desc != "" {
+			invoice := nonAlphaNum.ReplaceAllString(strings.ToUpper(p.Invoice), "")
+			if invoice != "" && strings.Contains(desc, invoice) && amountEqual(tx.Amount, p.Outstanding(), float64(PaymentUniqueCode(p))) {
+				id := p.ID
+				return StatementMatch{PaymentID: &id, Reason: MatchByInvoice, Candidates: 1}
+			}
+		}
+		if amountEqual(tx.Amount, p.Outstanding()+float64(PaymentUniqueCode(p)), 0) {
+			byCode = append(byCode, p.ID)
+		}
+		if amountEqual(tx.Amount, p.Outstanding(), 0) && withinPaymentWindow(tx.Date, p, windowDays) {
+			byAmount = append(byAmount, p.ID)
+		}
+	}
+
+	switch {
+	case len(byCode) == 1:
+		return StatementMatch{PaymentID: &byCode[0], Reason: MatchByUniqueCode, Candidates: 1}
+	case len(byCode) == 0 && len(byAmount) == 1:
+		return StatementMatch{PaymentID: &byAmount[0], Reason: MatchByAmountDate, Candidates: 1}
+	}
+	return StatementMatch{Candidates: len(byCode) + len(byAmount)}
+}
+
+// amountEqual membandingkan nominal; tolerance mengizinkan selisih sebesar
+// kode unik saat invoice sudah cocok.
+func amountEqual(paid, expected, tolerance float64) bool {
+	diff := paid - expected
+	return math.Abs(diff) < 1 || (tolerance > 0 && diff >= 0 && diff <= tolerance+0.5)
+}
+
+// withinPaymentWindow memeriksa tanggal transfer berada di antara tanggal
+// tagihan dan jatuh tempo, dengan kelonggaran windowDays di kedua sisi.
+func withinPaymentWindow(date time.Time, payment models.Payment, windowDays int) bool {
+	start := payment.CreatedAt
+	if d, ok := payment.Date.Time(time.Local); ok {
+		start = d
+	}
+	end := start
+	if d, ok := payment.DueDate.Time(time.Local); ok && d.After(end) {
+		end = d
+	}
+	window := time.Duration(windowDays) * 24 * time.Hour
+	return !date.Before(start.Add(-window)) && !date.After(end.Add(window))
+}
@@ -0,0 +1,204 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"ssb_api/models"
+
+	"gorm.io/gorm"
+)
+
+func TestParseStatementAmount(t *testing.T) {
+	cases := []struct {
+		text string
+		want float64
+		err  bool
+	}{
+		{"1.500.000,00", 1500000, false},
+		{"1,500,000.00", 1500000, false},
+		{"150.00", 150, false},
+		{"150,50", 150.5, false},
+		{"150.000", 150000, false},
+		{"150,000", 150000, false},
+		{"1.500.000", 1500000, false},
+		{"1.234,5", 1234.5, false},
+		{"Rp 1.500.000,00", 1500000, false},
+		{"250,000.00 CR", 250000, false},
+		{"'75.000", 75000, false},
+		{"-150.000", -150000, false},
+		{"", 0, true},
+		{"Rp", 0, true},
+		{"abc", 0, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.text, func(t *testing.T) {
+			got, err := ParseStatementAmount(tc.text)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseBankStatement(t *testing.T) {
+	bca := strings.Join([]string{
+		"Informasi Rekening - Mutasi Rekening",
+		"No. rekening : ,1234567890",
+		"Tanggal,Keterangan,Cabang,Jumlah,,Saldo",
+		`'01/10,TRSF E-BANKING CR INV-2025-001 BUDI,0000,"150,000.00",CR,"1,150,000.00"`,
+		`'02/10,BIAYA ADM,0000,"10,000.00",DB,"1,140,000.00"`,
+		`'03/10,SETORAN TUNAI,0000,"250,123.00",CR,"1,390,123.00"`,
+		`Saldo Awal,,,"1,000,000.00"`,
+	}, "\n")
+	mandiri := "\xef\xbb\xbf" + strings.Join([]string{
+		"Account No;Date;Val. Date;Transaction Code;Description;Description;Reference No.;Debit;Credit;",
+		"1234;01/10/2025;01/10/2025;1234;TRANSFER DARI;BUDI INV-1;REF;0,00;150.000,00;",
+		"1234;02/10/2025;02/10/2025;1234;BIAYA;ADM;REF;10.000,00;0,00;",
+	}, "\n")
+	generic := strings.Join([]string{
+		"Posted,Memo,Amount",
+		"2025-10-05,Transfer Siti,\"200,000.00\"",
+		"2025-10-06,Refund,-50.000",
+	}, "\n")
+
+	cases := []struct {
+		name    string
+		input   string
+		format  string
+		mapping StatementMapping
+		want    []StatementTransaction
+	}{
+		{
+			name: "bca skips debit and summary rows", input: bca, format: StatementFormatBCA,
+			want: []StatementTransaction{
+				{Date: time.Date(2025, 10, 1, 0, 0, 0, 0, time.Local), Description: "TRSF E-BANKING CR INV-2025-001 BUDI", Amount: 150000},
+				{Date: time.Date(2025, 10, 3, 0, 0, 0, 0, time.Local), Description: "SETORAN TUNAI", Amount: 250123},
+			},
+		},
+		{
+			name: "mandiri joins split descriptions", input: mandiri, format: StatementFormatMandiri,
+			want: []StatementTransaction{
+				{Date: time.Date(2025, 10, 1, 0, 0, 0, 0, time.Local), Description: "TRANSFER DARI BUDI INV-1", Amount: 150000},
+			},
+		},
+		{
+			name: "generic with mapping", input: generic, format: StatementFormatGeneric,
+			mapping: StatementMapping{DateColumn: "posted", DescriptionColumn: "memo", AmountColumn: "amount"},
+			want: []StatementTransaction{
+				{Date: time.Date(2025, 10, 5, 0, 0, 0, 0, time.Local), Description: "Transfer Siti", Amount: 200000},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseBankStatement(strings.NewReader(tc.input), tc.format, tc.mapping, 2025)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %d transactions %+v, want %d", len(got), got, len(tc.want))
+			}
+			for i := range got {
+				if !got[i].Date.Equal(tc.want[i].Date) || got[i].Description != tc.want[i].Description || got[i].Amount != tc.want[i].Amount {
+					t.Errorf("row %d: got %+v, want %+v", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseBankStatementErrors(t *testing.T) {
+	if _, err := ParseBankStatement(strings.NewReader("a,b\n1,2"), StatementFormatBCA, StatementMapping{}, 2025); !errors.Is(err, ErrStatementHeader) {
+		t.Errorf("missing header: expected ErrStatementHeader, got %v", err)
+	}
+	if _, err := ParseBankStatement(strings.NewReader("Date,Amount"), StatementFormatGeneric, StatementMapping{DateColumn: "date"}, 2025); err == nil {
+		t.Error("generic without amount column: expected error")
+	}
+	if _, err := ParseBankStatement(strings.NewReader("Date,Amount"), "bni", StatementMapping{}, 2025); err == nil {
+		t.Error("unsupported format: expected error")
+	}
+}
+
+func statementPayment(id uint, invoice string, amount float64) models.Payment {
+	return models.Payment{
+		Model:   gorm.Model{ID: id},
+		Invoice: invoice,
+		Amount:  amount,
+		Date:    "2025-10-01",
+		DueDate: "2025-10-10",
+	}
+}
+
+func TestMatchStatementTransaction(t *testing.T) {
+	// Kode unik = ID%999 + 1, jadi 2, 3 dan 4
+	payments := []models.Payment{
+		statementPayment(1, "INV/2025/001", 150000),
+		statementPayment(2, "INV/2025/002", 200000),
+		statementPayment(3, "INV/2025/003", 200000),
+	}
+	inWindow := time.Date(2025, 10, 5, 0, 0, 0, 0, time.Local)
+
+	cases := []struct {
+		name       string
+		tx         StatementTransaction
+		used       map[uint]bool
+		wantID     uint
+		wantReason string
+		candidates int
+	}{
+		{"invoice in description", StatementTransaction{Date: inWindow, Description: "TRSF INV 2025 001 BUDI", Amount: 150000}, nil, 1, MatchByInvoice, 1},
+		{"invoice with unique code", StatementTransaction{Date: inWindow, Description: "inv/2025/001", Amount: 150002}, nil, 1, MatchByInvoice, 1},
+		{"invoice but wrong amount", StatementTransaction{Date: inWindow, Description: "INV/2025/001", Amount: 50000}, nil, 0, "", 0},
+		{"unique code", StatementTransaction{Date: inWindow, Description: "TRANSFER", Amount: 200003}, nil, 2, MatchByUniqueCode, 1},
+		{"same amount is ambiguous", StatementTransaction{Date: inWindow, Description: "TRANSFER", Amount: 200000}, nil, 0, "", 2},
+		{"used payments are skipped", StatementTransaction{Date: inWindow, Description: "TRANSFER", Amount: 200000}, map[uint]bool{2: true}, 3, MatchByAmountDate, 1},
+		{"outside payment window", StatementTransaction{Date: time.Date(2025, 12, 1, 0, 0, 0, 0, time.Local), Description: "TRANSFER", Amount: 150000}, nil, 0, "", 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			used := tc.used
+			if used == nil {
+				used = map[uint]bool{}
+			}
+			got := MatchStatementTransaction(tc.tx, payments, used, 7)
+			if tc.wantID == 0 {
+				if got.PaymentID != nil {
+					t.Fatalf("expected no match, got payment %d (%s)", *got.PaymentID, got.Reason)
+				}
+			} else if got.PaymentID == nil || *got.PaymentID != tc.wantID || got.Reason != tc.wantReason {
+				t.Fatalf("got %+v, want payment %d by %s", got, tc.wantID, tc.wantReason)
+			}
+			if got.Candidates != tc.candidates {
+				t.Fatalf("candidates = %d, want %d", got.Candidates, tc.candidates)
+			}
+		})
+	}
+}
+
+func TestBankTransactionFingerprint(t *testing.T) {
+	base := models.BankTransactionFingerprint("2025-10-01", 150000, "TRSF E-BANKING  budi")
+	if got := models.BankTransactionFingerprint("2025-10-01", 150000, " trsf e-banking BUDI "); got != base {
+		t.Error("fingerprint should ignore case and spacing")
+	}
+	for _, other := range []string{
+		models.BankTransactionFingerprint("2025-10-02", 150000, "TRSF E-BANKING BUDI"),
+		models.BankTransactionFingerprint("2025-10-01", 150001, "TRSF E-BANKING BUDI"),
+		models.BankTransactionFingerprint("2025-10-01", 150000, "TRSF E-BANKING SITI"),
+	} {
+		if other == base {
+			t.Error("different transactions must not share a fingerprint")
+		}
+	}
+}
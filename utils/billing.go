@@ -0,0 +1,354 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"ssb_api/config"
+	"ssb_api/models"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const PaymentTypeMembership = "membership"
+
+// BillingItem adalah satu tagihan yang dibuat (atau akan dibuat saat dry run).
+type BillingItem struct {
+	UserID   uint    `json:"user_id"`
+	UserName string  `json:"user_name"`
+	VendorID uint    `json:"vendor_id"`
+	EventID  *uint   `json:"event_id"`
+	Type     string  `json:"type"`
+	Amount   float64 `json:"amount"`
+	Prorated bool    `json:"prorated"`
+	Invoice  string  `json:"invoice,omitempty"`
+}
+
+type BillingResult struct {
+	Period  string        `json:"period"`
+	DryRun  bool          `json:"dry_run"`
+	Items   []BillingItem `json:"items"`
+	Skipped int           `json:"skipped"` // sudah punya tagihan di periode ini
+	Failed  int           `json:"failed"`
+}
+
+// billingMu mencegah scheduler dan endpoint admin berjalan bersamaan.
+var billingMu sync.Mutex
+
+// RunMonthlyBilling membuat satu tagihan per pemain per periode untuk iuran
+// akademi (Vendor.MembershipFee) dan setiap event berbayar bulanan. Pemain
+// yang bergabung di tengah bulan ditagih secara prorata. vendorID nil berarti
+// semua vendor.
+func RunMonthlyBilling(db *gorm.DB, period time.Time, vendorID *uint, dryRun bool) (BillingResult, error) {
+	billingMu.Lock()
+	defer billingMu.Unlock()
+
+	start := time.Date(period.Year(), period.Month(), 1, 0, 0, 0, 0, period.Location())
+	end := start.AddDate(0, 1, 0)
+	result := BillingResult{Period: start.Format("2006-01"), DryRun: dryRun, Items: []BillingItem{}}
+
+	// Iuran keanggotaan akademi
+	var vendors []models.Vendor
+	vendorQuery := db.Where("membership_fee > 0")
+	if vendorID != nil {
+		vendorQuery = vendorQuery.Where("id = ?", *vendorID)
+	}
+	if err := vendorQuery.Find(&vendors).Error; err != nil {
+		return result, err
+	}
+	for _, vendor := range vendors {
+		var players []models.User
+		if err := db.Where("vendor_id = ? AND role = ? AND created_at < ?", vendor.ID, models.RolePemain, end).
+			Where(billableAccount, true).Find(&players).Error; err != nil {
+			return result, err
+		}
+		joined, err := invitationAcceptedAt(db, vendor.ID)
+		if err != nil {
+			return result, err
+		}
+		for _, player := range players {
+			// Pemain hasil import bergabung saat menerima undangan, bukan
+			// saat baris user dibuat
+			joinedAt := player.CreatedAt
+			if accepted, ok := joined[player.ID]; ok {
+				joinedAt = accepted
+			}
+			if !joinedAt.Before(end) {
+				continue
+			}
+			charge := billingCharge{
+				vendorID: vendor.ID,
+				kind:     PaymentTypeMembership,
+				fee:      vendor.MembershipFee,
+				joinedAt: joinedAt,
+				note:     fmt.Sprintf("Iuran bulanan %s periode %s", vendor.Name, result.Period),
+			}
+			billOne(db, &result, player, charge, start, end)
+		}
+	}
+
+	// Event berbayar bulanan di luar series
+	var events []models.Event
+	eventQuery := db.Where("is_paid = ? AND payment_type = ? AND is_finish = ? AND is_cancelled = ? AND series_id IS NULL",
+		true, "monthly", false, false)
+	if vendorID != nil {
+		eventQuery = eventQuery.Where("vendor_id = ?", *vendorID)
+	}
+	if err := eventQuery.Find(&events).Error; err != nil {
+		return result, err
+	}
+	for _, event := range events {
+		if err := billEventPlayers(db, &result, []models.Event{event}, start, end); err != nil {
+			return result, err
+		}
+	}
+
+	// Series bulanan ditagih sekali per series, bukan per pertemuan. Pertemuan
+	// yang jatuh di periode ini dikelompokkan per series_id.
+	var occurrences []models.Event
+	seriesQuery := db.Where("is_paid = ? AND payment_type = ? AND is_cancelled = ? AND series_id IS NOT NULL AND occurrence_date >= ? AND occurrence_date < ?",
+		true, "monthly", false, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if vendorID != nil {
+		seriesQuery = seriesQuery.Where("vendor_id = ?", *vendorID)
+	}
+	if err := seriesQuery.Order("series_id, occurrence_date").Find(&occurrences).Error; err != nil {
+		return result, err
+	}
+	for i := 0; i < len(occurrences); {
+		j := i + 1
+		for j < len(occurrences) && *occurrences[j].SeriesID == *occurrences[i].SeriesID {
+			j++
+		}
+		if err := billEventPlayers(db, &result, occurrences[i:j], start, end); err != nil {
+			return result, err
+		}
+		i = j
+	}
+
+	return result, nil
+}
+
+// billableAccount memilih pemain yang sudah punya akun: mendaftar sendiri
+// (punya password) atau sudah menerima undangan (active). Pemain hasil import
+// yang belum menerima undangan belum ditagih.
+const billableAccount = "(active = ? OR password <> '')"
+
+// invitationAcceptedAt adalah waktu pemain vendor menerima undangan.
+func invitationAcceptedAt(db *gorm.DB, vendorID uint) (map[uint]time.Time, error) {
+	var invitations []models.UserInvitation
+	if err := db.Where("vendor_id = ? AND accepted_at IS NOT NULL", vendorID).
+		Find(&invitations).Error; err != nil {
+		return nil, err
+	}
+	accepted := map[uint]time.Time{}
+	for _, inv := range invitations {
+		accepted[inv.UserID] = *inv.AcceptedAt
+	}
+	return accepted, nil
+}
+
+// billEventPlayers menagih pemain terdaftar di events. events berisi satu
+// event biasa atau semua pertemuan satu series di periode ini; tagihan dicatat
+// pada event pertama dan pemain yang terdaftar di beberapa pertemuan hanya
+// ditagih sekali.
+func billEventPlayers(db *gorm.DB, result *BillingResult, events []models.Event, start, end time.Time) error {
+	first := events[0]
+	eventIDs := make([]uint, len(events))
+	for i, e := range events {
+		eventIDs[i] = e.ID
+	}
+
+	var logs []models.EventLog
+	if err := db.Where("event_id IN ? AND status = ? AND created_at < ?", eventIDs, true, end).
+		Order("created_at").Find(&logs).Error; err != nil {
+		return err
+	}
+	billed := map[uint]bool{}
+	for _, eventLog := range logs {
+		if billed[eventLog.UserID] {
+			continue
+		}
+		billed[eventLog.UserID] = true
+
+		var player models.User
+		if err := db.Where(billableAccount, true).First(&player, eventLog.UserID).Error; err != nil {
+			continue
+		}
+		eventID := first.ID
+		charge := billingCharge{
+			vendorID: first.VendorID,
+			eventID:  &eventID,
+			eventIDs: eventIDs,
+			kind:     "event",
+			fee:      first.Fee,
+			joinedAt: eventLog.CreatedAt,
+			note:     fmt.Sprintf("Tagihan bulanan %s periode %s", first.Title, result.Period),
+		}
+		billOne(db, result, player, charge, start, end)
+	}
+	return nil
+}
+
+type billingCharge struct {
+	vendorID uint
+	eventID  *uint
+	eventIDs []uint // semua pertemuan yang dianggap tagihan yang sama
+	kind     string
+	fee      float64
+	joinedAt time.Time
+	note     string
+}
+
+func billOne(db *gorm.DB, result *BillingResult, player models.User, charge billingCharge, start, end time.Time) {
+	// Lewati pemain yang sudah punya tagihan untuk periode ini
+	existing := db.Model(&models.Payment{}).
+		Where("user_id = ? AND vendor_id = ? AND type = ? AND billing_period = ?", player.ID, charge.vendorID, charge.kind, result.Period)
+	if len(charge.eventIDs) > 0 {
+		existing = existing.Where("event_id IN ?", charge.eventIDs)
+	} else if charge.eventID != nil {
+		existing = existing.Where("event_id = ?", *charge.eventID)
+	} else {
+		existing = existing.Where("event_id IS NULL")
+	}
+	var count int64
+	if err := existing.Count(&count).Error; err != nil {
+		result.Failed++
+		return
+	}
+	if count > 0 {
+		result.Skipped++
+		return
+	}
+
+	amount, prorated := prorate(charge.fee, charge.joinedAt, start, end)
+	item := BillingItem{
+		UserID:   player.ID,
+		UserName: player.Name,
+		VendorID: charge.vendorID,
+		EventID:  charge.eventID,
+		Type:     charge.kind,
+		Amount:   amount,
+		Prorated: prorated,
+	}
+	if result.DryRun {
+		quote, err := QuotePrice(db, PriceInput{
+			VendorID:    charge.vendorID,
+			UserID:      player.ID,
+			EventID:     charge.eventID,
+			PaymentType: charge.kind,
+			Fee:         amount,
+			Date:        time.Now(),
+		})
+		if err != nil {
+			result.Failed++
+			return
+		}
+		item.Amount = quote.Net
+		result.Items = append(result.Items, item)
+		return
+	}
+
+	vendorID := charge.vendorID
+	payment := models.Payment{
+		UserID:        player.ID,
+		UserName:      player.Name,
+		VendorID:      &vendorID,
+		EventID:       charge.eventID,
+		Amount:        amount,
+		Method:        "transfer",
+		Status:        models.PaymentStatusIssued,
+		Type:          charge.kind,
+		Date:          models.DateOf(time.Now()),
+		DueDate:       models.DateOf(billingDueDate(start)),
+		Note:          charge.note,
+		BillingPeriod: result.Period,
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return CreatePaymentRecord(tx, &payment, nil, "Monthly billing "+result.Period)
+	})
+	if err != nil {
+		// Unique index billing period menolak duplikat dari proses lain
+		log.Println("Billing gagal untuk user", player.ID, ":", err)
+		result.Failed++
+		return
+	}
+
+	item.Invoice = payment.Invoice
+	item.Amount = payment.Amount
+	result.Items = append(result.Items, item)
+
+	if player.FCMToken != "" {
+		body := fmt.Sprintf("Hai %s, tagihan bulanan periode %s sudah terbit.", player.Name, result.Period)
+		go CreateNotification(player.ID, player.FCMToken, "Tagihan Baru", body, "payment")
+	}
+}
+
+// prorate menghitung tagihan sesuai sisa hari di periode untuk pemain yang
+// bergabung setelah tanggal 1.
+func prorate(fee float64, joinedAt, start, end time.Time) (float64, bool) {
+	if !joinedAt.After(start) {
+		return fee, false
+	}
+	totalDays := int(end.Sub(start).Hours() / 24)
+	joinedDay := time.Date(joinedAt.Year(), joinedAt.Month(), joinedAt.Day(), 0, 0, 0, 0, start.Location())
+	remaining := int(end.Sub(joinedDay).Hours() / 24)
+	if remaining >= totalDays {
+		return fee, false
+	}
+	return math.Round(fee * float64(remaining) / float64(totalDays)), true
+}
+
+// billingDueDate adalah tanggal jatuh tempo tagihan bulanan (BILLING_DUE_DAY,
+// default tanggal 10). Tagihan yang terbit setelahnya jatuh tempo 7 hari lagi.
+func billingDueDate(start time.Time) time.Time {
+	dueDay := envInt("BILLING_DUE_DAY", 10)
+	due := start.AddDate(0, 0, dueDay-1)
+	if time.Now().After(due) {
+		return time.Now().AddDate(0, 0, 7)
+	}
+	return due
+}
+
+// StartBillingScheduler menjalankan RunMonthlyBilling di background setiap
+// BILLING_INTERVAL (default 6h) mulai tanggal BILLING_DAY (default 1).
+// Set BILLING_ENABLED=false untuk mematikan.
+func StartBillingScheduler() {
+	if os.Getenv("BILLING_ENABLED") == "false" {
+		return
+	}
+
+	interval, err := time.ParseDuration(os.Getenv("BILLING_INTERVAL"))
+	if err != nil || interval <= 0 {
+		interval = 6 * time.Hour
+	}
+	billingDay := envInt("BILLING_DAY", 1)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			now := time.Now()
+			if now.Day() >= billingDay {
+				db := config.DB.WithContext(config.SystemContext())
+				result, err := RunMonthlyBilling(db, now, nil, false)
+				if err != nil {
+					log.Println("❌ Monthly billing failed:", err)
+				} else if len(result.Items) > 0 || result.Failed > 0 {
+					log.Printf("✅ Monthly billing %s: %d created, %d skipped, %d failed\n", result.Period, len(result.Items), result.Skipped, result.Failed)
+				}
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+func envInt(key string, fallback int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v > 0 {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"ssb_api/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FeedPastDays adalah jangkauan event lampau yang masih ikut di feed.
+const FeedPastDays = 90
+
+// UserCalendarFeed mengambil token feed user, dibuat bila belum ada.
+func UserCalendarFeed(db *gorm.DB, userID uint) (models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	err := db.Where("user_id = ?", userID).First(&feed).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		feed = models.CalendarFeed{UserID: &userID}
+		err = RotateCalendarFeed(db, &feed)
+	}
+	return feed, err
+}
+
+// VendorCalendarFeed mengambil token feed vendor, dibuat bila belum ada.
+func VendorCalendarFeed(db *gorm.DB, vendorID uint) (models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	err := db.Where("owner_vendor_id = ?", vendorID).First(&feed).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		feed = models.CalendarFeed{OwnerVendorID: &vendorID}
+		err = RotateCalendarFeed(db, &feed)
+	}
+	return feed, err
+}
+
+// RotateCalendarFeed mengganti token feed, URL lama langsung tidak berlaku.
+func RotateCalendarFeed(db *gorm.DB, feed *models.CalendarFeed) error {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	feed.Token = hex.EncodeToString(buf)
+	return db.Save(feed).Error
+}
+
+// BuildUserCalendar berisi event yang didaftari user, kecuali yang dijawab
+// not_going. Pemain di antrean atau menjawab maybe ditandai TENTATIVE.
+// Pertemuan series ditulis satu per satu karena pendaftarannya per tanggal.
+func BuildUserCalendar(db *gorm.DB, user models.User, now time.Time) (*Calendar, error) {
+	cal := NewCalendar("Jadwal " + user.Name)
+
+	var logs []models.EventLog
+	if err := db.Where("user_id = ? AND rsvp <> ?", user.ID, models.RSVPNotGoing).Find(&logs).Error; err != nil {
+		return cal, err
+	}
+	if len(logs) == 0 {
+		return cal, nil
+	}
+	byEvent := map[uint]models.EventLog{}
+	var eventIDs []uint
+	for _, log := range logs {
+		byEvent[log.EventID] = log
+		eventIDs = append(eventIDs, log.EventID)
+	}
+
+	var events []models.Event
+	if err := db.Where("id IN ? AND start_at >= ?", eventIDs, now.AddDate(0, 0, -FeedPastDays)).
+		Order("start_at ASC").Find(&events).Error; err != nil {
+		return cal, err
+	}
+	zones := map[uint]*time.Location{}
+	for _, e := range events {
+		loc, ok := zones[e.VendorID]
+		if !ok {
+			loc = VendorLocation(db, e.VendorID)
+			zones[e.VendorID] = loc
+		}
+		status := ICalConfirmed
+		if log := byEvent[e.ID]; log.Waitlisted || log.RSVP == models.RSVPMaybe {
+			status = ICalTentative
+		}
+		cal.AddEvent(e, loc, status)
+	}
+	return cal, nil
+}
+
+// BuildVendorCalendar berisi semua event publik vendor. Series ditulis
+// sebagai event berulang beserta pembatalan dan perubahannya.
+func BuildVendorCalendar(db *gorm.DB, vendor models.Vendor, now time.Time) (*Calendar, error) {
+	cal := NewCalendar(vendor.Name)
+	loc := vendor.Location()
+	since := now.AddDate(0, 0, -FeedPastDays)
+
+	var series []models.EventSeries
+	if err := db.Preload("Exceptions").
+		Where("vendor_id = ? AND is_private = ? AND (until = '' OR until >= ?)", vendor.ID, false, since.Format(models.DateLayout)).
+		Find(&series).Error; err != nil {
+		return cal, err
+	}
+	if len(series) > 0 {
+		var seriesIDs []uint
+		for _, s := range series {
+			seriesIDs = append(seriesIDs, s.ID)
+		}
+		var occurrences []models.Event
+		if err := db.Unscoped().Where("series_id IN ?", seriesIDs).Find(&occurrences).Error; err != nil {
+			return cal, err
+		}
+		bySeries := map[uint][]models.Event{}
+		for _, o := range occurrences {
+			bySeries[*o.SeriesID] = append(bySeries[*o.SeriesID], o)
+		}
+		for _, s := range series {
+			if err := cal.AddSeries(s, bySeries[s.ID], loc); err != nil {
+				continue // aturan lama yang tidak valid dilewati
+			}
+		}
+	}
+
+	var events []models.Event
+	if err := db.Where("vendor_id = ? AND series_id IS NULL AND is_private = ? AND start_at >= ?", vendor.ID, false, since).
+		Order("start_at ASC").Find(&events).Error; err != nil {
+		return cal, err
+	}
+	for _, e := range events {
+		cal.AddEvent(e, loc, "")
+	}
+	return cal, nil
+}
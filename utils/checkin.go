@@ -0,0 +1,243 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"math"
+	"os"
+	"ssb_api/models"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// CheckInCodeTTL adalah umur kode QR; aplikasi pelatih mengambil kode
+	// baru setiap periode ini.
+	CheckInCodeTTL = 30 * time.Second
+	// checkInGrace memberi waktu kode yang baru saja diganti tetap diterima
+	// selama pemain memindai.
+	checkInGrace = 30 * time.Second
+
+	// Check-in dibuka sebelum event mulai dan ditutup setelah event selesai.
+	CheckInOpensBefore = 60 * time.Minute
+	CheckInClosesAfter = 30 * time.Minute
+
+	checkInTokenType = "event_checkin"
+)
+
+var (
+	ErrInvalidCheckInCode = errors.New("invalid or expired check-in code")
+	ErrCheckInNotOpen     = errors.New("check-in is not open yet for this event")
+	ErrCheckInClosed      = errors.New("check-in is closed for this event")
+	ErrEventUnscheduled   = errors.New("event has no schedule")
+	ErrNoLocationPoint    = errors.New("event has no location point")
+	ErrInvalidCoordinates = errors.New("latitude must be between -90 and 90 and longitude between -180 and 180")
+	ErrCheckInFull        = errors.New("event is full, ask the coach to check you in")
+)
+
+// DefaultCheckInRadius dipakai bila event tidak mengatur radius (meter).
+const DefaultCheckInRadius = 150
+
+// CheckInRecord adalah data check-in yang disimpan di EventLog.
+type CheckInRecord struct {
+	Method    string
+	Latitude  *float64
+	Longitude *float64
+	Accuracy  *float64
+	Distance  *float64
+	By        *uint // pelatih, untuk check-in manual
+	Note      string
+	// AnyTime melewati rentang waktu check-in, khusus koreksi pelatih
+	AnyTime bool
+}
+
+// CheckInWindow adalah rentang waktu check-in event.
+func CheckInWindow(event models.Event) (time.Time, time.Time, error) {
+	if event.StartAt == nil {
+		return time.Time{}, time.Time{}, ErrEventUnscheduled
+	}
+	end := event.StartAt.Add(models.DefaultEventDuration)
+	if event.EndAt != nil {
+		end = *event.EndAt
+	}
+	return event.StartAt.Add(-CheckInOpensBefore), end.Add(CheckInClosesAfter), nil
+}
+
+// CheckCheckInOpen menolak check-in untuk event yang selesai, dibatalkan
+// atau di luar rentang waktu check-in.
+func CheckCheckInOpen(event models.Event, now time.Time) error {
+	if event.IsFinish {
+		return ErrEventFinished
+	}
+	if event.IsCancelled {
+		return ErrEventCancelled
+	}
+	open, close, err := CheckInWindow(event)
+	if err != nil {
+		return err
+	}
+	if now.Before(open) {
+		return ErrCheckInNotOpen
+	}
+	if now.After(close) {
+		return ErrCheckInClosed
+	}
+	return nil
+}
+
+// NewCheckInCode membuat isi QR check-in yang ditandatangani server dan
+// berlaku CheckInCodeTTL.
+func NewCheckInCode(event models.Event, now time.Time) (string, time.Time, error) {
+	expiresAt := now.Add(CheckInCodeTTL)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"typ":       checkInTokenType,
+		"event_id":  event.ID,
+		"vendor_id": event.VendorID,
+		"iat":       now.Unix(),
+		"exp":       expiresAt.Add(checkInGrace).Unix(),
+	})
+	code, err := token.SignedString(checkInKey())
+	return code, expiresAt, err
+}
+
+// VerifyCheckInCode memeriksa tanda tangan dan umur kode, lalu
+// mengembalikan ID event di dalamnya.
+func VerifyCheckInCode(code string, now time.Time) (uint, error) {
+	token, err := jwt.Parse(code, func(token *jwt.Token) (interface{}, error) {
+		return checkInKey(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(func() time.Time { return now }))
+	if err != nil || !token.Valid {
+		return 0, ErrInvalidCheckInCode
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] != checkInTokenType {
+		return 0, ErrInvalidCheckInCode
+	}
+	eventID, ok := claims["event_id"].(float64)
+	if !ok || eventID <= 0 {
+		return 0, ErrInvalidCheckInCode
+	}
+	return uint(eventID), nil
+}
+
+// CheckIn mencatat kehadiran user di event. Pendaftaran dibuat bila belum
+// ada; pemain yang hadir dianggap going walau sebelumnya maybe atau masih
+// di antrean, asal kuota masih ada. Pelatih (By atau AnyTime diisi) boleh
+// melewati kuota. Counter kehadiran ikut bertambah bila status berubah. Nilai
+// bool bernilai true bila user sudah check-in sebelumnya.
+func CheckIn(db *gorm.DB, eventID uint, user models.User, record CheckInRecord, now time.Time) (models.EventLog, bool, error) {
+	var log models.EventLog
+	already := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var event models.Event
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&event, eventID).Error; err != nil {
+			return err
+		}
+		if record.AnyTime {
+			if event.IsFinish {
+				return ErrEventFinished
+			}
+		} else if err := CheckCheckInOpen(event, now); err != nil {
+			return err
+		}
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND event_id = ?", user.ID, event.ID).First(&log).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			log = models.EventLog{
+				UserID:    user.ID,
+				EventID:   event.ID,
+				VendorID:  event.VendorID,
+				UserName:  user.Name,
+				EventType: event.EventType,
+			}
+		case err != nil:
+			return err
+		case log.CheckedInAt != nil:
+			already = true
+			return nil
+		}
+
+		// Yang belum memegang kursi hanya boleh masuk bila kuota masih ada
+		override := record.AnyTime || record.By != nil
+		holdsSeat := log.ID != 0 && log.RSVP == models.RSVPGoing && !log.Waitlisted
+		if !override && !holdsSeat && event.Capacity > 0 {
+			taken, err := countGoing(tx, event.ID, log.ID)
+			if err != nil {
+				return err
+			}
+			if taken >= int64(event.Capacity) {
+				return ErrCheckInFull
+			}
+		}
+
+		counted := log.Status
+		log.RSVP = models.RSVPGoing
+		log.Status = true
+		log.Waitlisted = false
+		log.WaitlistedAt = nil
+		log.CheckedInAt = &now
+		log.CheckInMethod = record.Method
+		log.CheckInLatitude = record.Latitude
+		log.CheckInLongitude = record.Longitude
+		log.CheckInAccuracy = record.Accuracy
+		log.CheckInDistance = record.Distance
+		log.CheckInBy = record.By
+		if record.Note != "" {
+			log.Note = record.Note
+		}
+		if err := tx.Save(&log).Error; err != nil {
+			return err
+		}
+		if !counted {
+			return AdjustAttendanceCounter(tx, log.UserID, log.EventType, 1)
+		}
+		return nil
+	})
+	return log, already, err
+}
+
+// checkInKey diturunkan dari JWT_SECRET supaya kode QR tidak bisa dipakai
+// sebagai token login, begitu juga sebaliknya.
+func checkInKey() []byte {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("JWT_SECRET")))
+	mac.Write([]byte(checkInTokenType))
+	return mac.Sum(nil)
+}
+
+// CheckInRadius adalah radius check-in event dalam meter.
+func CheckInRadius(event models.Event) int {
+	if event.CheckInRadius > 0 {
+		return event.CheckInRadius
+	}
+	return DefaultCheckInRadius
+}
+
+// DistanceToEvent menghitung jarak (meter) koordinat ke titik lokasi event.
+func DistanceToEvent(event models.Event, lat, lng float64) (float64, error) {
+	if lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		return 0, ErrInvalidCoordinates
+	}
+	eventLat, eventLng, ok := ParseLocationPoint(event.LocationPoint)
+	if !ok {
+		return 0, ErrNoLocationPoint
+	}
+	return HaversineDistance(eventLat, eventLng, lat, lng), nil
+}
+
+// HaversineDistance menghitung jarak dua koordinat dalam meter.
+func HaversineDistance(lat1, lng1, lat2, lng2 float64) float64 {
+	const earthRadius = 6371000.0
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLng := (lng2 - lng1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
@@ -0,0 +1,247 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"ssb_api/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Tindakan atas tagihan event yang dibatalkan.
+const (
+	CancelPaymentsKeep   = ""       // tagihan dibiarkan
+	CancelPaymentsVoid   = "void"   // tagihan yang belum dibayar dibatalkan
+	CancelPaymentsRefund = "refund" // seperti void, tagihan lunas diajukan refund
+)
+
+var ErrInvalidPaymentAction = errors.New("payment_action must be void or refund")
+
+// Tagihan yang boleh dibatalkan selama belum ada pembayaran masuk.
+var voidablePaymentStatuses = []string{
+	models.PaymentStatusDraft,
+	models.PaymentStatusIssued,
+	models.PaymentStatusOverdue,
+	models.PaymentStatusRejected,
+}
+
+const eventChangeTimeLayout = "02/01/2006 15:04"
+
+// DiffEventSchedule membandingkan jadwal dan lokasi event sebelum dan
+// sesudah diubah. Jam ditulis dalam zona waktu vendor.
+func DiffEventSchedule(before, after models.Event, loc *time.Location) []models.EventFieldChange {
+	var changes []models.EventFieldChange
+	add := func(field, from, to string) {
+		if from != to {
+			changes = append(changes, models.EventFieldChange{Field: field, Before: from, After: to})
+		}
+	}
+	add("start_at", eventChangeTime(before.StartAt, loc), eventChangeTime(after.StartAt, loc))
+	add("end_at", eventChangeTime(before.EndAt, loc), eventChangeTime(after.EndAt, loc))
+	add("location", before.Location, after.Location)
+	add("location_point", before.LocationPoint, after.LocationPoint)
+	return changes
+}
+
+func eventChangeTime(t *time.Time, loc *time.Location) string {
+	if t == nil {
+		return ""
+	}
+	return t.In(loc).Format(eventChangeTimeLayout)
+}
+
+// RecordEventChange menyimpan perubahan event. Jumlah penerima diisi dari
+// pemain terdaftar beserta walinya.
+func RecordEventChange(tx *gorm.DB, event models.Event, kind string, changes []models.EventFieldChange, reason string, actor *models.User) (models.EventChange, error) {
+	change := models.EventChange{
+		VendorID: event.VendorID,
+		EventID:  event.ID,
+		Kind:     kind,
+		Changes:  changes,
+		Reason:   reason,
+	}
+	if actor != nil {
+		change.ChangedBy = &actor.ID
+	}
+	recipients, err := eventChangeRecipients(tx, event.ID)
+	if err != nil {
+		return change, err
+	}
+	change.Recipients = len(recipients)
+	return change, tx.Create(&change).Error
+}
+
+// eventRecipient adalah user yang diberi tahu. Player kosong berarti user
+// itu sendiri pemain yang terdaftar, terisi bila user adalah walinya.
+type eventRecipient struct {
+	User   models.User
+	Player string
+}
+
+// eventChangeRecipients mengambil semua pemain yang punya EventLog di event
+// beserta walinya. Wali dengan beberapa anak cukup diberi tahu sekali.
+func eventChangeRecipients(db *gorm.DB, eventID uint) ([]eventRecipient, error) {
+	var players []models.User
+	if err := db.Where("id IN (?)", db.Model(&models.EventLog{}).Select("user_id").Where("event_id = ?", eventID)).
+		Order("id ASC").Find(&players).Error; err != nil {
+		return nil, err
+	}
+
+	seen := map[uint]bool{}
+	var recipients []eventRecipient
+	var guardianIDs []uint
+	children := map[uint][]string{}
+	for _, p := range players {
+		seen[p.ID] = true
+		recipients = append(recipients, eventRecipient{User: p})
+		if p.GuardianID != nil {
+			if _, ok := children[*p.GuardianID]; !ok {
+				guardianIDs = append(guardianIDs, *p.GuardianID)
+			}
+			children[*p.GuardianID] = append(children[*p.GuardianID], p.Name)
+		}
+	}
+	if len(guardianIDs) == 0 {
+		return recipients, nil
+	}
+
+	var guardians []models.User
+	if err := db.Where("id IN ?", guardianIDs).Order("id ASC").Find(&guardians).Error; err != nil {
+		return nil, err
+	}
+	for _, g := range guardians {
+		if seen[g.ID] {
+			continue // wali yang juga terdaftar sudah dapat sebagai pemain
+		}
+		recipients = append(recipients, eventRecipient{User: g, Player: strings.Join(children[g.ID], ", ")})
+	}
+	return recipients, nil
+}
+
+// NotifyEventChange mengirim push dan notifikasi in-app ke pemain terdaftar
+// dan walinya. Dipanggil setelah transaksi berhasil.
+func NotifyEventChange(db *gorm.DB, event models.Event, change models.EventChange) {
+	recipients, err := eventChangeRecipients(db, event.ID)
+	if err != nil {
+		log.Printf("Gagal mengambil penerima perubahan event %d: %v", event.ID, err)
+		return
+	}
+
+	var title string
+	switch change.Kind {
+	case models.EventChangeCancelled:
+		title = "Event Dibatalkan: " + event.Title
+	case models.EventChangeRestored:
+		title = "Event Kembali Dijadwalkan: " + event.Title
+	default:
+		title = "Perubahan Event: " + event.Title
+	}
+	body := eventChangeBody(change)
+
+	for _, r := range recipients {
+		text := body
+		if r.Player != "" {
+			text = "Untuk " + r.Player + ". " + body
+		}
+		go CreateNotification(r.User.ID, r.User.FCMToken, title, text, "event_change")
+	}
+}
+
+var eventChangeLabels = map[string]string{
+	"start_at":       "Mulai",
+	"end_at":         "Selesai",
+	"location":       "Lokasi",
+	"location_point": "Titik lokasi",
+}
+
+// eventChangeBody menulis diff sebagai teks notifikasi, misal
+// "Mulai: 06/01/2025 15:00 → 07/01/2025 16:00".
+func eventChangeBody(change models.EventChange) string {
+	var lines []string
+	switch change.Kind {
+	case models.EventChangeCancelled:
+		lines = append(lines, "Event ini dibatalkan.")
+	case models.EventChangeRestored:
+		lines = append(lines, "Pembatalan event ini dicabut, event tetap berjalan.")
+	}
+	if change.Reason != "" {
+		lines = append(lines, "Alasan: "+change.Reason)
+	}
+	for _, fc := range change.Changes {
+		before, after := fc.Before, fc.After
+		if before == "" {
+			before = "-"
+		}
+		if after == "" {
+			after = "-"
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s → %s", eventChangeLabels[fc.Field], before, after))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CancelledEventPayments adalah hasil penanganan tagihan event yang
+// dibatalkan.
+type CancelledEventPayments struct {
+	Voided   []uint `json:"voided"`   // ID tagihan yang dibatalkan
+	Refunds  []uint `json:"refunds"`  // ID refund yang diajukan
+	Skipped  []uint `json:"skipped"`  // ID tagihan yang perlu ditangani manual
+	Reviewed int    `json:"reviewed"` // jumlah tagihan yang diperiksa
+}
+
+// SettleCancelledEventPayments membatalkan tagihan event yang belum dibayar.
+// Dengan CancelPaymentsRefund tagihan yang sudah lunas diajukan refund penuh
+// dan tetap menunggu persetujuan admin seperti refund biasa. Tagihan yang
+// dibayar sebagian atau sedang diverifikasi dilewati.
+func SettleCancelledEventPayments(tx *gorm.DB, event models.Event, action string, actor models.User) (CancelledEventPayments, error) {
+	var result CancelledEventPayments
+	switch action {
+	case CancelPaymentsKeep:
+		return result, nil
+	case CancelPaymentsVoid, CancelPaymentsRefund:
+	default:
+		return result, ErrInvalidPaymentAction
+	}
+
+	statuses := append([]string{}, voidablePaymentStatuses...)
+	statuses = append(statuses, models.PaymentStatusPartiallyPaid, models.PaymentStatusAwaitingVerification)
+	if action == CancelPaymentsRefund {
+		statuses = append(statuses, models.PaymentStatusPaid)
+	}
+	var payments []models.Payment
+	if err := tx.Where("event_id = ? AND status IN ?", event.ID, statuses).Order("id ASC").Find(&payments).Error; err != nil {
+		return result, err
+	}
+	result.Reviewed = len(payments)
+
+	reason := "Event dibatalkan: " + event.Title
+	for i := range payments {
+		p := &payments[i]
+		switch {
+		case p.Status == models.PaymentStatusPaid:
+			refundable, err := RefundableAmount(tx, *p)
+			if err != nil {
+				return result, err
+			}
+			if refundable <= 0 {
+				continue // sudah direfund atau sedang diajukan
+			}
+			refund, err := RequestRefund(tx, *p, 0, reason, actor)
+			if err != nil {
+				return result, err
+			}
+			result.Refunds = append(result.Refunds, refund.ID)
+		case p.PaidAmount <= 0 && models.CanTransitionPayment(p.Status, models.PaymentStatusCancelled):
+			if err := TransitionPayment(tx, p, models.PaymentStatusCancelled, &actor, reason); err != nil {
+				return result, err
+			}
+			result.Voided = append(result.Voided, p.ID)
+		default:
+			result.Skipped = append(result.Skipped, p.ID)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,290 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"ssb_api/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrEventNotFinished = errors.New("event is not finished")
+	ErrEventLogLocked   = errors.New("attendance is locked because the event is finished, reopen the event to edit it")
+)
+
+// EventInvoiceDueDays adalah jatuh tempo tagihan event harian sejak event
+// selesai.
+const EventInvoiceDueDays = 7
+
+// Tagihan event yang tidak dihitung saat mengecek tagihan ganda.
+var voidPaymentStatuses = []string{models.PaymentStatusCancelled, models.PaymentStatusRefunded}
+
+// EventFinishResult adalah ringkasan penutupan event.
+type EventFinishResult struct {
+	Closing  models.EventClosing `json:"closing"`
+	Invoices []models.Payment    `json:"invoices"`
+}
+
+// EventReopenResult adalah hasil membuka kembali event yang sudah selesai.
+type EventReopenResult struct {
+	Restored  int    `json:"restored"`  // log yang kembali seperti sebelum event selesai
+	Cancelled []uint `json:"cancelled"` // tagihan yang dibatalkan
+	Kept      []uint `json:"kept"`      // tagihan yang sudah dibayar, ditangani lewat refund
+}
+
+// FinishEvent menutup event dalam satu transaksi: pendaftar tanpa check-in
+// ditandai absen dan tidak dihitung hadir, semua EventLog dikunci, counter
+// kehadiran dihitung ulang, lalu event berbayar harian ditagihkan ke peserta
+// yang hadir sebesar Event.Fee. Yang diubah dicatat di EventClosing.
+func FinishEvent(db *gorm.DB, eventID uint, actor models.User, now time.Time) (EventFinishResult, error) {
+	var result EventFinishResult
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var event models.Event
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&event, eventID).Error; err != nil {
+			return err
+		}
+		if event.IsFinish {
+			return ErrEventFinished
+		}
+		if event.IsCancelled {
+			return ErrEventCancelled
+		}
+
+		closing := models.EventClosing{
+			VendorID:   event.VendorID,
+			EventID:    event.ID,
+			FinishedBy: &actor.ID,
+			FinishedAt: now,
+		}
+
+		var logs []models.EventLog
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("event_id = ?", event.ID).Order("id ASC").Find(&logs).Error; err != nil {
+			return err
+		}
+		var userIDs []uint
+		var attendees []models.EventLog
+		for i := range logs {
+			log := &logs[i]
+			userIDs = append(userIDs, log.UserID)
+			if log.Waitlisted || log.RSVP == models.RSVPNotGoing {
+				continue
+			}
+			if log.Status && log.CheckedInAt != nil {
+				closing.Present++
+				attendees = append(attendees, *log)
+				continue
+			}
+			closing.Absent++
+			closing.AbsentLogIDs = append(closing.AbsentLogIDs, log.ID)
+			if log.Status {
+				closing.UncountedLogIDs = append(closing.UncountedLogIDs, log.ID)
+			}
+		}
+
+		if len(closing.AbsentLogIDs) > 0 {
+			if err := tx.Model(&models.EventLog{}).Where("id IN ?", closing.AbsentLogIDs).
+				Updates(map[string]interface{}{"absent": true, "status": false}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.EventLog{}).Where("event_id = ?", event.ID).
+			Update("locked_at", now).Error; err != nil {
+			return err
+		}
+		if err := RecomputeAttendanceCounters(tx, userIDs); err != nil {
+			return err
+		}
+
+		if event.IsPaid && event.PaymentType == "daily" && event.Fee > 0 {
+			invoices, err := invoiceEventAttendees(tx, event, attendees, actor, now)
+			if err != nil {
+				return err
+			}
+			for _, p := range invoices {
+				closing.PaymentIDs = append(closing.PaymentIDs, p.ID)
+				closing.InvoicedAmount += p.Amount
+			}
+			result.Invoices = invoices
+		}
+
+		if err := tx.Create(&closing).Error; err != nil {
+			return err
+		}
+		result.Closing = closing
+		return tx.Model(&event).Update("is_finish", true).Error
+	})
+	return result, err
+}
+
+// invoiceEventAttendees menerbitkan tagihan Event.Fee untuk peserta yang
+// hadir. Peserta yang sudah punya tagihan aktif untuk event ini dilewati,
+// misal sudah bayar di muka atau tagihannya masih ada dari penutupan
+// sebelumnya.
+func invoiceEventAttendees(tx *gorm.DB, event models.Event, attendees []models.EventLog, actor models.User, now time.Time) ([]models.Payment, error) {
+	if len(attendees) == 0 {
+		return nil, nil
+	}
+	var billed []uint
+	if err := tx.Model(&models.Payment{}).
+		Where("event_id = ? AND status NOT IN ?", event.ID, voidPaymentStatuses).
+		Pluck("user_id", &billed).Error; err != nil {
+		return nil, err
+	}
+	skip := map[uint]bool{}
+	for _, id := range billed {
+		skip[id] = true
+	}
+
+	loc := VendorLocation(tx, event.VendorID)
+	var invoices []models.Payment
+	for _, log := range attendees {
+		if skip[log.UserID] {
+			continue
+		}
+		vendorID := event.VendorID
+		eventID := event.ID
+		payment := models.Payment{
+			UserID:   log.UserID,
+			UserName: log.UserName,
+			VendorID: &vendorID,
+			EventID:  &eventID,
+			Amount:   event.Fee,
+			Method:   "transfer",
+			Status:   models.PaymentStatusIssued,
+			Type:     "event",
+			Date:     models.DateOf(now.In(loc)),
+			DueDate:  models.DateOf(now.In(loc).AddDate(0, 0, EventInvoiceDueDays)),
+			Note:     fmt.Sprintf("Tagihan %s tanggal %s", event.Title, event.Date),
+		}
+		if err := CreatePaymentRecord(tx, &payment, &actor, "Event selesai: "+event.Title); err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, payment)
+	}
+	return invoices, nil
+}
+
+// ReopenEvent membatalkan efek FinishEvent: tanda absen dan status hadir
+// dikembalikan, kunci dibuka, counter dihitung ulang, dan tagihan yang belum
+// dibayar dibatalkan. Tagihan yang sudah dibayar (termasuk lewat saldo kredit)
+// dibiarkan dan harus direfund lewat alur refund.
+func ReopenEvent(db *gorm.DB, eventID uint, actor models.User, now time.Time) (EventReopenResult, error) {
+	var result EventReopenResult
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var event models.Event
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&event, eventID).Error; err != nil {
+			return err
+		}
+		if !event.IsFinish {
+			return ErrEventNotFinished
+		}
+
+		// Event yang diselesaikan sebelum ada EventClosing cukup dibuka kuncinya
+		var closing models.EventClosing
+		err := tx.Where("event_id = ? AND reopened_at IS NULL", event.ID).Order("id DESC").First(&closing).Error
+		hasClosing := err == nil
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if hasClosing {
+			if len(closing.AbsentLogIDs) > 0 {
+				if err := tx.Model(&models.EventLog{}).Where("id IN ?", closing.AbsentLogIDs).
+					Update("absent", false).Error; err != nil {
+					return err
+				}
+			}
+			if len(closing.UncountedLogIDs) > 0 {
+				if err := tx.Model(&models.EventLog{}).Where("id IN ?", closing.UncountedLogIDs).
+					Update("status", true).Error; err != nil {
+					return err
+				}
+			}
+			result.Restored = len(closing.AbsentLogIDs)
+
+			if len(closing.PaymentIDs) > 0 {
+				var payments []models.Payment
+				if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+					Where("id IN ?", closing.PaymentIDs).Order("id ASC").Find(&payments).Error; err != nil {
+					return err
+				}
+				for i := range payments {
+					p := &payments[i]
+					if p.Status == models.PaymentStatusCancelled {
+						continue
+					}
+					if p.PaidAmount > 0 || !models.CanTransitionPayment(p.Status, models.PaymentStatusCancelled) {
+						result.Kept = append(result.Kept, p.ID)
+						continue
+					}
+					if err := TransitionPayment(tx, p, models.PaymentStatusCancelled, &actor, "Event dibuka kembali: "+event.Title); err != nil {
+						return err
+					}
+					result.Cancelled = append(result.Cancelled, p.ID)
+				}
+			}
+
+			closing.ReopenedBy = &actor.ID
+			closing.ReopenedAt = &now
+			if err := tx.Model(&closing).Select("reopened_by", "reopened_at").Updates(&closing).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&models.EventLog{}).Where("event_id = ?", event.ID).
+			Update("locked_at", nil).Error; err != nil {
+			return err
+		}
+		var userIDs []uint
+		if err := tx.Model(&models.EventLog{}).Where("event_id = ?", event.ID).
+			Distinct().Pluck("user_id", &userIDs).Error; err != nil {
+			return err
+		}
+		if err := RecomputeAttendanceCounters(tx, userIDs); err != nil {
+			return err
+		}
+		return tx.Model(&event).Update("is_finish", false).Error
+	})
+	return result, err
+}
+
+// RecomputeAttendanceCounters menghitung ulang counter match, training dan
+// program user dari EventLog yang dihitung hadir.
+func RecomputeAttendanceCounters(tx *gorm.DB, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	count := func(eventType string) clause.Expr {
+		return gorm.Expr(`(SELECT COUNT(*) FROM event_logs
+			WHERE event_logs.user_id = users.id AND event_logs.status = TRUE
+			AND event_logs.deleted_at IS NULL AND LOWER(event_logs.event_type) = ?)`, eventType)
+	}
+	return tx.Model(&models.User{}).Where("id IN ?", userIDs).UpdateColumns(map[string]interface{}{
+		"match":    count("match"),
+		"training": count("training"),
+		"program":  count("program"),
+	}).Error
+}
+
+// NotifyEventFinished mengirim ringkasan penutupan ke pelatih dan tagihan
+// baru ke peserta.
+func NotifyEventFinished(db *gorm.DB, event models.Event, coach models.User, result EventFinishResult) {
+	summary := fmt.Sprintf("%s selesai. Hadir %d, absen %d.", event.Title, result.Closing.Present, result.Closing.Absent)
+	if len(result.Invoices) > 0 {
+		summary += fmt.Sprintf(" %d tagihan terbit, total %s.", len(result.Invoices), FormatRupiah(result.Closing.InvoicedAmount))
+	}
+	go CreateNotification(coach.ID, coach.FCMToken, "Ringkasan Event", summary, "event")
+
+	for _, p := range result.Invoices {
+		var player models.User
+		if err := db.First(&player, p.UserID).Error; err != nil || player.FCMToken == "" {
+			continue
+		}
+		body := fmt.Sprintf("Hai %s, tagihan %s sebesar %s sudah terbit.", player.Name, event.Title, FormatRupiah(p.Amount))
+		go CreateNotification(player.ID, player.FCMToken, "Tagihan Baru", body, "payment")
+	}
+}
@@ -0,0 +1,249 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"ssb_api/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrEventFinished      = errors.New("event is already finished")
+	ErrEventCancelled     = errors.New("event has been cancelled")
+	ErrRegistrationClosed = errors.New("registration deadline has passed")
+	ErrAlreadyRegistered  = errors.New("event log already exists for this user and event")
+	ErrInvalidRSVP        = errors.New("rsvp must be going, maybe or not_going")
+)
+
+// ValidRSVP memeriksa jawaban RSVP.
+func ValidRSVP(rsvp string) bool {
+	switch rsvp {
+	case models.RSVPGoing, models.RSVPMaybe, models.RSVPNotGoing:
+		return true
+	}
+	return false
+}
+
+// CheckRegistrationOpen menolak pendaftaran untuk event yang sudah selesai,
+// dibatalkan, atau lewat batas pendaftaran.
+func CheckRegistrationOpen(event models.Event, now time.Time) error {
+	if event.IsFinish {
+		return ErrEventFinished
+	}
+	if event.IsCancelled {
+		return ErrEventCancelled
+	}
+	if event.RegistrationDeadline != nil && now.After(*event.RegistrationDeadline) {
+		return ErrRegistrationClosed
+	}
+	return nil
+}
+
+// RegisterForEvent membuat pendaftaran baru. Pemain yang menjawab going
+// masuk antrean bila kuota event sudah penuh.
+func RegisterForEvent(db *gorm.DB, log *models.EventLog) error {
+	if log.RSVP == "" {
+		log.RSVP = models.RSVPGoing
+	}
+	if !ValidRSVP(log.RSVP) {
+		return ErrInvalidRSVP
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Kunci event supaya dua pendaftar tidak mengambil kursi terakhir bersamaan
+		var event models.Event
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&event, log.EventID).Error; err != nil {
+			return err
+		}
+		if err := CheckRegistrationOpen(event, time.Now()); err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.EventLog{}).
+			Where("user_id = ? AND event_id = ?", log.UserID, log.EventID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrAlreadyRegistered
+		}
+
+		if err := placeRSVP(tx, event, log); err != nil {
+			return err
+		}
+		if err := tx.Create(log).Error; err != nil {
+			return err
+		}
+		if log.Status {
+			return AdjustAttendanceCounter(tx, log.UserID, log.EventType, 1)
+		}
+		return nil
+	})
+}
+
+// UpdateRSVP mengubah jawaban RSVP pendaftaran. Kursi yang dilepas langsung
+// diberikan ke antrean, pemain yang naik dikembalikan untuk dinotifikasi.
+func UpdateRSVP(db *gorm.DB, log *models.EventLog, rsvp string) ([]models.EventLog, error) {
+	if !ValidRSVP(rsvp) {
+		return nil, ErrInvalidRSVP
+	}
+
+	var promoted []models.EventLog
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var event models.Event
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&event, log.EventID).Error; err != nil {
+			return err
+		}
+		if err := tx.First(log, log.ID).Error; err != nil {
+			return err
+		}
+		if event.IsFinish {
+			return ErrEventFinished
+		}
+		if log.RSVP == rsvp {
+			return nil
+		}
+		// Melepas kursi tetap boleh setelah batas pendaftaran
+		if rsvp == models.RSVPGoing {
+			if err := CheckRegistrationOpen(event, time.Now()); err != nil {
+				return err
+			}
+		}
+
+		freed := log.RSVP == models.RSVPGoing && !log.Waitlisted
+		counted := log.Status
+		log.RSVP = rsvp
+		if err := placeRSVP(tx, event, log); err != nil {
+			return err
+		}
+		if err := tx.Model(log).Select("rsvp", "status", "waitlisted", "waitlisted_at").Updates(log).Error; err != nil {
+			return err
+		}
+
+		if counted != log.Status {
+			delta := 1
+			if counted {
+				delta = -1
+			}
+			if err := AdjustAttendanceCounter(tx, log.UserID, log.EventType, delta); err != nil {
+				return err
+			}
+		}
+
+		if freed {
+			var err error
+			promoted, err = PromoteWaitlist(tx, event)
+			return err
+		}
+		return nil
+	})
+	return promoted, err
+}
+
+// PromoteWaitlist menaikkan antrean sesuai urutan daftar sampai kuota
+// terisi. Dipanggil di dalam transaksi yang sudah mengunci event.
+func PromoteWaitlist(tx *gorm.DB, event models.Event) ([]models.EventLog, error) {
+	if event.IsFinish {
+		return nil, nil
+	}
+
+	query := tx.Where("event_id = ? AND rsvp = ? AND waitlisted = ?", event.ID, models.RSVPGoing, true).
+		Order("waitlisted_at ASC, id ASC")
+	if event.Capacity > 0 {
+		taken, err := countGoing(tx, event.ID, 0)
+		if err != nil {
+			return nil, err
+		}
+		free := int64(event.Capacity) - taken
+		if free <= 0 {
+			return nil, nil
+		}
+		query = query.Limit(int(free))
+	}
+
+	var logs []models.EventLog
+	if err := query.Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	for i := range logs {
+		logs[i].Waitlisted = false
+		logs[i].WaitlistedAt = nil
+		logs[i].Status = true
+		if err := tx.Model(&logs[i]).Select("waitlisted", "waitlisted_at", "status").Updates(&logs[i]).Error; err != nil {
+			return nil, err
+		}
+		if err := AdjustAttendanceCounter(tx, logs[i].UserID, logs[i].EventType, 1); err != nil {
+			return nil, err
+		}
+	}
+	return logs, nil
+}
+
+// NotifyWaitlistPromotion mengirim push ke pemain yang naik dari antrean.
+func NotifyWaitlistPromotion(db *gorm.DB, event models.Event, logs []models.EventLog) {
+	for _, log := range logs {
+		var user models.User
+		if err := db.First(&user, log.UserID).Error; err != nil || user.FCMToken == "" {
+			continue
+		}
+		body := fmt.Sprintf("Hai %s, ada kursi kosong di %s. Kamu sudah terdaftar dari antrean.", user.Name, event.Title)
+		go CreateNotification(user.ID, user.FCMToken, "Kamu Dapat Tempat!", body, "event")
+	}
+}
+
+// AdjustAttendanceCounter menambah atau mengurangi counter match, training
+// atau program user sesuai tipe event. Counter tidak pernah negatif.
+func AdjustAttendanceCounter(db *gorm.DB, userID uint, eventType string, delta int) error {
+	var column string
+	switch strings.ToLower(eventType) {
+	case "match":
+		column = "match"
+	case "training":
+		column = "training"
+	case "program":
+		column = "program"
+	default:
+		return nil
+	}
+	return db.Model(&models.User{}).Where("id = ?", userID).
+		UpdateColumn(column, gorm.Expr(`GREATEST("`+column+`" + ?, 0)`, delta)).Error
+}
+
+// placeRSVP mengisi status dan antrean sesuai jawaban RSVP dan sisa kuota.
+// Hanya going yang menempati kursi dan dihitung hadir.
+func placeRSVP(tx *gorm.DB, event models.Event, log *models.EventLog) error {
+	log.Status = false
+	log.Waitlisted = false
+	log.WaitlistedAt = nil
+	if log.RSVP != models.RSVPGoing {
+		return nil
+	}
+
+	if event.Capacity > 0 {
+		taken, err := countGoing(tx, event.ID, log.ID)
+		if err != nil {
+			return err
+		}
+		if taken >= int64(event.Capacity) {
+			now := time.Now()
+			log.Waitlisted = true
+			log.WaitlistedAt = &now
+			return nil
+		}
+	}
+	log.Status = true
+	return nil
+}
+
+// countGoing menghitung kursi yang terisi, tanpa pendaftaran excludeID.
+func countGoing(tx *gorm.DB, eventID, excludeID uint) (int64, error) {
+	var count int64
+	err := tx.Model(&models.EventLog{}).
+		Where("event_id = ? AND rsvp = ? AND waitlisted = ? AND id <> ?", eventID, models.RSVPGoing, false, excludeID).
+		Count(&count).Error
+	return count, err
+}
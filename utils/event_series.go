@@ -0,0 +1,243 @@
+package utils
+
+import (
+	"errors"
+	"ssb_api/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SeriesHorizonDays adalah jangkauan pertemuan yang langsung dibuat saat
+// series dibuat. Pertemuan sesudahnya dibuat saat rentang tanggalnya diminta.
+const SeriesHorizonDays = 90
+
+// MaxExpandDays membatasi rentang tanggal yang boleh diminta GetEvents.
+const MaxExpandDays = 366
+
+// CreateSeries menyimpan series lalu membuat pertemuan sampai horizon. Jam
+// pertemuan dibaca di zona waktu vendor loc.
+func CreateSeries(tx *gorm.DB, series *models.EventSeries, loc *time.Location) error {
+	r, err := SeriesRecurrence(*series)
+	if err != nil {
+		return err
+	}
+	if series.Time != "" {
+		if series.Time, err = models.ParseClock(series.Time); err != nil {
+			return err
+		}
+	}
+	if series.DurationMinutes < 0 {
+		return ErrInvalidSchedule
+	}
+	if err := tx.Create(series).Error; err != nil {
+		return err
+	}
+	_, err = ExpandSeries(tx, *series, r.Start, r.Start.AddDate(0, 0, SeriesHorizonDays), loc)
+	return err
+}
+
+// ExpandSeries membuat pertemuan series di rentang [from, to] yang belum
+// ada. Tanggal yang dibatalkan atau pertemuan yang sudah dihapus tidak dibuat
+// ulang.
+func ExpandSeries(db *gorm.DB, series models.EventSeries, from, to time.Time, loc *time.Location) (int, error) {
+	r, err := SeriesRecurrence(series)
+	if err != nil {
+		return 0, err
+	}
+	dates := r.Between(from, to)
+	if len(dates) == 0 {
+		return 0, nil
+	}
+	var texts []string
+	for _, d := range dates {
+		texts = append(texts, d.Format("2006-01-02"))
+	}
+
+	var existing, cancelled []string
+	if err := db.Unscoped().Model(&models.Event{}).
+		Where("series_id = ? AND occurrence_date IN ?", series.ID, texts).
+		Pluck("occurrence_date", &existing).Error; err != nil {
+		return 0, err
+	}
+	if err := db.Model(&models.EventSeriesException{}).
+		Where("series_id = ? AND cancelled = ? AND date IN ?", series.ID, true, texts).
+		Pluck("date", &cancelled).Error; err != nil {
+		return 0, err
+	}
+	skip := map[string]bool{}
+	for _, d := range append(existing, cancelled...) {
+		skip[d] = true
+	}
+
+	var events []models.Event
+	for _, d := range texts {
+		if !skip[d] {
+			events = append(events, SeriesOccurrence(series, d, loc))
+		}
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+	if err := db.Create(&events).Error; err != nil {
+		return 0, err
+	}
+	return len(events), nil
+}
+
+// ExpandVendorSeries membuat pertemuan semua series vendor yang jatuh di
+// rentang [from, to].
+func ExpandVendorSeries(db *gorm.DB, vendorID uint, from, to time.Time) error {
+	var series []models.EventSeries
+	if err := db.Where("vendor_id = ? AND start_date <= ? AND (until = '' OR until >= ?)",
+		vendorID, to.Format("2006-01-02"), from.Format("2006-01-02")).Find(&series).Error; err != nil {
+		return err
+	}
+	if len(series) == 0 {
+		return nil
+	}
+	loc := VendorLocation(db, vendorID)
+	for _, s := range series {
+		if _, err := ExpandSeries(db, s, from, to, loc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SeriesOccurrence membuat event untuk satu tanggal series.
+func SeriesOccurrence(series models.EventSeries, date string, loc *time.Location) models.Event {
+	seriesID := series.ID
+	event := models.Event{
+		Title:          series.Title,
+		Description:    series.Description,
+		EventType:      series.EventType,
+		Date:           date,
+		Time:           series.Time,
+		Location:       series.Location,
+		LocationPoint:  series.LocationPoint,
+		IsPaid:         series.IsPaid,
+		PaymentType:    series.PaymentType,
+		Fee:            series.Fee,
+		VendorID:       series.VendorID,
+		Capacity:       series.Capacity,
+		IsPrivate:      series.IsPrivate,
+		CheckInRadius:  series.CheckInRadius,
+		SeriesID:       &seriesID,
+		OccurrenceDate: date,
+	}
+	if start, end, err := SeriesSchedule(series, date, loc); err == nil {
+		event.SetSchedule(start, end, loc)
+	}
+	return event
+}
+
+// SplitSeries menerapkan perubahan ke pertemuan occurrence dan semua
+// pertemuan sesudahnya. Series lama diakhiri sehari sebelumnya dan sisanya
+// dipindah ke series baru. Bila occurrence adalah pertemuan pertama, series
+// diubah langsung tanpa dipecah. Pertemuan yang diubah dikembalikan supaya
+// antreannya bisa diproses.
+func SplitSeries(tx *gorm.DB, occurrence models.Event, loc *time.Location, apply func(*models.EventSeries)) (models.EventSeries, []models.Event, error) {
+	var old models.EventSeries
+	if err := tx.First(&old, *occurrence.SeriesID).Error; err != nil {
+		return old, nil, err
+	}
+	r, err := SeriesRecurrence(old)
+	if err != nil {
+		return old, nil, err
+	}
+	date, err := time.Parse("2006-01-02", occurrence.OccurrenceDate)
+	if err != nil {
+		return old, nil, ErrNotOccurrence
+	}
+
+	target := old
+	if before := r.CountBefore(date); before > 0 {
+		target.Model = gorm.Model{}
+		target.StartDate = occurrence.OccurrenceDate
+		if old.Count > 0 {
+			target.Count = old.Count - before
+			old.Count = before
+		}
+		old.Until = date.AddDate(0, 0, -1).Format("2006-01-02")
+		if err := tx.Model(&old).Select("until", "count").Updates(&old).Error; err != nil {
+			return old, nil, err
+		}
+	}
+	apply(&target)
+	if err := tx.Save(&target).Error; err != nil {
+		return target, nil, err
+	}
+
+	if target.ID != old.ID {
+		if err := tx.Model(&models.EventSeriesException{}).
+			Where("series_id = ? AND date >= ?", old.ID, occurrence.OccurrenceDate).
+			Update("series_id", target.ID).Error; err != nil {
+			return target, nil, err
+		}
+	}
+
+	var events []models.Event
+	if err := tx.Where("series_id = ? AND occurrence_date >= ? AND is_finish = ?", old.ID, occurrence.OccurrenceDate, false).
+		Find(&events).Error; err != nil {
+		return target, nil, err
+	}
+	for i := range events {
+		e := &events[i]
+		seriesID := target.ID
+		e.SeriesID = &seriesID
+		e.Title = target.Title
+		e.Description = target.Description
+		e.EventType = target.EventType
+		if start, end, err := SeriesSchedule(target, e.Date, loc); err == nil {
+			e.SetSchedule(start, end, loc)
+		}
+		e.Location = target.Location
+		e.LocationPoint = target.LocationPoint
+		e.IsPaid = target.IsPaid
+		e.PaymentType = target.PaymentType
+		e.Fee = target.Fee
+		e.Capacity = target.Capacity
+		e.IsPrivate = target.IsPrivate
+		e.CheckInRadius = target.CheckInRadius
+		e.IsException = false
+		if err := tx.Save(e).Error; err != nil {
+			return target, nil, err
+		}
+	}
+	return target, events, nil
+}
+
+// SetSeriesDateCancelled membatalkan (atau memulihkan) satu tanggal series
+// beserta event pertemuannya bila sudah dibuat.
+func SetSeriesDateCancelled(tx *gorm.DB, series models.EventSeries, date, reason string, cancelled bool) error {
+	r, err := SeriesRecurrence(series)
+	if err != nil {
+		return err
+	}
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil || len(r.Between(day, day)) == 0 {
+		return ErrNotOccurrence
+	}
+
+	var exception models.EventSeriesException
+	err = tx.Where("series_id = ? AND date = ?", series.ID, date).First(&exception).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		exception = models.EventSeriesException{SeriesID: series.ID, VendorID: series.VendorID, Date: date}
+	case err != nil:
+		return err
+	}
+	exception.Cancelled = cancelled
+	exception.Reason = reason
+	if err := tx.Save(&exception).Error; err != nil {
+		return err
+	}
+
+	if !cancelled {
+		reason = ""
+	}
+	return tx.Model(&models.Event{}).
+		Where("series_id = ? AND occurrence_date = ?", series.ID, date).
+		Updates(map[string]interface{}{"is_cancelled": cancelled, "cancel_reason": reason}).Error
+}
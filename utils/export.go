@@ -0,0 +1,397 @@
+package utils
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format file export.
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+)
+
+var ErrUnknownExportFormat = errors.New("unknown export format, use csv or xlsx")
+
+const (
+	cellText = iota
+	cellNumber
+	cellMoney
+	cellDate
+	cellDateTime
+)
+
+// ExportCell adalah satu sel spreadsheet. Angka dan tanggal disimpan sebagai
+// nilai asli supaya XLSX tetap bisa dihitung, lalu diformat gaya Indonesia
+// saat ditulis ke CSV.
+type ExportCell struct {
+	kind   int
+	text   string
+	number float64
+	time   time.Time
+}
+
+// TextCell menulis teks apa adanya, kecuali teks yang bisa dibaca sebagai
+// rumus oleh Excel/Sheets (diawali =, +, -, @, tab atau CR) diberi awalan '
+// supaya data user tidak dijalankan saat export dibuka.
+func TextCell(s string) ExportCell {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		s = "'" + s
+	}
+	return ExportCell{kind: cellText, text: s}
+}
+
+// IDCell menulis ID atau nomor punggung sebagai teks tanpa pemisah ribuan,
+// supaya 1234 tidak terbaca sebagai desimal 1.234 di luar locale Indonesia.
+func IDCell(v int) ExportCell {
+	return ExportCell{kind: cellText, text: strconv.Itoa(v)}
+}
+
+func NumberCell(v float64) ExportCell     { return ExportCell{kind: cellNumber, number: v} }
+func MoneyCell(v float64) ExportCell      { return ExportCell{kind: cellMoney, number: v} }
+func DateTimeCell(t time.Time) ExportCell { return ExportCell{kind: cellDateTime, time: t} }
+
+// DateCell menerima tanggal YYYY-MM-DD. Nilai yang tidak bisa dibaca ditulis
+// apa adanya sebagai teks.
+func DateCell(date string) ExportCell {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return TextCell(date)
+	}
+	return ExportCell{kind: cellDate, time: t}
+}
+
+// BoolCell menulis Ya/Tidak.
+func BoolCell(v bool) ExportCell {
+	if v {
+		return TextCell("Ya")
+	}
+	return TextCell("Tidak")
+}
+
+// SpreadsheetWriter menulis baris demi baris langsung ke output tanpa
+// menampung seluruh data di memori.
+type SpreadsheetWriter interface {
+	WriteHeader(columns []string) error
+	WriteRow(cells []ExportCell) error
+	// Flush mengirim baris yang sudah ditulis ke output.
+	Flush() error
+	Close() error
+}
+
+// NewSpreadsheetWriter membuat writer CSV atau XLSX.
+func NewSpreadsheetWriter(w io.Writer, format, sheetName string) (SpreadsheetWriter, error) {
+	switch format {
+	case ExportFormatCSV:
+		return newCSVWriter(w)
+	case ExportFormatXLSX:
+		return newXLSXWriter(w, sheetName)
+	}
+	return nil, ErrUnknownExportFormat
+}
+
+// ExportContentType adalah MIME type untuk format export.
+func ExportContentType(format string) string {
+	if format == ExportFormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// FormatNumberID memformat angka gaya Indonesia: 1.250.000 atau 1.250,5.
+func FormatNumberID(v float64) string {
+	negative := v < 0
+	v = math.Abs(v)
+	whole := math.Floor(v)
+	frac := math.Round((v - whole) * 100)
+	if frac == 100 {
+		whole++
+		frac = 0
+	}
+
+	digits := strconv.FormatFloat(whole, 'f', 0, 64)
+	var b strings.Builder
+	if negative {
+		b.WriteByte('-')
+	}
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(d)
+	}
+	if frac > 0 {
+		b.WriteString("," + strings.TrimRight(fmt.Sprintf("%02.0f", frac), "0"))
+	}
+	return b.String()
+}
+
+// csvWriter memakai pemisah titik koma dan BOM UTF-8 supaya langsung terbaca
+// rapi di Excel dengan regional Indonesia.
+type csvWriter struct {
+	out io.Writer
+	w   *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return nil, err
+	}
+	cw := csv.NewWriter(w)
+	cw.Comma = ';'
+	return &csvWriter{out: w, w: cw}, nil
+}
+
+func (c *csvWriter) WriteHeader(columns []string) error {
+	return c.w.Write(columns)
+}
+
+func (c *csvWriter) WriteRow(cells []ExportCell) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		switch cell.kind {
+		case cellNumber, cellMoney:
+			record[i] = FormatNumberID(cell.number)
+		case cellDate:
+			record[i] = cell.time.Format("02/01/2006")
+		case cellDateTime:
+			record[i] = cell.time.Format("02/01/2006 15:04")
+		default:
+			record[i] = cell.text
+		}
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		return err
+	}
+	flushOutput(c.out)
+	return nil
+}
+
+func (c *csvWriter) Close() error {
+	return c.Flush()
+}
+
+// Style XLSX, urutannya sama dengan cellXfs di xlsxStyles.
+const (
+	xlsxStyleDefault = iota
+	xlsxStyleHeader
+	xlsxStyleMoney
+	xlsxStyleDate
+	xlsxStyleDateTime
+	xlsxStyleNumber
+)
+
+// xlsxWriter menulis workbook satu sheet. File pendukung ditulis di awal,
+// lalu sheet ditulis bertahap sebagai entry zip terakhir. String ditulis
+// inline supaya tidak perlu tabel shared strings.
+type xlsxWriter struct {
+	out  io.Writer
+	zw   *zip.Writer
+	buf  *bufio.Writer
+	rows int
+	cols int
+}
+
+func newXLSXWriter(w io.Writer, sheetName string) (*xlsxWriter, error) {
+	if sheetName == "" {
+		sheetName = "Sheet1"
+	}
+	zw := zip.NewWriter(w)
+	files := []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, xmlEscape(truncateText(sheetName, 31)))},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(fw, f.body); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	x := &xlsxWriter{out: w, zw: zw, buf: bufio.NewWriterSize(sheet, 32*1024)}
+	_, err = x.buf.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>` +
+		`<sheetData>`)
+	return x, err
+}
+
+func (x *xlsxWriter) WriteHeader(columns []string) error {
+	cells := make([]ExportCell, len(columns))
+	for i, col := range columns {
+		cells[i] = TextCell(col)
+	}
+	x.cols = len(columns)
+	return x.writeRow(cells, xlsxStyleHeader)
+}
+
+func (x *xlsxWriter) WriteRow(cells []ExportCell) error {
+	return x.writeRow(cells, xlsxStyleDefault)
+}
+
+func (x *xlsxWriter) writeRow(cells []ExportCell, textStyle int) error {
+	x.rows++
+	fmt.Fprintf(x.buf, `<row r="%d">`, x.rows)
+	for i, cell := range cells {
+		ref := xlsxColumn(i) + strconv.Itoa(x.rows)
+		switch cell.kind {
+		case cellNumber:
+			fmt.Fprintf(x.buf, `<c r="%s" s="%d"><v>%s</v></c>`, ref, xlsxStyleNumber, strconv.FormatFloat(cell.number, 'f', -1, 64))
+		case cellMoney:
+			fmt.Fprintf(x.buf, `<c r="%s" s="%d"><v>%s</v></c>`, ref, xlsxStyleMoney, strconv.FormatFloat(cell.number, 'f', -1, 64))
+		case cellDate:
+			fmt.Fprintf(x.buf, `<c r="%s" s="%d"><v>%s</v></c>`, ref, xlsxStyleDate, xlsxSerial(cell.time))
+		case cellDateTime:
+			fmt.Fprintf(x.buf, `<c r="%s" s="%d"><v>%s</v></c>`, ref, xlsxStyleDateTime, xlsxSerial(cell.time))
+		default:
+			if cell.text == "" {
+				continue
+			}
+			fmt.Fprintf(x.buf, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, textStyle, xmlEscape(cell.text))
+		}
+	}
+	_, err := x.buf.WriteString(`</row>`)
+	return err
+}
+
+func (x *xlsxWriter) Flush() error {
+	if err := x.buf.Flush(); err != nil {
+		return err
+	}
+	if err := x.zw.Flush(); err != nil {
+		return err
+	}
+	flushOutput(x.out)
+	return nil
+}
+
+// flushOutput mengirim data ke client bila output mendukung flush (HTTP).
+func flushOutput(w io.Writer) {
+	if f, ok := w.(interface{ Flush() }); ok {
+		f.Flush()
+	}
+}
+
+func (x *xlsxWriter) Close() error {
+	x.buf.WriteString(`</sheetData>`)
+	if x.cols > 0 && x.rows > 1 {
+		fmt.Fprintf(x.buf, `<autoFilter ref="A1:%s%d"/>`, xlsxColumn(x.cols-1), x.rows)
+	}
+	if _, err := x.buf.WriteString(`</worksheet>`); err != nil {
+		return err
+	}
+	if err := x.buf.Flush(); err != nil {
+		return err
+	}
+	return x.zw.Close()
+}
+
+// xlsxColumn mengubah indeks kolom (0) menjadi huruf kolom (A).
+func xlsxColumn(i int) string {
+	name := ""
+	for i >= 0 {
+		name = string(rune('A'+i%26)) + name
+		i = i/26 - 1
+	}
+	return name
+}
+
+// xlsxSerial mengubah waktu menjadi nomor seri tanggal Excel (sejak
+// 1899-12-30). Zona waktu diabaikan supaya jam yang tampil sama dengan data.
+func xlsxSerial(t time.Time) string {
+	local := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	days := local.Sub(time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)).Hours() / 24
+	return strconv.FormatFloat(math.Round(days*100000)/100000, 'f', -1, 64)
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '&':
+			b.WriteString("&amp;")
+		case r == '<':
+			b.WriteString("&lt;")
+		case r == '>':
+			b.WriteString("&gt;")
+		case r == '"':
+			b.WriteString("&quot;")
+		case r == '\t' || r == '\n' || r == '\r' || r >= 0x20:
+			b.WriteRune(r)
+		}
+		// Karakter kontrol lain tidak valid di XML dan dibuang
+	}
+	return b.String()
+}
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+</Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`
+
+// Format angka dan tanggal mengikuti kebiasaan Indonesia. Pemisah ribuan
+// tetap mengikuti regional setting Excel pengguna.
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="3">
+<numFmt numFmtId="164" formatCode="&quot;Rp&quot;\ #,##0"/>
+<numFmt numFmtId="165" formatCode="dd/mm/yyyy"/>
+<numFmt numFmtId="166" formatCode="dd/mm/yyyy\ hh:mm"/>
+</numFmts>
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="6">
+<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>
+<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>
+<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="166" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="3" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+</cellXfs>
+<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>
+</styleSheet>`
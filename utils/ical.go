@@ -0,0 +1,338 @@
+package utils
+
+import (
+	"io"
+	"sort"
+	"ssb_api/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	icalLocalLayout = "20060102T150405"
+	icalUTCLayout   = "20060102T150405Z"
+	icalLineLimit   = 75 // panjang baris maksimal dalam oktet (RFC 5545)
+)
+
+// Calendar menyusun dokumen iCalendar (RFC 5545) untuk feed langganan.
+type Calendar struct {
+	Name   string
+	zones  map[string]*time.Location
+	events [][]string
+}
+
+// NewCalendar membuat kalender kosong dengan nama tampilan name.
+func NewCalendar(name string) *Calendar {
+	return &Calendar{Name: name, zones: map[string]*time.Location{}}
+}
+
+// icalEvent adalah satu VEVENT. RRule dan ExDates hanya untuk event induk
+// series, RecurrenceID untuk pertemuan series yang diubah sendiri.
+type icalEvent struct {
+	UID          string
+	Stamp        time.Time
+	Start, End   time.Time
+	Loc          *time.Location
+	Summary      string
+	Description  string
+	Location     string
+	Geo          string
+	Status       string
+	RRule        string
+	ExDates      []time.Time
+	RecurrenceID *time.Time
+}
+
+// Status VEVENT.
+const (
+	ICalConfirmed = "CONFIRMED"
+	ICalTentative = "TENTATIVE"
+	ICalCancelled = "CANCELLED"
+)
+
+// AddEvent menambahkan satu event. Status kosong berarti CONFIRMED, atau
+// CANCELLED bila event dibatalkan.
+func (cal *Calendar) AddEvent(e models.Event, loc *time.Location, status string) {
+	if e.StartAt == nil {
+		return
+	}
+	ev := eventFields(e, loc, status)
+	ev.UID = "event-" + strconv.FormatUint(uint64(e.ID), 10) + "@ssb"
+	cal.add(ev)
+}
+
+// AddSeries menambahkan series sebagai satu event berulang (RRULE).
+// occurrences adalah event pertemuan series termasuk yang sudah dihapus:
+// pertemuan yang dihapus, dijadikan privat, atau tanggal yang dibatalkan
+// tanpa event menjadi EXDATE, pertemuan yang diubah sendiri atau dibatalkan
+// ditulis sebagai RECURRENCE-ID. Feed ini publik, jadi detail pertemuan
+// privat tidak boleh ikut tertulis.
+func (cal *Calendar) AddSeries(series models.EventSeries, occurrences []models.Event, loc *time.Location) error {
+	r, err := SeriesRecurrence(series)
+	if err != nil {
+		return err
+	}
+	first := r.Between(r.Start, r.Start.AddDate(1, 0, 0))
+	if len(first) == 0 {
+		return nil
+	}
+	start, end, err := SeriesSchedule(series, first[0].Format(models.DateLayout), loc)
+	if err != nil {
+		return err
+	}
+	uid := "series-" + strconv.FormatUint(uint64(series.ID), 10) + "@ssb"
+
+	master := icalEvent{
+		UID:         uid,
+		Stamp:       series.UpdatedAt,
+		Start:       start,
+		End:         end,
+		Loc:         loc,
+		Summary:     series.Title,
+		Description: series.Description,
+		Location:    series.Location,
+		Geo:         icalGeo(series.LocationPoint),
+		Status:      ICalConfirmed,
+		RRule:       icalRRule(r, loc),
+	}
+
+	materialized := map[string]bool{}
+	var overrides []icalEvent
+	for _, o := range occurrences {
+		materialized[o.OccurrenceDate] = true
+		original, _, err := SeriesSchedule(series, o.OccurrenceDate, loc)
+		if err != nil {
+			continue
+		}
+		switch {
+		case o.DeletedAt.Valid, o.IsPrivate:
+			master.ExDates = append(master.ExDates, original)
+		case (o.IsCancelled || o.IsException) && o.StartAt != nil:
+			ev := eventFields(o, loc, "")
+			ev.UID = uid
+			ev.RecurrenceID = &original
+			overrides = append(overrides, ev)
+		}
+	}
+	for _, ex := range series.Exceptions {
+		if !ex.Cancelled || materialized[ex.Date] {
+			continue
+		}
+		if original, _, err := SeriesSchedule(series, ex.Date, loc); err == nil {
+			master.ExDates = append(master.ExDates, original)
+		}
+	}
+
+	cal.add(master)
+	for _, ev := range overrides {
+		cal.add(ev)
+	}
+	return nil
+}
+
+// WriteTo menulis kalender dengan baris CRLF yang dilipat per 75 oktet.
+func (cal *Calendar) WriteTo(w io.Writer) (int64, error) {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//SSB API//Kalender Akademi//ID",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:" + icalText(cal.Name),
+		"X-PUBLISHED-TTL:PT1H",
+		"REFRESH-INTERVAL;VALUE=DURATION:PT1H",
+	}
+
+	names := make([]string, 0, len(cal.zones))
+	for name := range cal.zones {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		lines = append(lines, icalTimezone(cal.zones[name])...)
+	}
+	for _, ev := range cal.events {
+		lines = append(lines, ev...)
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(icalFold(line))
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (cal *Calendar) add(ev icalEvent) {
+	cal.zones[ev.Loc.String()] = ev.Loc
+	tzid := ";TZID=" + ev.Loc.String() + ":"
+
+	lines := []string{
+		"BEGIN:VEVENT",
+		"UID:" + ev.UID,
+		"DTSTAMP:" + ev.Stamp.UTC().Format(icalUTCLayout),
+		"LAST-MODIFIED:" + ev.Stamp.UTC().Format(icalUTCLayout),
+	}
+	if ev.RecurrenceID != nil {
+		lines = append(lines, "RECURRENCE-ID"+tzid+ev.RecurrenceID.In(ev.Loc).Format(icalLocalLayout))
+	}
+	lines = append(lines,
+		"DTSTART"+tzid+ev.Start.In(ev.Loc).Format(icalLocalLayout),
+		"DTEND"+tzid+ev.End.In(ev.Loc).Format(icalLocalLayout),
+	)
+	if ev.RRule != "" {
+		lines = append(lines, "RRULE:"+ev.RRule)
+	}
+	for _, d := range ev.ExDates {
+		lines = append(lines, "EXDATE"+tzid+d.In(ev.Loc).Format(icalLocalLayout))
+	}
+	lines = append(lines, "SUMMARY:"+icalText(ev.Summary))
+	if ev.Description != "" {
+		lines = append(lines, "DESCRIPTION:"+icalText(ev.Description))
+	}
+	if ev.Location != "" {
+		lines = append(lines, "LOCATION:"+icalText(ev.Location))
+	}
+	if ev.Geo != "" {
+		lines = append(lines, "GEO:"+ev.Geo)
+	}
+	lines = append(lines, "STATUS:"+ev.Status, "END:VEVENT")
+	cal.events = append(cal.events, lines)
+}
+
+// eventFields mengisi field VEVENT dari event. Alasan pembatalan ditambahkan
+// ke deskripsi.
+func eventFields(e models.Event, loc *time.Location, status string) icalEvent {
+	end := e.StartAt.Add(models.DefaultEventDuration)
+	if e.EndAt != nil {
+		end = *e.EndAt
+	}
+	description := e.Description
+	if e.IsCancelled {
+		status = ICalCancelled
+		note := "Dibatalkan"
+		if e.CancelReason != "" {
+			note += ": " + e.CancelReason
+		}
+		description = strings.TrimSpace(note + "\n\n" + description)
+	}
+	if status == "" {
+		status = ICalConfirmed
+	}
+	return icalEvent{
+		Stamp:       e.UpdatedAt,
+		Start:       *e.StartAt,
+		End:         end,
+		Loc:         loc,
+		Summary:     e.Title,
+		Description: description,
+		Location:    e.Location,
+		Geo:         icalGeo(e.LocationPoint),
+		Status:      status,
+	}
+}
+
+// icalRRule menulis aturan series. UNTIL ditulis dalam UTC karena DTSTART
+// memakai TZID, dan series tanpa batas dibatasi seperti saat dibuat server.
+func icalRRule(r Recurrence, loc *time.Location) string {
+	rule := r
+	rule.Until, rule.Count = time.Time{}, 0
+	s := rule.String()
+
+	until := r.Until
+	if until.IsZero() && r.Count == 0 {
+		until = r.Start.AddDate(maxSeriesYears, 0, 0)
+	}
+	switch {
+	case r.Count > 0 && (until.IsZero() || len(r.Between(r.Start, until)) >= r.Count):
+		s += ";COUNT=" + strconv.Itoa(r.Count)
+	case !until.IsZero():
+		end := time.Date(until.Year(), until.Month(), until.Day(), 23, 59, 59, 0, loc)
+		s += ";UNTIL=" + end.UTC().Format(icalUTCLayout)
+	}
+	return s
+}
+
+// icalTimezone menulis VTIMEZONE dengan offset saat ini. Zona Indonesia
+// (WIB, WITA, WIT) tidak memakai DST sehingga satu komponen STANDARD cukup.
+func icalTimezone(loc *time.Location) []string {
+	name, offset := time.Now().In(loc).Zone()
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	tzOffset := sign + twoDigits(offset/3600) + twoDigits(offset%3600/60)
+	return []string{
+		"BEGIN:VTIMEZONE",
+		"TZID:" + loc.String(),
+		"BEGIN:STANDARD",
+		"DTSTART:19700101T000000",
+		"TZOFFSETFROM:" + tzOffset,
+		"TZOFFSETTO:" + tzOffset,
+		"TZNAME:" + name,
+		"END:STANDARD",
+		"END:VTIMEZONE",
+	}
+}
+
+// icalGeo mengubah LocationPoint "lat,lng" ke format GEO "lat;lng".
+func icalGeo(point string) string {
+	lat, lng, ok := ParseLocationPoint(point)
+	if !ok {
+		return ""
+	}
+	return strconv.FormatFloat(lat, 'f', 6, 64) + ";" + strconv.FormatFloat(lng, 'f', 6, 64)
+}
+
+// ParseLocationPoint membaca titik lokasi "lat,lng".
+func ParseLocationPoint(point string) (float64, float64, bool) {
+	latText, lngText, found := strings.Cut(point, ",")
+	if !found {
+		return 0, 0, false
+	}
+	lat, errLat := strconv.ParseFloat(strings.TrimSpace(latText), 64)
+	lng, errLng := strconv.ParseFloat(strings.TrimSpace(lngText), 64)
+	if errLat != nil || errLng != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		return 0, 0, false
+	}
+	return lat, lng, true
+}
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "")
+
+func icalText(s string) string {
+	return icalEscaper.Replace(s)
+}
+
+// icalFold memotong baris panjang tanpa memecah karakter UTF-8. Baris
+// lanjutan diawali spasi.
+func icalFold(line string) string {
+	var b strings.Builder
+	limit := icalLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = icalLineLimit - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+func twoDigits(n int) string {
+	if n < 10 {
+		return "0" + strconv.Itoa(n)
+	}
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"ssb_api/models"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// Format nomor invoice default: INV/ACADEMY/2026/000123
+const (
+	DefaultInvoiceFormat = "INV/{CODE}/{YYYY}/{SEQ}"
+	DefaultInvoiceDigits = 6
+
+	DefaultCreditNoteFormat = "CN/{CODE}/{YYYY}/{SEQ}"
+)
+
+var ErrInvoiceVendorRequired = errors.New("vendor is required to allocate an invoice number")
+
+// NextInvoiceNumber mengambil nomor urut invoice berikutnya untuk vendor pada
+// tahun tertentu. Harus dipanggil di dalam transaksi yang sama dengan insert
+// pembayaran: baris sequence terkunci sampai commit, sehingga request
+// bersamaan tidak mendapat nomor yang sama dan rollback tidak meninggalkan
+// nomor yang bolong.
+func NextInvoiceNumber(tx *gorm.DB, vendorID *uint, at time.Time) (string, error) {
+	vendor, seq, err := nextDocumentSequence(tx, "invoice_sequences", vendorID, at.Year())
+	if err != nil {
+		return "", err
+	}
+	return FormatInvoiceNumber(vendor.InvoiceFormat, InvoiceCode(vendor), at.Year(), seq, vendor.InvoiceDigits), nil
+}
+
+// NextCreditNoteNumber mengambil nomor nota kredit berikutnya. Urutannya
+// terpisah dari invoice supaya nomor invoice tetap berurutan.
+func NextCreditNoteNumber(tx *gorm.DB, vendorID *uint, at time.Time) (string, error) {
+	vendor, seq, err := nextDocumentSequence(tx, "credit_note_sequences", vendorID, at.Year())
+	if err != nil {
+		return "", err
+	}
+	return FormatInvoiceNumber(DefaultCreditNoteFormat, InvoiceCode(vendor), at.Year(), seq, vendor.InvoiceDigits), nil
+}
+
+func nextDocumentSequence(tx *gorm.DB, table string, vendorID *uint, year int) (models.Vendor, int, error) {
+	var vendor models.Vendor
+	if vendorID == nil || *vendorID == 0 {
+		return vendor, 0, ErrInvoiceVendorRequired
+	}
+	if err := tx.First(&vendor, *vendorID).Error; err != nil {
+		return vendor, 0, err
+	}
+
+	var seq int
+	err := tx.Raw(`INSERT INTO `+table+` (vendor_id, year, last_number, created_at, updated_at)
+		VALUES (?, ?, 1, NOW(), NOW())
+		ON CONFLICT (vendor_id, year)
+		DO UPDATE SET last_number = `+table+`.last_number + 1, updated_at = NOW()
+		RETURNING last_number`, vendor.ID, year).Scan(&seq).Error
+	return vendor, seq, err
+}
+
+// FormatInvoiceNumber mengganti placeholder {CODE}, {YYYY}, {YY} dan {SEQ}
+// pada format invoice vendor.
+func FormatInvoiceNumber(format, code string, year, seq, digits int) string {
+	if format == "" {
+		format = DefaultInvoiceFormat
+	}
+	if digits <= 0 {
+		digits = DefaultInvoiceDigits
+	}
+	return strings.NewReplacer(
+		"{CODE}", code,
+		"{YYYY}", fmt.Sprintf("%04d", year),
+		"{YY}", fmt.Sprintf("%02d", year%100),
+		"{SEQ}", fmt.Sprintf("%0*d", digits, seq),
+	).Replace(format)
+}
+
+// ValidateInvoiceFormat memastikan format tetap menghasilkan nomor unik.
+func ValidateInvoiceFormat(format string) error {
+	if format == "" {
+		return nil
+	}
+	if !strings.Contains(format, "{SEQ}") {
+		return errors.New("invoice format must contain {SEQ}")
+	}
+	if !strings.Contains(format, "{YYYY}") && !strings.Contains(format, "{YY}") {
+		return errors.New("invoice format must contain {YYYY} or {YY}")
+	}
+	return nil
+}
+
+// InvoiceCode adalah kode akademi di nomor invoice. Bila Vendor.InvoiceCode
+// kosong, diambil dari huruf dan angka pada nama vendor.
+func InvoiceCode(vendor models.Vendor) string {
+	if vendor.InvoiceCode != "" {
+		return vendor.InvoiceCode
+	}
+	var b strings.Builder
+	for _, r := range strings.ToUpper(vendor.Name) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+		}
+		if b.Len() == 10 {
+			break
+		}
+	}
+	if b.Len() == 0 {
+		return fmt.Sprintf("V%d", vendor.ID)
+	}
+	return b.String()
+}
@@ -19,11 +19,12 @@ func GenerateJWT(userID uint, email string) (string, error) {
 	return token.SignedString([]byte(secret))
 }
 
-func GenerateTokens(userID uint, email string) (string, string, error) {
+func GenerateTokens(userID uint, email, role string) (string, string, error) {
 	// Access token: 15 menit
 	accessClaims := jwt.MapClaims{
 		"user_id": userID,
 		"email":   email,
+		"role":    role,
 		"exp":     time.Now().Add(6 * time.Hour).Unix(),
 	}
 	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
@@ -0,0 +1,337 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"ssb_api/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrPaymentNotPayable = errors.New("payment cannot receive transactions in its current status")
+
+// CreatePaymentRecord menyimpan tagihan baru lengkap dengan harga dari aturan
+// harga vendor, nomor invoice dan riwayat status awalnya. Tagihan issued
+// langsung dipotong saldo kredit pemain, tagihan yang dibuat lunas dicatat
+// sebagai satu pembayaran penuh. Harus dipanggil di dalam transaksi.
+func CreatePaymentRecord(tx *gorm.DB, payment *models.Payment, actor *models.User, reason string) error {
+	if err := PricePayment(tx, payment); err != nil {
+		return err
+	}
+	invoice, err := NextInvoiceNumber(tx, payment.VendorID, time.Now())
+	if err != nil {
+		return err
+	}
+	payment.Invoice = invoice
+	if err := tx.Create(payment).Error; err != nil {
+		return err
+	}
+	if err := RecordPaymentStatus(tx, payment, actor, reason); err != nil {
+		return err
+	}
+
+	switch payment.Status {
+	case models.PaymentStatusPaid:
+		return settleOutstanding(tx, payment, reason)
+	case models.PaymentStatusIssued:
+		return ApplyAccountCredit(tx, payment)
+	}
+	return nil
+}
+
+// ApplyPaymentTransaction mengonfirmasi satu cicilan, menghitung ulang total
+// terbayar lalu memindahkan tagihan ke partially_paid atau paid. Kelebihan
+// bayar disimpan sebagai saldo kredit pemain.
+func ApplyPaymentTransaction(db *gorm.DB, payment *models.Payment, txn *models.PaymentTransaction, actor *models.User) error {
+	if payment.Outstanding() <= 0 || !models.CanTransitionPayment(payment.Status, models.PaymentStatusPaid) {
+		return ErrPaymentNotPayable
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		txn.PaymentID = payment.ID
+		txn.VendorID = payment.VendorID
+		txn.UserID = payment.UserID
+		txn.Status = models.PaymentTransactionConfirmed
+		if actor != nil {
+			txn.VerifiedByID = &actor.ID
+		}
+		if txn.Date == "" {
+			txn.Date = time.Now().Format("2006-01-02")
+		}
+		if err := tx.Save(txn).Error; err != nil {
+			return err
+		}
+
+		var paid float64
+		if err := tx.Model(&models.PaymentTransaction{}).
+			Where("payment_id = ? AND status = ?", payment.ID, models.PaymentTransactionConfirmed).
+			Select("COALESCE(SUM(amount), 0)").Scan(&paid).Error; err != nil {
+			return err
+		}
+
+		if excess := paid - payment.Amount; excess > 0 {
+			credit := models.AccountCredit{
+				UserID:        payment.UserID,
+				VendorID:      payment.VendorID,
+				Amount:        excess,
+				PaymentID:     &payment.ID,
+				TransactionID: &txn.ID,
+				Note:          "Kelebihan bayar " + payment.Invoice,
+			}
+			if err := tx.Create(&credit).Error; err != nil {
+				return err
+			}
+			paid = payment.Amount
+		}
+		if err := tx.Model(payment).Update("paid_amount", paid).Error; err != nil {
+			return err
+		}
+		payment.PaidAmount = paid
+
+		reason := fmt.Sprintf("%s %s via %s", transactionLabel(payment), FormatRupiah(txn.Amount), txn.Method)
+		switch {
+		case payment.Outstanding() <= 0:
+			return TransitionPayment(tx, payment, models.PaymentStatusPaid, actor, reason)
+		case payment.Status != models.PaymentStatusPartiallyPaid:
+			return TransitionPayment(tx, payment, models.PaymentStatusPartiallyPaid, actor, reason)
+		}
+		return nil
+	})
+}
+
+func transactionLabel(payment *models.Payment) string {
+	if payment.Outstanding() <= 0 {
+		return "Pelunasan"
+	}
+	return "Cicilan"
+}
+
+// settleOutstanding mencatat sisa tagihan sebagai satu pembayaran saat
+// tagihan ditandai lunas tanpa lewat cicilan (verifikasi bukti, gateway,
+// tunai).
+func settleOutstanding(tx *gorm.DB, payment *models.Payment, note string) error {
+	remaining := payment.Outstanding()
+	if remaining <= 0 {
+		return nil
+	}
+	txn := models.PaymentTransaction{
+		PaymentID: payment.ID,
+		VendorID:  payment.VendorID,
+		UserID:    payment.UserID,
+		Amount:    remaining,
+		Method:    payment.Method,
+		Date:      time.Now().Format("2006-01-02"),
+		Photo:     payment.Photo,
+		Note:      note,
+		Status:    models.PaymentTransactionConfirmed,
+	}
+	if err := tx.Create(&txn).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(payment).Update("paid_amount", payment.Amount).Error; err != nil {
+		return err
+	}
+	payment.PaidAmount = payment.Amount
+	return nil
+}
+
+// CreditBalance adalah saldo kredit pemain di satu vendor.
+func CreditBalance(db *gorm.DB, userID uint, vendorID *uint) (float64, error) {
+	var balance float64
+	err := db.Model(&models.AccountCredit{}).
+		Where("user_id = ? AND vendor_id = ?", userID, vendorID).
+		Select("COALESCE(SUM(amount), 0)").Scan(&balance).Error
+	return balance, err
+}
+
+// ApplyAccountCredit memakai saldo kredit pemain untuk membayar tagihan.
+func ApplyAccountCredit(tx *gorm.DB, payment *models.Payment) error {
+	if payment.VendorID == nil || payment.Outstanding() <= 0 {
+		return nil
+	}
+	balance, err := CreditBalance(tx, payment.UserID, payment.VendorID)
+	if err != nil || balance <= 0 {
+		return err
+	}
+
+	use := balance
+	if use > payment.Outstanding() {
+		use = payment.Outstanding()
+	}
+	used := models.AccountCredit{
+		UserID:    payment.UserID,
+		VendorID:  payment.VendorID,
+		Amount:    -use,
+		PaymentID: &payment.ID,
+		Note:      "Dipakai untuk " + payment.Invoice,
+	}
+	if err := tx.Create(&used).Error; err != nil {
+		return err
+	}
+
+	txn := models.PaymentTransaction{
+		Amount: use,
+		Method: models.PaymentMethodCredit,
+		Note:   "Saldo kredit",
+	}
+	return ApplyPaymentTransaction(tx, payment, &txn, nil)
+}
+
+// StatementEntry adalah satu baris rekening koran pemain.
+type StatementEntry struct {
+	Date        string  `json:"date"`
+	Kind        string  `json:"kind"` // charge, payment, credit_note, refund
+	PaymentID   uint    `json:"payment_id"`
+	Invoice     string  `json:"invoice"`
+	Description string  `json:"description"`
+	Debit       float64 `json:"debit"`  // tagihan
+	Credit      float64 `json:"credit"` // pembayaran
+	Balance     float64 `json:"balance"`
+}
+
+type AccountStatement struct {
+	UserID         uint             `json:"user_id"`
+	VendorID       *uint            `json:"vendor_id"`
+	StartDate      string           `json:"start_date"`
+	EndDate        string           `json:"end_date"`
+	OpeningBalance float64          `json:"opening_balance"`
+	Entries        []StatementEntry `json:"entries"`
+	ClosingBalance float64          `json:"closing_balance"` // positif = utang, negatif = lebih bayar
+	Outstanding    float64          `json:"outstanding"`
+	CreditBalance  float64          `json:"credit_balance"`
+}
+
+// BuildAccountStatement menyusun rekening koran pemain: semua tagihan,
+// pembayaran dan saldo berjalan. Pembayaran dari saldo kredit tidak dihitung
+// ulang karena kelebihan bayarnya sudah tercatat di pembayaran asal.
+// startDate dan endDate (YYYY-MM-DD) boleh kosong.
+func BuildAccountStatement(db *gorm.DB, userID uint, vendorID *uint, startDate, endDate string) (AccountStatement, error) {
+	statement := AccountStatement{UserID: userID, VendorID: vendorID, StartDate: startDate, EndDate: endDate, Entries: []StatementEntry{}}
+
+	paymentQuery := db.Where("user_id = ? AND status NOT IN ?", userID,
+		[]string{models.PaymentStatusDraft, models.PaymentStatusCancelled})
+	if vendorID != nil {
+		paymentQuery = paymentQuery.Where("vendor_id = ?", *vendorID)
+	}
+	var payments []models.Payment
+	if err := paymentQuery.Find(&payments).Error; err != nil {
+		return statement, err
+	}
+
+	invoices := map[uint]string{}
+	var entries []StatementEntry
+	for _, p := range payments {
+		invoices[p.ID] = p.Invoice
+		date := p.Date.String()
+		if date == "" {
+			date = p.CreatedAt.Format("2006-01-02")
+		}
+		entries = append(entries, StatementEntry{
+			Date:        date,
+			Kind:        "charge",
+			PaymentID:   p.ID,
+			Invoice:     p.Invoice,
+			Description: paymentDescription(p, nil),
+			Debit:       p.Amount,
+		})
+		statement.Outstanding += p.Outstanding()
+	}
+
+	txnQuery := db.Where("user_id = ? AND status = ? AND method <> ?", userID,
+		models.PaymentTransactionConfirmed, models.PaymentMethodCredit)
+	if vendorID != nil {
+		txnQuery = txnQuery.Where("vendor_id = ?", *vendorID)
+	}
+	var txns []models.PaymentTransaction
+	if err := txnQuery.Find(&txns).Error; err != nil {
+		return statement, err
+	}
+	for _, t := range txns {
+		invoice, ok := invoices[t.PaymentID]
+		if !ok {
+			// Tagihan sudah dibatalkan
+			continue
+		}
+		entries = append(entries, StatementEntry{
+			Date:        t.Date,
+			Kind:        "payment",
+			PaymentID:   t.PaymentID,
+			Invoice:     invoice,
+			Description: "Pembayaran " + t.Method,
+			Credit:      t.Amount,
+		})
+	}
+
+	// Nota kredit mengurangi tagihan, pengembalian dana menambah lagi. Refund
+	// ke saldo kredit tidak punya baris pengembalian karena dananya tetap di
+	// akun pemain.
+	var refunds []models.Refund
+	refundQuery := db.Where("user_id = ? AND status IN ?", userID, issuedRefundStatuses)
+	if vendorID != nil {
+		refundQuery = refundQuery.Where("vendor_id = ?", *vendorID)
+	}
+	if err := refundQuery.Find(&refunds).Error; err != nil {
+		return statement, err
+	}
+	for _, r := range refunds {
+		invoice, ok := invoices[r.PaymentID]
+		if !ok || r.ApprovedAt == nil {
+			continue
+		}
+		entries = append(entries, StatementEntry{
+			Date:        r.ApprovedAt.Format("2006-01-02"),
+			Kind:        "credit_note",
+			PaymentID:   r.PaymentID,
+			Invoice:     invoice,
+			Description: "Nota kredit " + r.CreditNoteNumber,
+			Credit:      r.Amount,
+		})
+		if r.Status == models.RefundStatusPaidOut && r.PayoutMethod != models.RefundPayoutCredit && r.PaidOutAt != nil {
+			entries = append(entries, StatementEntry{
+				Date:        r.PaidOutAt.Format("2006-01-02"),
+				Kind:        "refund",
+				PaymentID:   r.PaymentID,
+				Invoice:     invoice,
+				Description: "Pengembalian dana " + r.CreditNoteNumber,
+				Debit:       r.Amount,
+			})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Date != entries[j].Date {
+			return entries[i].Date < entries[j].Date
+		}
+		// Tagihan dulu baru pembayarannya di hari yang sama
+		return entries[i].Kind == "charge" && entries[j].Kind != "charge"
+	})
+
+	balance := 0.0
+	for _, e := range entries {
+		balance += e.Debit - e.Credit
+		e.Balance = balance
+		switch {
+		case startDate != "" && e.Date < startDate:
+			statement.OpeningBalance = balance
+		case endDate != "" && e.Date > endDate:
+			continue
+		default:
+			statement.Entries = append(statement.Entries, e)
+			statement.ClosingBalance = balance
+		}
+	}
+	if len(statement.Entries) == 0 {
+		statement.ClosingBalance = statement.OpeningBalance
+	}
+
+	if vendorID != nil {
+		credit, err := CreditBalance(db, userID, vendorID)
+		if err != nil {
+			return statement, err
+		}
+		statement.CreditBalance = credit
+	}
+	return statement, nil
+}
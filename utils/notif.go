@@ -45,6 +45,13 @@ func SendFCMNotification(token, title, body string) error {
 }
 
 func CreateNotification(userID uint, token, title, body, notifType string) error {
+	_, err := SendNotification(userID, token, title, body, notifType)
+	return err
+}
+
+// SendNotification mencatat notifikasi lalu mengirimkannya lewat FCM bila
+// user punya device token. Notifikasi tetap tersimpan walau FCM gagal.
+func SendNotification(userID uint, token, title, body, notifType string) (models.Notification, error) {
 	notif := models.Notification{
 		UserID: userID,
 		Title:  title,
@@ -54,7 +61,10 @@ func CreateNotification(userID uint, token, title, body, notifType string) error
 	}
 
 	if err := config.DB.Create(&notif).Error; err != nil {
-		return err
+		return notif, err
+	}
+	if token == "" {
+		return notif, nil
 	}
 
 	if err := SendFCMNotification(token, title, body); err == nil {
@@ -63,9 +73,11 @@ func CreateNotification(userID uint, token, title, body, notifType string) error
 			"is_sent": true,
 			"sent_at": &now,
 		})
+		notif.IsSent = true
+		notif.SentAt = &now
 	} else {
 		fmt.Println("Gagal kirim FCM:", err.Error())
 	}
 
-	return nil
+	return notif, nil
 }
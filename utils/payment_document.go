@@ -0,0 +1,361 @@
+package utils
+
+import (
+	"fmt"
+	"ssb_api/models"
+	"strings"
+	"time"
+)
+
+const (
+	PaymentDocumentInvoice = "invoice"
+	PaymentDocumentReceipt = "receipt"
+)
+
+// PaymentDocument adalah data yang dicetak di invoice atau kwitansi.
+type PaymentDocument struct {
+	Kind    string // invoice atau receipt
+	Payment models.Payment
+	Vendor  models.Vendor
+	Player  models.User
+	Event   *models.Event
+	PaidAt  *time.Time
+}
+
+var paymentStatusLabels = map[string]string{
+	models.PaymentStatusDraft:                "DRAFT",
+	models.PaymentStatusIssued:               "BELUM DIBAYAR",
+	models.PaymentStatusAwaitingVerification: "MENUNGGU VERIFIKASI",
+	models.PaymentStatusPartiallyPaid:        "DIBAYAR SEBAGIAN",
+	models.PaymentStatusPaid:                 "LUNAS",
+	models.PaymentStatusRejected:             "DITOLAK",
+	models.PaymentStatusRefunded:             "DIKEMBALIKAN",
+	models.PaymentStatusCancelled:            "DIBATALKAN",
+	models.PaymentStatusOverdue:              "JATUH TEMPO",
+}
+
+// RenderPaymentPDF membuat invoice atau kwitansi pembayaran dalam format PDF.
+func RenderPaymentPDF(doc PaymentDocument) []byte {
+	const (
+		left  = 50.0
+		right = PDFPageWidth - 50
+	)
+	pdf := NewPDF()
+	payment := doc.Payment
+
+	title := "INVOICE"
+	if doc.Kind == PaymentDocumentReceipt {
+		title = "KWITANSI"
+	}
+	drawDocumentHeader(pdf, doc.Vendor, title, payment.Invoice)
+
+	// Data tagihan
+	pdf.Text(left, 140, 9, true, "DITAGIHKAN KEPADA")
+	pdf.Text(left, 156, 11, true, doc.Player.Name)
+	y := 170.0
+	for _, line := range []string{doc.Player.Email, doc.Player.Phone} {
+		if line == "" {
+			continue
+		}
+		pdf.Text(left, y, 9, false, line)
+		y += 12
+	}
+
+	meta := [][2]string{
+		{"Tanggal", FormatDateID(payment.Date.String())},
+		{"Jatuh Tempo", FormatDateID(payment.DueDate.String())},
+		{"Status", paymentStatusLabel(payment.Status)},
+	}
+	if doc.Kind == PaymentDocumentReceipt && doc.PaidAt != nil {
+		meta = append(meta, [2]string{"Dibayar", FormatDateID(doc.PaidAt.Format("2006-01-02"))})
+	}
+	y = 140
+	for _, row := range meta {
+		pdf.Text(340, y, 9, true, row[0])
+		pdf.TextRight(right, y, 9, false, row[1])
+		y += 16
+	}
+
+	// Rincian
+	y = 240
+	pdf.SetColor(235, 240, 248)
+	pdf.Rect(left, y, right-left, 22, 0, true)
+	pdf.SetColor(20, 20, 20)
+	pdf.Text(left+8, y+15, 10, true, "Keterangan")
+	pdf.TextRight(right-8, y+15, 10, true, "Jumlah")
+
+	y += 40
+	pdf.Text(left+8, y, 10, false, paymentDescription(payment, doc.Event))
+	if len(payment.LineItems) > 0 {
+		// Harga dasar lalu setiap potongan
+		pdf.TextRight(right-8, y, 10, false, FormatRupiah(payment.GrossAmount))
+		for _, item := range payment.LineItems {
+			if item.Kind != models.LineItemDiscount {
+				continue
+			}
+			y += 14
+			pdf.SetColor(100, 100, 100)
+			pdf.Text(left+20, y, 9, false, truncateText(item.Description, 70))
+			pdf.TextRight(right-8, y, 9, false, "-"+FormatRupiah(-item.Amount))
+			pdf.SetColor(20, 20, 20)
+		}
+	} else {
+		pdf.TextRight(right-8, y, 10, false, FormatRupiah(payment.Amount))
+	}
+	if payment.Note != "" {
+		y += 14
+		pdf.SetColor(100, 100, 100)
+		pdf.Text(left+8, y, 8, false, truncateText(payment.Note, 100))
+		pdf.SetColor(20, 20, 20)
+	}
+
+	y += 18
+	pdf.SetStrokeColor(200, 200, 200)
+	pdf.Line(left, y, right, y, 0.5)
+	y += 22
+	pdf.Text(340, y, 11, true, "Total")
+	pdf.TextRight(right-8, y, 12, true, FormatRupiah(payment.Amount))
+	if payment.PaidAmount > 0 && payment.Outstanding() > 0 {
+		y += 18
+		pdf.Text(340, y, 10, false, "Sudah dibayar")
+		pdf.TextRight(right-8, y, 10, false, FormatRupiah(payment.PaidAmount))
+		y += 16
+		pdf.Text(340, y, 10, true, "Sisa tagihan")
+		pdf.TextRight(right-8, y, 10, true, FormatRupiah(payment.Outstanding()))
+	}
+
+	// Instruksi pembayaran di invoice, cap lunas di kwitansi
+	y += 50
+	if doc.Kind == PaymentDocumentReceipt {
+		pdf.SetColor(20, 20, 20)
+		pdf.Text(left, y, 10, false, fmt.Sprintf("Telah diterima dari %s pembayaran sebesar %s.", doc.Player.Name, FormatRupiah(payment.Amount)))
+		if payment.Method != "" {
+			pdf.Text(left, y+14, 9, false, "Metode pembayaran: "+payment.Method)
+		}
+		if payment.Status == models.PaymentStatusPaid {
+			pdf.SetStrokeColor(200, 40, 40)
+			pdf.SetColor(200, 40, 40)
+			pdf.Rect(380, y+30, 140, 48, 3, false)
+			pdf.Text(398, y+64, 28, true, "LUNAS")
+		}
+	} else if doc.Vendor.BankName != "" || doc.Vendor.BankAccount != "" {
+		pdf.Text(left, y, 10, true, "Pembayaran dapat ditransfer ke:")
+		pdf.Text(left, y+16, 10, false, joinNonEmpty(" - ", doc.Vendor.BankName, doc.Vendor.BankAccount))
+		if doc.Vendor.BankHolder != "" {
+			pdf.Text(left, y+30, 10, false, "a.n. "+doc.Vendor.BankHolder)
+		}
+		pdf.SetColor(100, 100, 100)
+		pdf.Text(left, y+48, 8, false, "Cantumkan nomor invoice pada berita transfer.")
+		pdf.Text(left, y+60, 8, false, fmt.Sprintf("Atau transfer tepat %s (termasuk kode unik) agar terverifikasi otomatis.",
+			FormatRupiah(payment.Outstanding()+float64(PaymentUniqueCode(payment)))))
+	}
+
+	pdf.SetColor(130, 130, 130)
+	pdf.Text(left, PDFPageHeight-40, 8, false,
+		fmt.Sprintf("Dokumen ini dibuat otomatis pada %s.", FormatDateID(time.Now().Format("2006-01-02"))))
+
+	return pdf.Bytes()
+}
+
+// drawDocumentHeader mencetak kop dokumen: logo, identitas akademi, judul dan
+// nomor dokumen.
+func drawDocumentHeader(pdf *PDF, vendor models.Vendor, title, number string) {
+	const (
+		left  = 50.0
+		right = PDFPageWidth - 50
+	)
+	textLeft := left
+	if vendor.Photo != "" {
+		if err := pdf.ImageFile(vendor.Photo, left, 40, 60, 60); err == nil {
+			textLeft = left + 72
+		}
+	}
+	pdf.SetColor(20, 20, 20)
+	pdf.Text(textLeft, 60, 16, true, vendor.Name)
+	y := 76.0
+	for _, line := range []string{vendor.Address, joinNonEmpty(" | ", vendor.Phone, vendor.Email)} {
+		if line == "" {
+			continue
+		}
+		pdf.Text(textLeft, y, 9, false, line)
+		y += 12
+	}
+
+	pdf.SetColor(30, 90, 160)
+	pdf.TextRight(right, 62, 22, true, title)
+	pdf.SetColor(20, 20, 20)
+	pdf.TextRight(right, 80, 10, false, number)
+
+	pdf.SetStrokeColor(30, 90, 160)
+	pdf.Line(left, 112, right, 112, 1.5)
+}
+
+// CreditNoteDocument adalah data yang dicetak di nota kredit refund.
+type CreditNoteDocument struct {
+	Refund  models.Refund
+	Payment models.Payment
+	Vendor  models.Vendor
+	Player  models.User
+	Event   *models.Event
+}
+
+var refundPayoutLabels = map[string]string{
+	models.RefundPayoutManual:   "Transfer/tunai",
+	models.RefundPayoutProvider: "Payment gateway",
+	models.RefundPayoutCredit:   "Saldo kredit",
+}
+
+// RenderCreditNotePDF membuat nota kredit untuk refund yang sudah disetujui.
+func RenderCreditNotePDF(doc CreditNoteDocument) []byte {
+	const (
+		left  = 50.0
+		right = PDFPageWidth - 50
+	)
+	pdf := NewPDF()
+	refund := doc.Refund
+	drawDocumentHeader(pdf, doc.Vendor, "NOTA KREDIT", refund.CreditNoteNumber)
+
+	pdf.Text(left, 140, 9, true, "DIBERIKAN KEPADA")
+	pdf.Text(left, 156, 11, true, doc.Player.Name)
+	y := 170.0
+	for _, line := range []string{doc.Player.Email, doc.Player.Phone} {
+		if line == "" {
+			continue
+		}
+		pdf.Text(left, y, 9, false, line)
+		y += 12
+	}
+
+	meta := [][2]string{{"Referensi Invoice", doc.Payment.Invoice}}
+	if refund.ApprovedAt != nil {
+		meta = append(meta, [2]string{"Tanggal", FormatDateID(refund.ApprovedAt.Format("2006-01-02"))})
+	}
+	if label, ok := refundPayoutLabels[refund.PayoutMethod]; ok {
+		meta = append(meta, [2]string{"Pengembalian", label})
+	}
+	if refund.PaidOutAt != nil {
+		meta = append(meta, [2]string{"Dikembalikan", FormatDateID(refund.PaidOutAt.Format("2006-01-02"))})
+	}
+	y = 140
+	for _, row := range meta {
+		pdf.Text(340, y, 9, true, row[0])
+		pdf.TextRight(right, y, 9, false, row[1])
+		y += 16
+	}
+
+	y = 240
+	pdf.SetColor(235, 240, 248)
+	pdf.Rect(left, y, right-left, 22, 0, true)
+	pdf.SetColor(20, 20, 20)
+	pdf.Text(left+8, y+15, 10, true, "Keterangan")
+	pdf.TextRight(right-8, y+15, 10, true, "Jumlah")
+
+	y += 40
+	pdf.Text(left+8, y, 10, false, "Refund "+paymentDescription(doc.Payment, doc.Event))
+	pdf.TextRight(right-8, y, 10, false, FormatRupiah(refund.Amount))
+	if refund.Reason != "" {
+		y += 14
+		pdf.SetColor(100, 100, 100)
+		pdf.Text(left+8, y, 8, false, truncateText("Alasan: "+refund.Reason, 100))
+		pdf.SetColor(20, 20, 20)
+	}
+
+	y += 18
+	pdf.SetStrokeColor(200, 200, 200)
+	pdf.Line(left, y, right, y, 0.5)
+	y += 22
+	pdf.Text(340, y, 10, false, "Nilai invoice")
+	pdf.TextRight(right-8, y, 10, false, FormatRupiah(doc.Payment.Amount))
+	y += 18
+	pdf.Text(340, y, 11, true, "Total kredit")
+	pdf.TextRight(right-8, y, 12, true, FormatRupiah(refund.Amount))
+
+	pdf.SetColor(130, 130, 130)
+	pdf.Text(left, PDFPageHeight-40, 8, false,
+		fmt.Sprintf("Dokumen ini dibuat otomatis pada %s.", FormatDateID(time.Now().Format("2006-01-02"))))
+
+	return pdf.Bytes()
+}
+
+func paymentDescription(payment models.Payment, event *models.Event) string {
+	var desc string
+	switch {
+	case event != nil:
+		desc = "Biaya " + event.Title
+	case payment.Type == PaymentTypeMembership:
+		desc = "Iuran keanggotaan"
+	case payment.Type != "":
+		desc = "Pembayaran " + payment.Type
+	default:
+		desc = "Pembayaran"
+	}
+	if payment.BillingPeriod != "" {
+		if period, err := time.Parse("2006-01", payment.BillingPeriod); err == nil {
+			desc += fmt.Sprintf(" (%s %d)", indonesianMonths[period.Month()-1], period.Year())
+		}
+	}
+	return truncateText(desc, 70)
+}
+
+func paymentStatusLabel(status string) string {
+	if label, ok := paymentStatusLabels[models.NormalizePaymentStatus(status)]; ok {
+		return label
+	}
+	return strings.ToUpper(status)
+}
+
+var indonesianMonths = [12]string{
+	"Januari", "Februari", "Maret", "April", "Mei", "Juni",
+	"Juli", "Agustus", "September", "Oktober", "November", "Desember",
+}
+
+// FormatDateID mengubah tanggal YYYY-MM-DD menjadi "2 Januari 2026". Tanggal
+// yang tidak bisa dibaca dikembalikan apa adanya, kosong menjadi "-".
+func FormatDateID(date string) string {
+	if date == "" {
+		return "-"
+	}
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return fmt.Sprintf("%d %s %d", t.Day(), indonesianMonths[t.Month()-1], t.Year())
+}
+
+// FormatRupiah memformat angka sebagai "Rp 1.500.000".
+func FormatRupiah(amount float64) string {
+	negative := amount < 0
+	if negative {
+		amount = -amount
+	}
+	digits := fmt.Sprintf("%.0f", amount)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(d)
+	}
+	if negative {
+		return "-Rp " + b.String()
+	}
+	return "Rp " + b.String()
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	var kept []string
+	for _, p := range parts {
+		if strings.TrimSpace(p) != "" {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, sep)
+}
+
+func truncateText(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-3]) + "..."
+}
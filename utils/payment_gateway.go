@@ -0,0 +1,245 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"ssb_api/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Channel pembayaran yang didukung provider.
+const (
+	ChannelSnap = "snap" // halaman pembayaran hosted
+	ChannelVA   = "va"   // virtual account
+	ChannelQRIS = "qris"
+)
+
+// Status hasil webhook dari provider.
+const (
+	GatewayStatusPaid    = "paid"
+	GatewayStatusFailed  = "failed"
+	GatewayStatusExpired = "expired"
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+type CheckoutRequest struct {
+	Payment  models.Payment
+	Channel  string
+	Customer models.User
+}
+
+type Checkout struct {
+	Reference string
+	Channel   string
+	PayURL    string
+	VANumber  string
+	QRString  string
+	ExpiresAt time.Time
+}
+
+type WebhookEvent struct {
+	Reference string    `json:"reference"`
+	Status    string    `json:"status"`
+	Amount    float64   `json:"amount"`
+	PaidAt    time.Time `json:"paid_at"`
+}
+
+// PaymentProvider adalah kontrak untuk payment gateway (Midtrans, Xendit,
+// dsb). Provider baru cukup mengimplementasikan interface ini lalu didaftarkan
+// lewat RegisterPaymentProvider.
+type PaymentProvider interface {
+	Name() string
+	CreateCheckout(req CheckoutRequest) (Checkout, error)
+	// ParseWebhook memverifikasi signature lalu membaca isi callback.
+	ParseWebhook(header http.Header, body []byte) (WebhookEvent, error)
+}
+
+type RefundRequest struct {
+	Reference      string // reference checkout pembayaran asal
+	Amount         float64
+	Reason         string
+	IdempotencyKey string // sama untuk percobaan ulang refund yang sama
+}
+
+type RefundResult struct {
+	Reference string
+	Completed bool // false berarti masih diproses provider
+}
+
+// RefundProvider diimplementasikan provider yang mendukung pengembalian dana
+// lewat gateway. Provider tanpa refund cukup dibayar manual.
+type RefundProvider interface {
+	Refund(req RefundRequest) (RefundResult, error)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]PaymentProvider{}
+)
+
+func RegisterPaymentProvider(p PaymentProvider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[p.Name()] = p
+}
+
+func GetPaymentProvider(name string) (PaymentProvider, bool) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	p, ok := providers[name]
+	return p, ok
+}
+
+// DefaultPaymentProvider adalah provider dari env PAYMENT_PROVIDER. Bila
+// kosong, provider fake dipakai hanya kalau diaktifkan lewat
+// PAYMENT_FAKE_ENABLED.
+func DefaultPaymentProvider() (PaymentProvider, bool) {
+	name := os.Getenv("PAYMENT_PROVIDER")
+	if name == "" {
+		if !FakePaymentEnabled() {
+			return nil, false
+		}
+		name = FakeProviderName
+	}
+	return GetPaymentProvider(name)
+}
+
+// FakePaymentEnabled bernilai true bila PAYMENT_FAKE_ENABLED=true. Hanya
+// untuk dev/test: checkout fake bisa dilunasi tanpa membayar.
+func FakePaymentEnabled() bool {
+	return os.Getenv("PAYMENT_FAKE_ENABLED") == "true"
+}
+
+// InitPaymentGateway dipanggil sekali setelah .env dimuat. Provider fake
+// hanya didaftarkan bila diaktifkan, dan provider sungguhan wajib punya
+// PAYMENT_WEBHOOK_SECRET.
+func InitPaymentGateway() error {
+	if FakePaymentEnabled() {
+		secret := os.Getenv("PAYMENT_WEBHOOK_SECRET")
+		if secret == "" {
+			// Secret acak per proses, webhook fake hanya dikirim server sendiri
+			b := make([]byte, 32)
+			if _, err := rand.Read(b); err != nil {
+				return err
+			}
+			secret = hex.EncodeToString(b)
+		}
+		RegisterPaymentProvider(&FakeProvider{Secret: secret})
+	}
+
+	name := os.Getenv("PAYMENT_PROVIDER")
+	if name != "" && name != FakeProviderName && os.Getenv("PAYMENT_WEBHOOK_SECRET") == "" {
+		return fmt.Errorf("PAYMENT_WEBHOOK_SECRET is required for payment provider %q", name)
+	}
+	return nil
+}
+
+// SignWebhook menghasilkan signature HMAC-SHA256 (hex) dari body callback.
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature membandingkan signature secara constant-time.
+func VerifyWebhookSignature(secret string, body []byte, signature string) bool {
+	if secret == "" || signature == "" {
+		return false
+	}
+	expected := SignWebhook(secret, body)
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
+
+func newGatewayReference(prefix string) string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return fmt.Sprintf("%s-%d-%s", prefix, time.Now().Unix(), hex.EncodeToString(b))
+}
+
+const FakeProviderName = "fake"
+
+// FakeProvider meniru alur Snap/VA/QRIS tanpa gateway sungguhan supaya alur
+// pembayaran bisa dites di lokal. Pembayaran disimulasikan lewat endpoint
+// /api/payment/gateway/fake/:reference/pay yang mengirim webhook bertanda
+// tangan ke server sendiri. Hanya aktif bila PAYMENT_FAKE_ENABLED=true.
+type FakeProvider struct {
+	Secret  string
+	BaseURL string
+}
+
+// secret dan baseURL dibaca saat dipakai karena .env baru dimuat setelah init.
+// Tanpa secret semua webhook ditolak.
+func (p *FakeProvider) secret() string {
+	if p.Secret != "" {
+		return p.Secret
+	}
+	return os.Getenv("PAYMENT_WEBHOOK_SECRET")
+}
+
+func (p *FakeProvider) baseURL() string {
+	if p.BaseURL != "" {
+		return p.BaseURL
+	}
+	return strings.TrimRight(os.Getenv("BASE_URL_F"), "/")
+}
+
+func (p *FakeProvider) Name() string { return FakeProviderName }
+
+func (p *FakeProvider) CreateCheckout(req CheckoutRequest) (Checkout, error) {
+	checkout := Checkout{
+		Reference: newGatewayReference("FAKE"),
+		Channel:   req.Channel,
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+	}
+	switch req.Channel {
+	case ChannelSnap:
+		checkout.PayURL = fmt.Sprintf("%s/api/payment/gateway/fake/%s/pay", p.baseURL(), checkout.Reference)
+	case ChannelVA:
+		checkout.VANumber = fmt.Sprintf("8808%012d", req.Payment.ID)
+	case ChannelQRIS:
+		checkout.QRString = fmt.Sprintf("FAKEQRIS|%s|%.0f", checkout.Reference, req.Payment.Amount)
+	default:
+		return Checkout{}, fmt.Errorf("unsupported channel %q", req.Channel)
+	}
+	return checkout, nil
+}
+
+func (p *FakeProvider) ParseWebhook(header http.Header, body []byte) (WebhookEvent, error) {
+	if !VerifyWebhookSignature(p.secret(), body, header.Get("X-Signature")) {
+		return WebhookEvent{}, ErrInvalidSignature
+	}
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return WebhookEvent{}, err
+	}
+	return event, nil
+}
+
+func (p *FakeProvider) Refund(req RefundRequest) (RefundResult, error) {
+	if req.Reference == "" || req.Amount <= 0 {
+		return RefundResult{}, errors.New("invalid refund request")
+	}
+	return RefundResult{Reference: newGatewayReference("FAKERF"), Completed: true}, nil
+}
+
+// SimulatePayment membuat body webhook dan signature seolah-olah dikirim
+// gateway untuk reference tertentu.
+func (p *FakeProvider) SimulatePayment(reference string, amount float64) ([]byte, string) {
+	body, _ := json.Marshal(WebhookEvent{
+		Reference: reference,
+		Status:    GatewayStatusPaid,
+		Amount:    amount,
+		PaidAt:    time.Now(),
+	})
+	return body, SignWebhook(p.secret(), body)
+}
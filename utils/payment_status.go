@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"fmt"
+	"ssb_api/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidPaymentTransition dikembalikan saat perpindahan status tidak diizinkan.
+type ErrInvalidPaymentTransition struct {
+	From string
+	To   string
+}
+
+func (e ErrInvalidPaymentTransition) Error() string {
+	return fmt.Sprintf("cannot change payment status from %s to %s", e.From, e.To)
+}
+
+// RecordPaymentStatus mencatat status awal pembayaran yang baru dibuat.
+func RecordPaymentStatus(db *gorm.DB, payment *models.Payment, actor *models.User, reason string) error {
+	return db.Create(newPaymentStatusHistory(payment, "", payment.Status, actor, reason)).Error
+}
+
+// TransitionPayment memindahkan status pembayaran sesuai PaymentTransitions
+// dan mencatatnya di PaymentStatusHistory dalam satu transaksi. actor nil
+// berarti perubahan dilakukan sistem. Perpindahan ke paid mencatat sisa
+// tagihan sebagai PaymentTransaction.
+func TransitionPayment(db *gorm.DB, payment *models.Payment, to string, actor *models.User, reason string) error {
+	from := models.NormalizePaymentStatus(payment.Status)
+	to = models.NormalizePaymentStatus(to)
+	if !models.CanTransitionPayment(from, to) {
+		return ErrInvalidPaymentTransition{From: from, To: to}
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Kunci perpindahan pada status lama supaya dua request bersamaan
+		// tidak sama-sama berhasil
+		result := tx.Model(&models.Payment{}).
+			Where("id = ? AND status = ?", payment.ID, payment.Status).
+			Update("status", to)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidPaymentTransition{From: from, To: to}
+		}
+
+		if err := tx.Create(newPaymentStatusHistory(payment, from, to, actor, reason)).Error; err != nil {
+			return err
+		}
+
+		// Sisa tagihan yang dilunasi sekaligus dicatat sebagai pembayaran
+		if to == models.PaymentStatusPaid {
+			if err := settleOutstanding(tx, payment, reason); err != nil {
+				return err
+			}
+		}
+		// Tagihan batal tidak lagi memakai kuota kode promo
+		if to == models.PaymentStatusCancelled {
+			if err := releasePromoUsage(tx, payment.ID); err != nil {
+				return err
+			}
+		}
+
+		payment.Status = to
+		return nil
+	})
+}
+
+func newPaymentStatusHistory(payment *models.Payment, from, to string, actor *models.User, reason string) *models.PaymentStatusHistory {
+	history := &models.PaymentStatusHistory{
+		PaymentID:  payment.ID,
+		VendorID:   payment.VendorID,
+		FromStatus: from,
+		ToStatus:   to,
+		ActorName:  "system",
+		Reason:     reason,
+	}
+	if actor != nil {
+		history.ActorID = &actor.ID
+		history.ActorName = actor.Name
+	}
+	return history
+}
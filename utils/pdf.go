@@ -0,0 +1,228 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+	"strings"
+)
+
+// PDF adalah penulis PDF satu halaman A4 yang sangat sederhana: teks
+// Helvetica, garis, kotak dan gambar JPEG/PNG. Cukup untuk dokumen seperti
+// invoice tanpa perlu library eksternal. Koordinat memakai titik (1/72 inci)
+// dengan origin di kiri atas.
+type PDF struct {
+	content bytes.Buffer
+	images  []pdfImage
+}
+
+type pdfImage struct {
+	data       []byte
+	width      int
+	height     int
+	colorSpace string
+}
+
+const (
+	PDFPageWidth  = 595.28
+	PDFPageHeight = 841.89
+)
+
+func NewPDF() *PDF {
+	return &PDF{}
+}
+
+// SetColor mengatur warna isi (teks dan kotak) dalam RGB 0-255.
+func (p *PDF) SetColor(r, g, b uint8) {
+	fmt.Fprintf(&p.content, "%.3f %.3f %.3f rg\n", float64(r)/255, float64(g)/255, float64(b)/255)
+}
+
+// SetStrokeColor mengatur warna garis dalam RGB 0-255.
+func (p *PDF) SetStrokeColor(r, g, b uint8) {
+	fmt.Fprintf(&p.content, "%.3f %.3f %.3f RG\n", float64(r)/255, float64(g)/255, float64(b)/255)
+}
+
+// Text menulis teks dengan baseline di (x, y).
+func (p *PDF) Text(x, y, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(&p.content, "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n",
+		font, size, x, PDFPageHeight-y, pdfEscape(s))
+}
+
+// TextRight menulis teks rata kanan dengan ujung kanan di x.
+func (p *PDF) TextRight(x, y, size float64, bold bool, s string) {
+	p.Text(x-PDFTextWidth(s, size, bold), y, size, bold, s)
+}
+
+// Line menggambar garis dari (x1, y1) ke (x2, y2).
+func (p *PDF) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%.2f w %.2f %.2f m %.2f %.2f l S\n",
+		width, x1, PDFPageHeight-y1, x2, PDFPageHeight-y2)
+}
+
+// Rect menggambar kotak dengan sudut kiri atas (x, y). fill true untuk kotak
+// berisi warna SetColor, false untuk garis tepi saja.
+func (p *PDF) Rect(x, y, w, h, lineWidth float64, fill bool) {
+	op := "S"
+	if fill {
+		op = "f"
+	}
+	fmt.Fprintf(&p.content, "%.2f w %.2f %.2f %.2f %.2f re %s\n",
+		lineWidth, x, PDFPageHeight-y-h, w, h, op)
+}
+
+// ImageFile menempelkan gambar JPEG, PNG atau GIF di dalam kotak (x, y, w, h)
+// dengan rasio aspek dipertahankan.
+func (p *PDF) ImageFile(path string, x, y, w, h float64) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	img, err := pdfImageFrom(raw)
+	if err != nil {
+		return err
+	}
+
+	scale := math.Min(w/float64(img.width), h/float64(img.height))
+	drawW, drawH := float64(img.width)*scale, float64(img.height)*scale
+	name := fmt.Sprintf("Im%d", len(p.images)+1)
+	p.images = append(p.images, img)
+	fmt.Fprintf(&p.content, "q %.2f 0 0 %.2f %.2f %.2f cm /%s Do Q\n",
+		drawW, drawH, x, PDFPageHeight-y-drawH, name)
+	return nil
+}
+
+// pdfImageFrom memakai JPEG apa adanya (DCTDecode). Format lain diubah ke
+// JPEG di atas latar putih.
+func pdfImageFrom(raw []byte) (pdfImage, error) {
+	if cfg, err := jpeg.DecodeConfig(bytes.NewReader(raw)); err == nil {
+		switch cfg.ColorModel {
+		case color.GrayModel:
+			return pdfImage{data: raw, width: cfg.Width, height: cfg.Height, colorSpace: "DeviceGray"}, nil
+		case color.YCbCrModel:
+			return pdfImage{data: raw, width: cfg.Width, height: cfg.Height, colorSpace: "DeviceRGB"}, nil
+		}
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return pdfImage{}, err
+	}
+	bounds := src.Bounds()
+	canvas := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(canvas, canvas.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(canvas, canvas.Bounds(), src, bounds.Min, draw.Over)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, canvas, &jpeg.Options{Quality: 90}); err != nil {
+		return pdfImage{}, err
+	}
+	return pdfImage{data: buf.Bytes(), width: bounds.Dx(), height: bounds.Dy(), colorSpace: "DeviceRGB"}, nil
+}
+
+// Bytes menyusun dokumen PDF lengkap.
+func (p *PDF) Bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// 1 catalog, 2 pages, 3 page, 4-5 font, 6.. gambar, terakhir konten
+	contentID := 6 + len(p.images)
+	var xobjects strings.Builder
+	for i := range p.images {
+		fmt.Fprintf(&xobjects, "/Im%d %d 0 R ", i+1, 6+i)
+	}
+
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj("<< /Type /Pages /Kids [3 0 R] /Count 1 >>")
+	obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+		"/Resources << /Font << /F1 4 0 R /F2 5 0 R >> /XObject << %s>> >> /Contents %d 0 R >>",
+		PDFPageWidth, PDFPageHeight, xobjects.String(), contentID))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for _, img := range p.images {
+		obj(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s "+
+			"/BitsPerComponent 8 /Filter /DCTDecode /Length %d >>\nstream\n%s\nendstream",
+			img.width, img.height, img.colorSpace, len(img.data), img.data))
+	}
+	obj(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", p.content.Len(), p.content.Bytes()))
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+// pdfEscape mengubah teks ke WinAnsi dan meng-escape karakter khusus PDF.
+// Karakter di luar Latin-1 diganti "?".
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r == '\n' || r == '\r' || r == '\t':
+			b.WriteByte(' ')
+		case r < 32 || (r >= 127 && r < 160) || r > 255:
+			b.WriteByte('?')
+		default:
+			b.WriteByte(byte(r))
+		}
+	}
+	return b.String()
+}
+
+// Lebar karakter Helvetica (per 1000 unit) untuk ASCII 32-126.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
+
+// PDFTextWidth menghitung lebar teks dalam titik.
+func PDFTextWidth(s string, size float64, bold bool) float64 {
+	widths := &helveticaWidths
+	if bold {
+		widths = &helveticaBoldWidths
+	}
+	total := 0
+	for _, r := range s {
+		if r >= 32 && r <= 126 {
+			total += widths[r-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
@@ -0,0 +1,253 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"ssb_api/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPromoNotFound  = errors.New("promo code not found")
+	ErrPromoInvalid   = errors.New("promo code is not valid for this payment")
+	ErrPromoExhausted = errors.New("promo code usage limit reached")
+)
+
+// PriceInput adalah data tagihan yang akan dihitung harganya.
+type PriceInput struct {
+	VendorID    uint
+	UserID      uint
+	EventID     *uint
+	PaymentType string
+	Fee         float64
+	Date        time.Time
+	PromoCode   string
+}
+
+// PriceQuote adalah hasil perhitungan harga beserta rinciannya.
+type PriceQuote struct {
+	Gross     float64                  `json:"gross"`
+	Net       float64                  `json:"net"`
+	LineItems []models.PaymentLineItem `json:"line_items"`
+	promo     *models.PromoCode
+}
+
+// QuotePrice menghitung harga tagihan lewat aturan harga vendor dengan urutan:
+// beasiswa pemain, aturan otomatis (general, early bird, saudara) sesuai
+// Priority, lalu kode promo. Potongan persen dihitung dari sisa harga
+// setelah potongan sebelumnya dan harga tidak pernah di bawah nol.
+func QuotePrice(db *gorm.DB, in PriceInput) (PriceQuote, error) {
+	quote := newPriceQuote(in.VendorID, in.Fee)
+	if in.Fee <= 0 {
+		return quote, nil
+	}
+	date := in.Date.Format("2006-01-02")
+
+	// Beasiswa
+	var scholarships []models.Scholarship
+	if err := db.Where("vendor_id = ? AND user_id = ?", in.VendorID, in.UserID).
+		Order("id ASC").Find(&scholarships).Error; err != nil {
+		return quote, err
+	}
+	for _, s := range scholarships {
+		if !inScope(s.PaymentType, in.PaymentType) || !inWindow(s.ValidFrom, s.ValidUntil, date) {
+			continue
+		}
+		id := s.ID
+		quote.addDiscount(models.PaymentLineItem{Description: "Beasiswa " + s.Name, ScholarshipID: &id}, s.DiscountType, s.Value)
+	}
+
+	// Aturan otomatis
+	var rules []models.PricingRule
+	if err := db.Where("vendor_id = ? AND active = ?", in.VendorID, true).
+		Order("priority ASC, id ASC").Find(&rules).Error; err != nil {
+		return quote, err
+	}
+	for _, r := range rules {
+		if !inScope(r.PaymentType, in.PaymentType) || !sameEvent(r.EventID, in.EventID) || !inWindow(r.ValidFrom, r.ValidUntil, date) {
+			continue
+		}
+		if r.Kind == models.PricingRuleSibling {
+			ok, err := isSiblingDiscounted(db, in.VendorID, in.UserID, r.MinSiblings)
+			if err != nil {
+				return quote, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		id := r.ID
+		quote.addDiscount(models.PaymentLineItem{Description: r.Name, PricingRuleID: &id}, r.DiscountType, r.Value)
+	}
+
+	// Kode promo
+	if code := strings.ToUpper(strings.TrimSpace(in.PromoCode)); code != "" {
+		var promo models.PromoCode
+		if err := db.Where("vendor_id = ? AND code = ?", in.VendorID, code).First(&promo).Error; err != nil {
+			return quote, ErrPromoNotFound
+		}
+		if !promo.Active || !inScope(promo.PaymentType, in.PaymentType) || !sameEvent(promo.EventID, in.EventID) ||
+			!inWindow(promo.ValidFrom, promo.ValidUntil, date) {
+			return quote, ErrPromoInvalid
+		}
+		if promo.MaxUses > 0 && promo.UsedCount >= promo.MaxUses {
+			return quote, ErrPromoExhausted
+		}
+		id := promo.ID
+		quote.addDiscount(models.PaymentLineItem{Description: "Promo " + promo.Code, PromoCodeID: &id}, promo.DiscountType, promo.Value)
+		quote.promo = &promo
+	}
+
+	return quote, nil
+}
+
+// newPriceQuote memulai perhitungan dari harga dasar fee.
+func newPriceQuote(vendorID uint, fee float64) PriceQuote {
+	return PriceQuote{
+		Gross: fee,
+		Net:   fee,
+		LineItems: []models.PaymentLineItem{{
+			VendorID:    &vendorID,
+			Kind:        models.LineItemBase,
+			Description: "Harga dasar",
+			Amount:      fee,
+		}},
+	}
+}
+
+// addDiscount memotong sisa harga dan mencatatnya sebagai rincian. Potongan
+// persen dihitung dari Net saat ini dan potongan tidak melebihi sisa harga.
+func (q *PriceQuote) addDiscount(line models.PaymentLineItem, discountType string, value float64) {
+	cut := value
+	if discountType == models.DiscountPercent {
+		cut = math.Round(q.Net * value / 100)
+	}
+	if cut > q.Net {
+		cut = q.Net
+	}
+	if cut <= 0 {
+		return
+	}
+	q.Net -= cut
+	line.VendorID = q.LineItems[0].VendorID
+	line.Kind = models.LineItemDiscount
+	line.Amount = -cut
+	q.LineItems = append(q.LineItems, line)
+}
+
+// PricePayment menghitung harga akhir tagihan dari Amount (sebagai harga
+// dasar) lalu mengisi GrossAmount, Amount dan LineItems. Pemakaian kode promo
+// dikunci di transaksi yang sama supaya batas pemakaian tidak terlewati.
+func PricePayment(tx *gorm.DB, payment *models.Payment) error {
+	if payment.VendorID == nil || payment.Amount <= 0 {
+		payment.GrossAmount = payment.Amount
+		return nil
+	}
+
+	quote, err := QuotePrice(tx, PriceInput{
+		VendorID:    *payment.VendorID,
+		UserID:      payment.UserID,
+		EventID:     payment.EventID,
+		PaymentType: payment.Type,
+		Fee:         payment.Amount,
+		Date:        time.Now(),
+		PromoCode:   payment.PromoCode,
+	})
+	if err != nil {
+		return err
+	}
+
+	if quote.promo != nil {
+		result := tx.Model(&models.PromoCode{}).
+			Where("id = ? AND (max_uses = 0 OR used_count < max_uses)", quote.promo.ID).
+			Update("used_count", gorm.Expr("used_count + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrPromoExhausted
+		}
+		payment.PromoCode = quote.promo.Code
+	}
+
+	payment.GrossAmount = quote.Gross
+	payment.Amount = quote.Net
+	// Tanpa potongan cukup simpan harga dasar tanpa rincian
+	if len(quote.LineItems) > 1 {
+		payment.LineItems = quote.LineItems
+	}
+	return nil
+}
+
+// releasePromoUsage mengembalikan kuota kode promo yang dipakai tagihan,
+// dipanggil saat tagihan dibatalkan.
+func releasePromoUsage(tx *gorm.DB, paymentID uint) error {
+	var promoIDs []uint
+	if err := tx.Model(&models.PaymentLineItem{}).
+		Where("payment_id = ? AND promo_code_id IS NOT NULL", paymentID).
+		Pluck("promo_code_id", &promoIDs).Error; err != nil || len(promoIDs) == 0 {
+		return err
+	}
+	return tx.Model(&models.PromoCode{}).Where("id IN ? AND used_count > 0", promoIDs).
+		Update("used_count", gorm.Expr("used_count - 1")).Error
+}
+
+// isSiblingDiscounted cek apakah pemain adalah anak ke-minSiblings atau
+// lebih di keluarganya (diurutkan dari yang paling dulu terdaftar).
+func isSiblingDiscounted(db *gorm.DB, vendorID, userID uint, minSiblings int) (bool, error) {
+	if minSiblings < 2 {
+		minSiblings = 2
+	}
+	var user models.User
+	if err := db.Select("id", "family_id").First(&user, userID).Error; err != nil || user.FamilyID == nil {
+		return false, nil
+	}
+
+	var siblingIDs []uint
+	if err := db.Model(&models.User{}).
+		Where("family_id = ? AND vendor_id = ?", *user.FamilyID, vendorID).
+		Pluck("id", &siblingIDs).Error; err != nil {
+		return false, err
+	}
+	sort.Slice(siblingIDs, func(i, j int) bool { return siblingIDs[i] < siblingIDs[j] })
+	for i, id := range siblingIDs {
+		if id == userID {
+			return i+1 >= minSiblings, nil
+		}
+	}
+	return false, nil
+}
+
+func inScope(ruleType, paymentType string) bool {
+	return ruleType == "" || ruleType == paymentType
+}
+
+func sameEvent(ruleEvent, eventID *uint) bool {
+	return ruleEvent == nil || (eventID != nil && *ruleEvent == *eventID)
+}
+
+func inWindow(from, until, date string) bool {
+	return (from == "" || date >= from) && (until == "" || date <= until)
+}
+
+// ValidateDiscount memastikan jenis dan nilai potongan masuk akal.
+func ValidateDiscount(discountType string, value float64) error {
+	switch discountType {
+	case models.DiscountPercent:
+		if value <= 0 || value > 100 {
+			return errors.New("percent discount must be between 0 and 100")
+		}
+	case models.DiscountFixed:
+		if value <= 0 {
+			return errors.New("fixed discount must be greater than 0")
+		}
+	default:
+		return fmt.Errorf("unknown discount type %q", discountType)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"testing"
+
+	"ssb_api/models"
+)
+
+type testDiscount struct {
+	kind  string
+	value float64
+}
+
+func TestPriceQuoteDiscountStacking(t *testing.T) {
+	cases := []struct {
+		name      string
+		fee       float64
+		discounts []testDiscount
+		wantNet   float64
+		wantCuts  []float64
+	}{
+		{
+			name: "percent after fixed uses the remaining price", fee: 500000,
+			discounts: []testDiscount{{models.DiscountFixed, 100000}, {models.DiscountPercent, 10}},
+			wantNet:   360000, wantCuts: []float64{-100000, -40000},
+		},
+		{
+			name: "fixed after percent", fee: 500000,
+			discounts: []testDiscount{{models.DiscountPercent, 10}, {models.DiscountFixed, 100000}},
+			wantNet:   350000, wantCuts: []float64{-50000, -100000},
+		},
+		{
+			name: "percents compound", fee: 100000,
+			discounts: []testDiscount{{models.DiscountPercent, 50}, {models.DiscountPercent, 50}},
+			wantNet:   25000, wantCuts: []float64{-50000, -25000},
+		},
+		{
+			name: "percent is rounded to whole rupiah", fee: 99999,
+			discounts: []testDiscount{{models.DiscountPercent, 10}},
+			wantNet:   89999, wantCuts: []float64{-10000},
+		},
+		{
+			name: "fixed is capped at the remaining price", fee: 100000,
+			discounts: []testDiscount{{models.DiscountFixed, 80000}, {models.DiscountFixed, 50000}},
+			wantNet:   0, wantCuts: []float64{-80000, -20000},
+		},
+		{
+			name: "nothing left to discount", fee: 100000,
+			discounts: []testDiscount{{models.DiscountPercent, 100}, {models.DiscountFixed, 10000}, {models.DiscountPercent, 10}},
+			wantNet:   0, wantCuts: []float64{-100000},
+		},
+		{
+			name: "zero discount adds no line", fee: 100000,
+			discounts: []testDiscount{{models.DiscountPercent, 0}, {models.DiscountFixed, 0}},
+			wantNet:   100000, wantCuts: nil,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			quote := newPriceQuote(7, tc.fee)
+			for _, d := range tc.discounts {
+				quote.addDiscount(models.PaymentLineItem{Description: "Diskon"}, d.kind, d.value)
+			}
+			if quote.Gross != tc.fee || quote.Net != tc.wantNet {
+				t.Fatalf("gross %v net %v, want %v and %v", quote.Gross, quote.Net, tc.fee, tc.wantNet)
+			}
+			if len(quote.LineItems) != len(tc.wantCuts)+1 {
+				t.Fatalf("got %d line items, want %d", len(quote.LineItems), len(tc.wantCuts)+1)
+			}
+			base := quote.LineItems[0]
+			if base.Kind != models.LineItemBase || base.Amount != tc.fee {
+				t.Fatalf("unexpected base line %+v", base)
+			}
+			total := base.Amount
+			for i, cut := range tc.wantCuts {
+				line := quote.LineItems[i+1]
+				if line.Kind != models.LineItemDiscount || line.Amount != cut || line.VendorID == nil || *line.VendorID != 7 {
+					t.Errorf("line %d: got %+v, want discount %v", i+1, line, cut)
+				}
+				total += line.Amount
+			}
+			if total != quote.Net {
+				t.Errorf("line items sum to %v, net is %v", total, quote.Net)
+			}
+		})
+	}
+}
+
+func TestPricingRuleMatching(t *testing.T) {
+	one, two := uint(1), uint(2)
+	cases := []struct {
+		name string
+		got  bool
+		want bool
+	}{
+		{"any payment type", inScope("", "event"), true},
+		{"same payment type", inScope("membership", "membership"), true},
+		{"other payment type", inScope("membership", "event"), false},
+		{"rule for any event", sameEvent(nil, nil), true},
+		{"rule for event, payment without event", sameEvent(&one, nil), false},
+		{"rule for same event", sameEvent(&one, &one), true},
+		{"rule for other event", sameEvent(&one, &two), false},
+		{"open window", inWindow("", "", "2025-06-15"), true},
+		{"first day included", inWindow("2025-06-15", "2025-06-30", "2025-06-15"), true},
+		{"last day included", inWindow("2025-06-01", "2025-06-15", "2025-06-15"), true},
+		{"before window", inWindow("2025-06-16", "", "2025-06-15"), false},
+		{"after window", inWindow("", "2025-06-14", "2025-06-15"), false},
+	}
+	for _, tc := range cases {
+		if tc.got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestValidateDiscount(t *testing.T) {
+	cases := []struct {
+		kind  string
+		value float64
+		ok    bool
+	}{
+		{models.DiscountPercent, 10, true},
+		{models.DiscountPercent, 100, true},
+		{models.DiscountPercent, 0, false},
+		{models.DiscountPercent, 101, false},
+		{models.DiscountFixed, 50000, true},
+		{models.DiscountFixed, 0, false},
+		{models.DiscountFixed, -1, false},
+		{"bogus", 10, false},
+	}
+	for _, tc := range cases {
+		if err := ValidateDiscount(tc.kind, tc.value); (err == nil) != tc.ok {
+			t.Errorf("ValidateDiscount(%q, %v) = %v, want ok=%v", tc.kind, tc.value, err, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"ssb_api/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Batas pengulangan supaya series tanpa akhir tidak membuat loop panjang.
+const (
+	maxSeriesYears       = 5
+	maxSeriesOccurrences = 1000
+)
+
+var (
+	ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+	ErrNotOccurrence     = errors.New("date is not an occurrence of this series")
+)
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+var weekdayNames = [7]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// Recurrence adalah aturan pengulangan series yang sudah divalidasi.
+type Recurrence struct {
+	Start     time.Time
+	Frequency string
+	Interval  int
+	ByDay     []time.Weekday
+	Until     time.Time // zero berarti tanpa batas
+	Count     int       // 0 berarti tanpa batas
+}
+
+// ParseRRule membaca aturan gaya iCalendar seperti
+// "FREQ=WEEKLY;BYDAY=TU,TH;UNTIL=20250630" ke field EventSeries.
+func ParseRRule(rule string, series *models.EventSeries) error {
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:"), ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return fmt.Errorf("%w: %q", ErrInvalidRecurrence, part)
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			series.Frequency = strings.ToLower(value)
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("%w: interval", ErrInvalidRecurrence)
+			}
+			series.Interval = n
+		case "BYDAY":
+			series.ByDay = strings.ToUpper(value)
+		case "UNTIL":
+			// UNTIL iCalendar bisa berisi jam, cukup ambil tanggalnya
+			date := value
+			if len(date) >= 8 && !strings.Contains(date, "-") {
+				date = date[:4] + "-" + date[4:6] + "-" + date[6:8]
+			}
+			series.Until = date
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("%w: count", ErrInvalidRecurrence)
+			}
+			series.Count = n
+		default:
+			return fmt.Errorf("%w: unsupported part %s", ErrInvalidRecurrence, key)
+		}
+	}
+	return nil
+}
+
+// SeriesRecurrence memvalidasi aturan pengulangan series.
+func SeriesRecurrence(series models.EventSeries) (Recurrence, error) {
+	start, err := time.Parse("2006-01-02", series.StartDate)
+	if err != nil {
+		return Recurrence{}, fmt.Errorf("%w: start date must use YYYY-MM-DD", ErrInvalidRecurrence)
+	}
+	r := Recurrence{Start: start, Frequency: series.Frequency, Interval: series.Interval, Count: series.Count}
+	if r.Interval == 0 {
+		r.Interval = 1
+	}
+	if r.Interval < 0 || r.Count < 0 {
+		return Recurrence{}, fmt.Errorf("%w: interval and count must not be negative", ErrInvalidRecurrence)
+	}
+
+	switch r.Frequency {
+	case models.FrequencyDaily, models.FrequencyMonthly:
+	case models.FrequencyWeekly:
+		for _, code := range strings.Split(series.ByDay, ",") {
+			code = strings.ToUpper(strings.TrimSpace(code))
+			if code == "" {
+				continue
+			}
+			day, ok := weekdayCodes[code]
+			if !ok {
+				return Recurrence{}, fmt.Errorf("%w: unknown day %q", ErrInvalidRecurrence, code)
+			}
+			r.ByDay = append(r.ByDay, day)
+		}
+		if len(r.ByDay) == 0 {
+			r.ByDay = []time.Weekday{start.Weekday()}
+		}
+	default:
+		return Recurrence{}, fmt.Errorf("%w: frequency must be daily, weekly or monthly", ErrInvalidRecurrence)
+	}
+
+	if series.Until != "" {
+		if r.Until, err = time.Parse("2006-01-02", series.Until); err != nil {
+			return Recurrence{}, fmt.Errorf("%w: until must use YYYY-MM-DD", ErrInvalidRecurrence)
+		}
+		if r.Until.Before(start) {
+			return Recurrence{}, fmt.Errorf("%w: until is before start date", ErrInvalidRecurrence)
+		}
+	}
+	return r, nil
+}
+
+// Between mengembalikan tanggal pertemuan di rentang [from, to]. COUNT
+// dihitung sejak tanggal mulai, bukan sejak from.
+func (r Recurrence) Between(from, to time.Time) []time.Time {
+	end := r.Start.AddDate(maxSeriesYears, 0, 0)
+	if !r.Until.IsZero() && r.Until.Before(end) {
+		end = r.Until
+	}
+	if to.Before(end) {
+		end = to
+	}
+
+	var dates []time.Time
+	n := 0
+	for d := r.Start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		if !r.matches(d) {
+			continue
+		}
+		n++
+		if (r.Count > 0 && n > r.Count) || n > maxSeriesOccurrences {
+			break
+		}
+		if !d.Before(from) {
+			dates = append(dates, d)
+		}
+	}
+	return dates
+}
+
+// CountBefore menghitung pertemuan sebelum tanggal date.
+func (r Recurrence) CountBefore(date time.Time) int {
+	if !date.After(r.Start) {
+		return 0
+	}
+	return len(r.Between(r.Start, date.AddDate(0, 0, -1)))
+}
+
+// String menulis aturan dalam format RRULE iCalendar.
+func (r Recurrence) String() string {
+	parts := []string{"FREQ=" + strings.ToUpper(r.Frequency)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Frequency == models.FrequencyWeekly {
+		var days []string
+		for _, day := range r.ByDay {
+			days = append(days, weekdayNames[day])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+func (r Recurrence) matches(d time.Time) bool {
+	switch r.Frequency {
+	case models.FrequencyDaily:
+		return daysBetween(r.Start, d)%r.Interval == 0
+	case models.FrequencyWeekly:
+		// Minggu dihitung mulai Senin
+		week := daysBetween(startOfWeek(r.Start), startOfWeek(d)) / 7
+		if week%r.Interval != 0 {
+			return false
+		}
+		for _, day := range r.ByDay {
+			if d.Weekday() == day {
+				return true
+			}
+		}
+		return false
+	case models.FrequencyMonthly:
+		months := (d.Year()-r.Start.Year())*12 + int(d.Month()-r.Start.Month())
+		return d.Day() == r.Start.Day() && months%r.Interval == 0
+	}
+	return false
+}
+
+func daysBetween(a, b time.Time) int {
+	return int(b.Sub(a).Hours() / 24)
+}
+
+func startOfWeek(d time.Time) time.Time {
+	offset := (int(d.Weekday()) + 6) % 7
+	return d.AddDate(0, 0, -offset)
+}
@@ -0,0 +1,228 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"ssb_api/models"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func formatDates(dates []time.Time) string {
+	out := make([]string, len(dates))
+	for i, d := range dates {
+		out[i] = d.Format("2006-01-02")
+	}
+	return strings.Join(out, ",")
+}
+
+func TestParseRRule(t *testing.T) {
+	cases := []struct {
+		rule string
+		want models.EventSeries
+		err  bool
+	}{
+		{"FREQ=WEEKLY;BYDAY=tu,th;UNTIL=20250630", models.EventSeries{Frequency: "weekly", ByDay: "TU,TH", Until: "2025-06-30"}, false},
+		{"RRULE:FREQ=DAILY;INTERVAL=2;COUNT=10", models.EventSeries{Frequency: "daily", Interval: 2, Count: 10}, false},
+		// UNTIL berisi jam cukup diambil tanggalnya
+		{"FREQ=MONTHLY;UNTIL=20251231T235959Z", models.EventSeries{Frequency: "monthly", Until: "2025-12-31"}, false},
+		{"FREQ=WEEKLY;UNTIL=2025-06-30;", models.EventSeries{Frequency: "weekly", Until: "2025-06-30"}, false},
+		{"FREQ=WEEKLY;INTERVAL=x", models.EventSeries{}, true},
+		{"FREQ=WEEKLY;COUNT=many", models.EventSeries{}, true},
+		{"FREQ=WEEKLY;BYMONTH=1", models.EventSeries{}, true},
+		{"FREQ", models.EventSeries{}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.rule, func(t *testing.T) {
+			var got models.EventSeries
+			err := ParseRRule(tc.rule, &got)
+			if tc.err {
+				if !errors.Is(err, ErrInvalidRecurrence) {
+					t.Fatalf("expected ErrInvalidRecurrence, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Frequency != tc.want.Frequency || got.Interval != tc.want.Interval || got.ByDay != tc.want.ByDay ||
+				got.Until != tc.want.Until || got.Count != tc.want.Count {
+				t.Fatalf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSeriesRecurrenceValidation(t *testing.T) {
+	cases := []struct {
+		name   string
+		series models.EventSeries
+	}{
+		{"bad start", models.EventSeries{StartDate: "01/06/2025", Frequency: "daily"}},
+		{"unknown frequency", models.EventSeries{StartDate: "2025-06-01", Frequency: "yearly"}},
+		{"unknown day", models.EventSeries{StartDate: "2025-06-01", Frequency: "weekly", ByDay: "XX"}},
+		{"negative interval", models.EventSeries{StartDate: "2025-06-01", Frequency: "daily", Interval: -1}},
+		{"negative count", models.EventSeries{StartDate: "2025-06-01", Frequency: "daily", Count: -1}},
+		{"until before start", models.EventSeries{StartDate: "2025-06-01", Frequency: "daily", Until: "2025-05-31"}},
+		{"bad until", models.EventSeries{StartDate: "2025-06-01", Frequency: "daily", Until: "20250601"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := SeriesRecurrence(tc.series); !errors.Is(err, ErrInvalidRecurrence) {
+				t.Fatalf("expected ErrInvalidRecurrence, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRecurrenceBetween(t *testing.T) {
+	cases := []struct {
+		name     string
+		series   models.EventSeries
+		from, to string
+		want     string
+	}{
+		{
+			name:   "weekly tuesday and thursday",
+			series: models.EventSeries{StartDate: "2025-06-03", Frequency: "weekly", ByDay: "TU,TH"},
+			from:   "2025-06-01", to: "2025-06-14",
+			want: "2025-06-03,2025-06-05,2025-06-10,2025-06-12",
+		},
+		{
+			name:   "weekly defaults to start weekday",
+			series: models.EventSeries{StartDate: "2025-06-04", Frequency: "weekly"},
+			from:   "2025-06-01", to: "2025-06-20",
+			want: "2025-06-04,2025-06-11,2025-06-18",
+		},
+		{
+			name:   "every other week counts from the start week",
+			series: models.EventSeries{StartDate: "2025-06-05", Frequency: "weekly", Interval: 2, ByDay: "TU,TH"},
+			from:   "2025-06-01", to: "2025-06-30",
+			want: "2025-06-05,2025-06-17,2025-06-19",
+		},
+		{
+			name:   "until is inclusive",
+			series: models.EventSeries{StartDate: "2025-06-02", Frequency: "daily", Interval: 3, Until: "2025-06-08"},
+			from:   "2025-06-01", to: "2025-06-30",
+			want: "2025-06-02,2025-06-05,2025-06-08",
+		},
+		{
+			name:   "count is counted from the start date",
+			series: models.EventSeries{StartDate: "2025-06-02", Frequency: "daily", Count: 5},
+			from:   "2025-06-05", to: "2025-06-30",
+			want: "2025-06-05,2025-06-06",
+		},
+		{
+			name:   "monthly on the 31st skips short months",
+			series: models.EventSeries{StartDate: "2025-01-31", Frequency: "monthly"},
+			from:   "2025-01-01", to: "2025-06-30",
+			want: "2025-01-31,2025-03-31,2025-05-31",
+		},
+		{
+			// Tanggal dihitung tanpa jam, jadi pergantian DST tidak menggeser hari
+			name:   "weekly across a DST change",
+			series: models.EventSeries{StartDate: "2025-03-23", Frequency: "weekly", ByDay: "SU"},
+			from:   "2025-03-01", to: "2025-04-06",
+			want: "2025-03-23,2025-03-30,2025-04-06",
+		},
+		{
+			name:   "range before start",
+			series: models.EventSeries{StartDate: "2025-06-02", Frequency: "daily"},
+			from:   "2025-05-01", to: "2025-05-31",
+			want: "",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := SeriesRecurrence(tc.series)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := formatDates(r.Between(day(tc.from), day(tc.to))); got != tc.want {
+				t.Fatalf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRecurrenceBetweenIsBounded(t *testing.T) {
+	r, err := SeriesRecurrence(models.EventSeries{StartDate: "2025-01-01", Frequency: "daily"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dates := r.Between(day("2025-01-01"), day("2040-01-01"))
+	if len(dates) != maxSeriesOccurrences {
+		t.Fatalf("expected %d occurrences, got %d", maxSeriesOccurrences, len(dates))
+	}
+}
+
+func TestRecurrenceCountBefore(t *testing.T) {
+	r, err := SeriesRecurrence(models.EventSeries{StartDate: "2025-06-03", Frequency: "weekly", ByDay: "TU,TH"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		date string
+		want int
+	}{
+		{"2025-06-01", 0},
+		{"2025-06-03", 0},
+		{"2025-06-05", 1},
+		{"2025-06-10", 2},
+		{"2025-06-11", 3},
+	}
+	for _, tc := range cases {
+		if got := r.CountBefore(day(tc.date)); got != tc.want {
+			t.Errorf("CountBefore(%s) = %d, want %d", tc.date, got, tc.want)
+		}
+	}
+}
+
+func TestRecurrenceStringRoundTrip(t *testing.T) {
+	series := models.EventSeries{StartDate: "2025-06-03", Frequency: "weekly", Interval: 2, ByDay: "TU,TH", Until: "2025-12-31"}
+	r, err := SeriesRecurrence(series)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := r.String()
+	if rule != "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH;UNTIL=20251231" {
+		t.Fatalf("unexpected rule %s", rule)
+	}
+	parsed := models.EventSeries{StartDate: series.StartDate}
+	if err := ParseRRule(rule, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Frequency != series.Frequency || parsed.Interval != series.Interval ||
+		parsed.ByDay != series.ByDay || parsed.Until != series.Until {
+		t.Fatalf("round trip got %+v", parsed)
+	}
+}
+
+func TestSeriesScheduleKeepsLocalTimeAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+	series := models.EventSeries{Time: "18:00", DurationMinutes: 90}
+	for _, date := range []string{"2025-03-29", "2025-03-30", "2025-03-31"} {
+		start, end, err := SeriesSchedule(series, date, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if start.Hour() != 18 || start.Minute() != 0 || start.Format("2006-01-02") != date {
+			t.Errorf("%s: start %s, want 18:00 local", date, start)
+		}
+		if end.Sub(start) != 90*time.Minute {
+			t.Errorf("%s: duration %s", date, end.Sub(start))
+		}
+	}
+}
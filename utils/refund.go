@@ -0,0 +1,301 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"ssb_api/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrRefundPaymentNotPaid      = errors.New("only paid payments can be refunded")
+	ErrRefundExceedsPaid         = errors.New("refund amount exceeds the refundable amount")
+	ErrRefundNotRequested        = errors.New("refund is not waiting for approval")
+	ErrRefundNotApproved         = errors.New("refund must be approved before payout")
+	ErrRefundProviderUnsupported = errors.New("payment was not paid through a provider that supports refunds")
+)
+
+// Status refund yang sudah mengurangi jumlah yang bisa direfund.
+var activeRefundStatuses = []string{
+	models.RefundStatusRequested,
+	models.RefundStatusApproved,
+	models.RefundStatusProcessing,
+	models.RefundStatusPaidOut,
+}
+
+// Status refund yang sudah punya nota kredit.
+var issuedRefundStatuses = []string{
+	models.RefundStatusApproved,
+	models.RefundStatusProcessing,
+	models.RefundStatusPaidOut,
+}
+
+// RefundableAmount adalah jumlah yang masih bisa direfund dari sebuah
+// tagihan: total terbayar dikurangi refund yang sedang diajukan atau sudah
+// disetujui.
+func RefundableAmount(db *gorm.DB, payment models.Payment) (float64, error) {
+	var refunded float64
+	if err := db.Model(&models.Refund{}).
+		Where("payment_id = ? AND status IN ?", payment.ID, activeRefundStatuses).
+		Select("COALESCE(SUM(amount), 0)").Scan(&refunded).Error; err != nil {
+		return 0, err
+	}
+	if refunded >= payment.PaidAmount {
+		return 0, nil
+	}
+	return payment.PaidAmount - refunded, nil
+}
+
+// RequestRefund mengajukan refund penuh (amount 0) atau sebagian atas tagihan
+// yang sudah lunas.
+func RequestRefund(db *gorm.DB, payment models.Payment, amount float64, reason string, actor models.User) (models.Refund, error) {
+	refund := models.Refund{
+		PaymentID:     payment.ID,
+		VendorID:      payment.VendorID,
+		UserID:        payment.UserID,
+		Reason:        reason,
+		Status:        models.RefundStatusRequested,
+		RequestedByID: actor.ID,
+	}
+	if payment.Status != models.PaymentStatusPaid {
+		return refund, ErrRefundPaymentNotPaid
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Kunci tagihan supaya dua pengajuan bersamaan tidak melebihi total bayar
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, payment.ID).Error; err != nil {
+			return err
+		}
+		refundable, err := RefundableAmount(tx, payment)
+		if err != nil {
+			return err
+		}
+		if amount <= 0 {
+			amount = refundable
+		}
+		if amount <= 0 || amount > refundable {
+			return ErrRefundExceedsPaid
+		}
+		refund.Amount = amount
+		return tx.Create(&refund).Error
+	})
+	return refund, err
+}
+
+// ApproveRefund menyetujui refund lalu menerbitkan nota kredit. Tagihan
+// berpindah ke refunded bila seluruh pembayarannya sudah direfund. Refund
+// dikunci dan dibaca ulang supaya dua persetujuan bersamaan tidak menambah
+// refunded_amount dua kali.
+func ApproveRefund(db *gorm.DB, refund *models.Refund, actor models.User) error {
+	if refund.Status != models.RefundStatusRequested {
+		return ErrRefundNotRequested
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(refund, refund.ID).Error; err != nil {
+			return err
+		}
+		if refund.Status != models.RefundStatusRequested {
+			return ErrRefundNotRequested
+		}
+
+		var payment models.Payment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, refund.PaymentID).Error; err != nil {
+			return err
+		}
+		if payment.Status != models.PaymentStatusPaid {
+			return ErrRefundPaymentNotPaid
+		}
+
+		number, err := NextCreditNoteNumber(tx, refund.VendorID, time.Now())
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		refund.Status = models.RefundStatusApproved
+		refund.ApprovedByID = &actor.ID
+		refund.ApprovedAt = &now
+		refund.CreditNoteNumber = number
+		result := tx.Model(&models.Refund{}).
+			Where("id = ? AND status = ?", refund.ID, models.RefundStatusRequested).
+			Updates(map[string]interface{}{
+				"status":             refund.Status,
+				"approved_by_id":     actor.ID,
+				"approved_at":        now,
+				"credit_note_number": number,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefundNotRequested
+		}
+
+		refunded := payment.RefundedAmount + refund.Amount
+		if err := tx.Model(&payment).Update("refunded_amount", refunded).Error; err != nil {
+			return err
+		}
+		payment.RefundedAmount = refunded
+		if refunded < payment.PaidAmount {
+			return nil
+		}
+		return TransitionPayment(tx, &payment, models.PaymentStatusRefunded, &actor,
+			fmt.Sprintf("Refund %s (%s): %s", FormatRupiah(refund.Amount), number, refund.Reason))
+	})
+}
+
+// RejectRefund menolak pengajuan refund.
+func RejectRefund(db *gorm.DB, refund *models.Refund, actor models.User, reason string) error {
+	if refund.Status != models.RefundStatusRequested {
+		return ErrRefundNotRequested
+	}
+	result := db.Model(&models.Refund{}).
+		Where("id = ? AND status = ?", refund.ID, models.RefundStatusRequested).
+		Updates(map[string]interface{}{
+			"status":         models.RefundStatusRejected,
+			"approved_by_id": actor.ID,
+			"reject_reason":  reason,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRefundNotRequested
+	}
+	refund.Status = models.RefundStatusRejected
+	refund.ApprovedByID = &actor.ID
+	refund.RejectReason = reason
+	return nil
+}
+
+// PayoutRefund mengembalikan dana refund yang sudah disetujui: dicatat manual
+// (reference berisi bukti transfer), lewat provider pembayaran asal, atau
+// masuk saldo kredit pemain. Refund berstatus processing bisa dicatat manual
+// setelah provider mengonfirmasi. Baris refund dikunci dan statusnya diubah
+// bersyarat supaya dua payout bersamaan tidak sama-sama membayar.
+func PayoutRefund(db *gorm.DB, refund *models.Refund, method, reference string) error {
+	if method == models.RefundPayoutProvider {
+		return payoutRefundViaProvider(db, refund)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(refund, refund.ID).Error; err != nil {
+			return err
+		}
+		from := refund.Status
+		switch {
+		case from == models.RefundStatusApproved:
+		case from == models.RefundStatusProcessing && method == models.RefundPayoutManual:
+			// Konfirmasi refund provider yang masih diproses
+			method = refund.PayoutMethod
+			if reference == "" {
+				reference = refund.PayoutReference
+			}
+		default:
+			return ErrRefundNotApproved
+		}
+
+		switch method {
+		case models.RefundPayoutManual, models.RefundPayoutProvider:
+		case models.RefundPayoutCredit:
+			credit := models.AccountCredit{
+				UserID:    refund.UserID,
+				VendorID:  refund.VendorID,
+				Amount:    refund.Amount,
+				PaymentID: &refund.PaymentID,
+				Note:      "Refund " + refund.CreditNoteNumber,
+			}
+			if err := tx.Create(&credit).Error; err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown payout method %q", method)
+		}
+		return finishRefundPayout(tx, refund, from, models.RefundStatusPaidOut, method, reference)
+	})
+}
+
+// payoutRefundViaProvider menandai refund processing dulu sebelum provider
+// dipanggil, sehingga refund yang dananya sudah dikirim provider tidak bisa
+// dibayar lagi walaupun penyimpanan hasilnya gagal. Refund processing tanpa
+// reference (panggilan provider sebelumnya gagal) boleh dicoba ulang; ID
+// refund dikirim sebagai idempotency key supaya provider tidak mengirim dana
+// dua kali.
+func payoutRefundViaProvider(db *gorm.DB, refund *models.Refund) error {
+	var checkout models.PaymentCheckout
+	var refunder RefundProvider
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(refund, refund.ID).Error; err != nil {
+			return err
+		}
+		retry := refund.Status == models.RefundStatusProcessing &&
+			refund.PayoutMethod == models.RefundPayoutProvider && refund.PayoutReference == ""
+		if refund.Status != models.RefundStatusApproved && !retry {
+			return ErrRefundNotApproved
+		}
+
+		if err := tx.Where("payment_id = ? AND status = ?", refund.PaymentID, GatewayStatusPaid).
+			Order("paid_at DESC").First(&checkout).Error; err != nil {
+			return ErrRefundProviderUnsupported
+		}
+		provider, ok := GetPaymentProvider(checkout.Provider)
+		if !ok {
+			return ErrRefundProviderUnsupported
+		}
+		if refunder, ok = provider.(RefundProvider); !ok {
+			return ErrRefundProviderUnsupported
+		}
+		if retry {
+			return nil
+		}
+		return finishRefundPayout(tx, refund, models.RefundStatusApproved, models.RefundStatusProcessing, models.RefundPayoutProvider, "")
+	})
+	if err != nil {
+		return err
+	}
+
+	result, err := refunder.Refund(RefundRequest{
+		Reference:      checkout.Reference,
+		Amount:         refund.Amount,
+		Reason:         refund.Reason,
+		IdempotencyKey: fmt.Sprintf("refund-%d", refund.ID),
+	})
+	if err != nil {
+		return err
+	}
+	status := models.RefundStatusPaidOut
+	if !result.Completed {
+		status = models.RefundStatusProcessing
+	}
+	return finishRefundPayout(db, refund, models.RefundStatusProcessing, status, models.RefundPayoutProvider, result.Reference)
+}
+
+// finishRefundPayout mengubah status refund hanya bila statusnya masih from.
+func finishRefundPayout(tx *gorm.DB, refund *models.Refund, from, to, method, reference string) error {
+	updates := map[string]interface{}{
+		"status":           to,
+		"payout_method":    method,
+		"payout_reference": reference,
+	}
+	var paidOutAt *time.Time
+	if to == models.RefundStatusPaidOut {
+		now := time.Now()
+		paidOutAt = &now
+		updates["paid_out_at"] = now
+	}
+	result := tx.Model(&models.Refund{}).Where("id = ? AND status = ?", refund.ID, from).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRefundNotApproved
+	}
+	refund.Status = to
+	refund.PayoutMethod = method
+	refund.PayoutReference = reference
+	refund.PaidOutAt = paidOutAt
+	return nil
+}
@@ -0,0 +1,260 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"ssb_api/config"
+	"ssb_api/models"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultReminderSchedule adalah jadwal pengingat dalam hari terhadap jatuh
+// tempo: 3 hari sebelum, hari H, lalu 3, 7 dan 14 hari setelahnya.
+var DefaultReminderSchedule = []int{-3, 0, 3, 7, 14}
+
+// ReminderScheduleOff mematikan pengingat untuk vendor.
+const ReminderScheduleOff = "off"
+
+// Status tagihan yang masih perlu ditagih.
+var unpaidPaymentStatuses = []string{
+	models.PaymentStatusIssued,
+	models.PaymentStatusOverdue,
+	models.PaymentStatusRejected,
+	models.PaymentStatusPartiallyPaid,
+}
+
+// Status tagihan yang ditandai overdue setelah lewat jatuh tempo.
+var overdueablePaymentStatuses = []string{
+	models.PaymentStatusIssued,
+	models.PaymentStatusRejected,
+	models.PaymentStatusPartiallyPaid,
+}
+
+type ReminderResult struct {
+	Date          string `json:"date"`
+	MarkedOverdue int    `json:"marked_overdue"`
+	Sent          int    `json:"sent"`
+	Failed        int    `json:"failed"`
+}
+
+var reminderMu sync.Mutex
+
+// ParseReminderSchedule membaca jadwal vendor, contoh "-3,0,3,7,14". String
+// kosong berarti DefaultReminderSchedule, "off" berarti tanpa pengingat.
+func ParseReminderSchedule(s string) ([]int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return DefaultReminderSchedule, nil
+	}
+	if strings.EqualFold(s, ReminderScheduleOff) {
+		return nil, nil
+	}
+
+	seen := map[int]bool{}
+	var offsets []int
+	for _, part := range strings.Split(s, ",") {
+		offset, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid reminder offset %q", part)
+		}
+		if offset < -30 || offset > 90 {
+			return nil, errors.New("reminder offset must be between -30 and 90 days")
+		}
+		if !seen[offset] {
+			seen[offset] = true
+			offsets = append(offsets, offset)
+		}
+	}
+	sort.Ints(offsets)
+	return offsets, nil
+}
+
+// RunPaymentReminders menandai tagihan yang lewat jatuh tempo sebagai overdue
+// lalu mengirim pengingat sesuai jadwal vendor. Setiap langkah jadwal hanya
+// dikirim sekali per tagihan; langkah yang terlewat (misal server mati)
+// tidak dikirim ulang, hanya langkah terbaru. Tanggal hari ini dihitung di
+// zona waktu vendor.
+func RunPaymentReminders(db *gorm.DB, now time.Time) (ReminderResult, error) {
+	reminderMu.Lock()
+	defer reminderMu.Unlock()
+
+	result := ReminderResult{Date: now.Format("2006-01-02")}
+
+	locations := map[uint]*time.Location{}
+	localToday := func(vendorID *uint) time.Time {
+		loc := now.Location()
+		if vendorID != nil {
+			if _, ok := locations[*vendorID]; !ok {
+				locations[*vendorID] = VendorLocation(db, *vendorID)
+			}
+			loc = locations[*vendorID]
+		}
+		local := now.In(loc)
+		return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	}
+
+	// Tandai overdue. Tanggal vendor bisa lebih maju dari tanggal server,
+	// jadi kandidat diambil longgar lalu disaring per vendor.
+	var pastDue []models.Payment
+	if err := db.Where("status IN ? AND due_date IS NOT NULL AND due_date < ?",
+		overdueablePaymentStatuses, now.AddDate(0, 0, 2).Format("2006-01-02")).
+		Find(&pastDue).Error; err != nil {
+		return result, err
+	}
+	for i := range pastDue {
+		if pastDue[i].DueDate.String() >= localToday(pastDue[i].VendorID).Format("2006-01-02") {
+			continue
+		}
+		if err := TransitionPayment(db, &pastDue[i], models.PaymentStatusOverdue, nil, "Past due date "+pastDue[i].DueDate.String()); err != nil {
+			log.Println("Gagal menandai overdue payment", pastDue[i].ID, ":", err)
+			continue
+		}
+		result.MarkedOverdue++
+	}
+
+	// Kirim pengingat
+	var payments []models.Payment
+	if err := db.Where("status IN ? AND due_date IS NOT NULL", unpaidPaymentStatuses).
+		Find(&payments).Error; err != nil {
+		return result, err
+	}
+
+	schedules := map[uint][]int{}
+	for _, payment := range payments {
+		if payment.VendorID == nil {
+			continue
+		}
+		schedule, ok := schedules[*payment.VendorID]
+		if !ok {
+			var vendor models.Vendor
+			if err := db.First(&vendor, *payment.VendorID).Error; err != nil {
+				continue
+			}
+			parsed, err := ParseReminderSchedule(vendor.ReminderSchedule)
+			if err != nil {
+				parsed = DefaultReminderSchedule
+			}
+			schedule = parsed
+			schedules[*payment.VendorID] = schedule
+		}
+
+		today := localToday(payment.VendorID)
+		due, ok := payment.DueDate.Time(today.Location())
+		if !ok {
+			continue
+		}
+		days := int(math.Round(today.Sub(due).Hours() / 24))
+
+		offset, ok := currentReminderStep(schedule, days)
+		if !ok {
+			continue
+		}
+		sent, err := sendPaymentReminder(db, payment, offset, days)
+		if err != nil {
+			log.Println("Gagal kirim pengingat payment", payment.ID, ":", err)
+			result.Failed++
+		} else if sent {
+			result.Sent++
+		}
+	}
+
+	return result, nil
+}
+
+// currentReminderStep mengembalikan langkah jadwal terakhir yang sudah
+// tercapai pada hari ke-days relatif terhadap jatuh tempo.
+func currentReminderStep(schedule []int, days int) (int, bool) {
+	step, ok := 0, false
+	for _, offset := range schedule {
+		if offset <= days {
+			step, ok = offset, true
+		}
+	}
+	return step, ok
+}
+
+func sendPaymentReminder(db *gorm.DB, payment models.Payment, offset, days int) (bool, error) {
+	var count int64
+	if err := db.Model(&models.PaymentReminder{}).
+		Where("payment_id = ? AND day_offset = ?", payment.ID, offset).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+
+	var user models.User
+	if err := db.First(&user, payment.UserID).Error; err != nil {
+		return false, err
+	}
+
+	// Catat dulu supaya proses lain tidak mengirim pengingat yang sama
+	reminder := models.PaymentReminder{
+		PaymentID: payment.ID,
+		DayOffset: offset,
+		VendorID:  payment.VendorID,
+		UserID:    payment.UserID,
+		SentAt:    time.Now(),
+	}
+	if err := db.Create(&reminder).Error; err != nil {
+		return false, err
+	}
+
+	title, body := reminderMessage(user.Name, payment, days)
+	notif, err := SendNotification(user.ID, user.FCMToken, title, body, "tagihan")
+	if err != nil {
+		return false, err
+	}
+	db.Model(&reminder).Update("notification_id", notif.ID)
+	return true, nil
+}
+
+func reminderMessage(name string, payment models.Payment, days int) (string, string) {
+	amount := FormatRupiah(payment.Outstanding())
+	due := FormatDateID(payment.DueDate.String())
+	switch {
+	case days < 0:
+		return "Pengingat Tagihan", fmt.Sprintf("Hai %s, tagihan %s sebesar %s akan jatuh tempo pada %s.", name, payment.Invoice, amount, due)
+	case days == 0:
+		return "Tagihan Jatuh Tempo Hari Ini", fmt.Sprintf("Hai %s, tagihan %s sebesar %s jatuh tempo hari ini.", name, payment.Invoice, amount)
+	default:
+		return "Tagihan Terlambat", fmt.Sprintf("Hai %s, tagihan %s sebesar %s sudah lewat %d hari dari jatuh tempo (%s). Mohon segera dilunasi.", name, payment.Invoice, amount, days, due)
+	}
+}
+
+// StartReminderScheduler menjalankan RunPaymentReminders setiap
+// REMINDER_INTERVAL (default 1h). Set REMINDER_ENABLED=false untuk mematikan.
+func StartReminderScheduler() {
+	if os.Getenv("REMINDER_ENABLED") == "false" {
+		return
+	}
+
+	interval, err := time.ParseDuration(os.Getenv("REMINDER_INTERVAL"))
+	if err != nil || interval <= 0 {
+		interval = time.Hour
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			db := config.DB.WithContext(config.SystemContext())
+			result, err := RunPaymentReminders(db, time.Now())
+			if err != nil {
+				log.Println("❌ Payment reminders failed:", err)
+			} else if result.Sent > 0 || result.MarkedOverdue > 0 || result.Failed > 0 {
+				log.Printf("✅ Payment reminders %s: %d overdue, %d sent, %d failed\n", result.Date, result.MarkedOverdue, result.Sent, result.Failed)
+			}
+			<-ticker.C
+		}
+	}()
+}
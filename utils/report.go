@@ -0,0 +1,411 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"ssb_api/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RevenueRow adalah pendapatan vendor dalam satu bulan.
+type RevenueRow struct {
+	Month     string  `json:"month"` // format: YYYY-MM
+	Collected float64 `json:"collected"`
+	Refunded  float64 `json:"refunded"`
+	Net       float64 `json:"net"`
+}
+
+type RevenueReport struct {
+	VendorID  uint         `json:"vendor_id"`
+	StartDate string       `json:"start_date"`
+	EndDate   string       `json:"end_date"`
+	Months    []RevenueRow `json:"months"`
+	Collected float64      `json:"collected"`
+	Refunded  float64      `json:"refunded"`
+	Net       float64      `json:"net"`
+}
+
+// BuildRevenueReport menghitung uang masuk per bulan dikurangi refund yang
+// nota kreditnya terbit di bulan tersebut. Pembayaran dari saldo kredit tidak
+// dihitung karena uangnya sudah masuk di pembayaran asal. startDate dan
+// endDate (YYYY-MM-DD) boleh kosong.
+func BuildRevenueReport(db *gorm.DB, vendorID uint, startDate, endDate string) (RevenueReport, error) {
+	report := RevenueReport{VendorID: vendorID, StartDate: startDate, EndDate: endDate, Months: []RevenueRow{}}
+
+	type monthTotal struct {
+		Month string
+		Total float64
+	}
+
+	var collected []monthTotal
+	q := db.Model(&models.PaymentTransaction{}).
+		Select("LEFT(date, 7) AS month, COALESCE(SUM(amount), 0) AS total").
+		Where("vendor_id = ? AND status = ? AND method <> ?", vendorID, models.PaymentTransactionConfirmed, models.PaymentMethodCredit)
+	if startDate != "" {
+		q = q.Where("date >= ?", startDate)
+	}
+	if endDate != "" {
+		q = q.Where("date <= ?", endDate)
+	}
+	if err := q.Group("LEFT(date, 7)").Scan(&collected).Error; err != nil {
+		return report, err
+	}
+
+	var refunded []monthTotal
+	q = db.Model(&models.Refund{}).
+		Select("TO_CHAR(approved_at, 'YYYY-MM') AS month, COALESCE(SUM(amount), 0) AS total").
+		Where("vendor_id = ? AND status IN ?", vendorID, issuedRefundStatuses)
+	if startDate != "" {
+		q = q.Where("approved_at >= ?", startDate)
+	}
+	if endDate != "" {
+		q = q.Where("approved_at < ?::date + 1", endDate)
+	}
+	if err := q.Group("TO_CHAR(approved_at, 'YYYY-MM')").Scan(&refunded).Error; err != nil {
+		return report, err
+	}
+
+	rows := map[string]*RevenueRow{}
+	row := func(month string) *RevenueRow {
+		if rows[month] == nil {
+			rows[month] = &RevenueRow{Month: month}
+		}
+		return rows[month]
+	}
+	for _, m := range collected {
+		row(m.Month).Collected += m.Total
+		report.Collected += m.Total
+	}
+	for _, m := range refunded {
+		row(m.Month).Refunded += m.Total
+		report.Refunded += m.Total
+	}
+
+	for _, r := range rows {
+		r.Net = r.Collected - r.Refunded
+		report.Months = append(report.Months, *r)
+	}
+	sort.Slice(report.Months, func(i, j int) bool { return report.Months[i].Month < report.Months[j].Month })
+	report.Net = report.Collected - report.Refunded
+	return report, nil
+}
+
+var (
+	ErrInvalidReportRange = errors.New("invalid date range, use YYYY-MM-DD with start_date before end_date")
+	ErrUnknownBreakdown   = errors.New("unknown breakdown, use month, event, type or method")
+)
+
+// Tagihan yang masih punya sisa pembayaran.
+var openPaymentStatuses = []string{
+	models.PaymentStatusIssued,
+	models.PaymentStatusOverdue,
+	models.PaymentStatusRejected,
+	models.PaymentStatusPartiallyPaid,
+	models.PaymentStatusAwaitingVerification,
+}
+
+// Tagihan yang dihitung sebagai tagihan terbit di laporan.
+var billedPaymentStatuses = append([]string{
+	models.PaymentStatusPaid,
+	models.PaymentStatusRefunded,
+}, openPaymentStatuses...)
+
+// ReportRange adalah periode laporan (YYYY-MM-DD, inklusif). Start atau End
+// kosong berarti tanpa batas.
+type ReportRange struct {
+	Start string `json:"start_date"`
+	End   string `json:"end_date"`
+}
+
+// ParseReportRange memvalidasi periode laporan. Format lama DD/MM/YYYY
+// diubah ke YYYY-MM-DD.
+func ParseReportRange(start, end string) (ReportRange, error) {
+	r := ReportRange{Start: start, End: end}
+	s, errStart := models.ParseDate(start)
+	e, errEnd := models.ParseDate(end)
+	if errStart != nil || errEnd != nil {
+		return r, ErrInvalidReportRange
+	}
+	r = ReportRange{Start: s.String(), End: e.String()}
+	if r.Start != "" && r.End != "" && r.Start > r.End {
+		return r, ErrInvalidReportRange
+	}
+	return r, nil
+}
+
+// Periode pembanding laporan.
+const (
+	CompareNone     = ""
+	ComparePrevious = "previous" // periode sebelumnya dengan panjang yang sama
+	CompareYear     = "year"     // periode yang sama tahun lalu
+)
+
+// ComparisonRange menghitung periode pembanding dari periode laporan. Mode
+// previous dan year butuh start_date dan end_date.
+func ComparisonRange(r ReportRange, mode string) (ReportRange, error) {
+	if r.Start == "" || r.End == "" {
+		return ReportRange{}, errors.New("start_date and end_date are required for comparison")
+	}
+	start, _ := time.Parse("2006-01-02", r.Start)
+	end, _ := time.Parse("2006-01-02", r.End)
+
+	switch mode {
+	case ComparePrevious:
+		days := int(end.Sub(start).Hours()/24) + 1
+		return ReportRange{
+			Start: start.AddDate(0, 0, -days).Format("2006-01-02"),
+			End:   start.AddDate(0, 0, -1).Format("2006-01-02"),
+		}, nil
+	case CompareYear:
+		return ReportRange{
+			Start: start.AddDate(-1, 0, 0).Format("2006-01-02"),
+			End:   end.AddDate(-1, 0, 0).Format("2006-01-02"),
+		}, nil
+	}
+	return ReportRange{}, fmt.Errorf("unknown compare mode %q, use previous or year", mode)
+}
+
+// apply memfilter kolom tanggal string (YYYY-MM-DD) sesuai periode.
+func (r ReportRange) apply(q *gorm.DB, column string) *gorm.DB {
+	if r.Start != "" {
+		q = q.Where(column+" >= ?", r.Start)
+	}
+	if r.End != "" {
+		q = q.Where(column+" <= ?", r.End)
+	}
+	return q
+}
+
+// FinancialSummary adalah ringkasan keuangan vendor dalam satu periode.
+// Billed, Paid dan Outstanding dihitung dari tagihan bertanggal di periode
+// tersebut, Collected dan Refunded dari uang yang masuk/keluar di periode itu.
+type FinancialSummary struct {
+	Range          ReportRange `json:"range"`
+	PaymentCount   int64       `json:"payment_count"`
+	PaidCount      int64       `json:"paid_count"`
+	Billed         float64     `json:"billed"`
+	Paid           float64     `json:"paid"`
+	Outstanding    float64     `json:"outstanding"`
+	CollectionRate float64     `json:"collection_rate"` // persen, Paid / Billed
+	Collected      float64     `json:"collected"`
+	Refunded       float64     `json:"refunded"`
+	NetRevenue     float64     `json:"net_revenue"`
+}
+
+// BuildFinancialSummary menghitung ringkasan keuangan vendor lewat agregasi
+// SQL.
+func BuildFinancialSummary(db *gorm.DB, vendorID uint, r ReportRange) (FinancialSummary, error) {
+	summary := FinancialSummary{Range: r}
+
+	var billed struct {
+		PaymentCount int64
+		PaidCount    int64
+		Billed       float64
+		Paid         float64
+		Outstanding  float64
+	}
+	q := db.Model(&models.Payment{}).
+		Select(`COUNT(*) AS payment_count,
+			COUNT(*) FILTER (WHERE status IN ?) AS paid_count,
+			COALESCE(SUM(amount), 0) AS billed,
+			COALESCE(SUM(LEAST(paid_amount, amount)), 0) AS paid,
+			COALESCE(SUM(GREATEST(amount - paid_amount, 0)) FILTER (WHERE status IN ?), 0) AS outstanding`,
+			[]string{models.PaymentStatusPaid, models.PaymentStatusRefunded}, openPaymentStatuses).
+		Where("vendor_id = ? AND status IN ?", vendorID, billedPaymentStatuses)
+	if err := r.apply(q, "date").Scan(&billed).Error; err != nil {
+		return summary, err
+	}
+	summary.PaymentCount = billed.PaymentCount
+	summary.PaidCount = billed.PaidCount
+	summary.Billed = billed.Billed
+	summary.Paid = billed.Paid
+	summary.Outstanding = billed.Outstanding
+	summary.CollectionRate = percent(billed.Paid, billed.Billed)
+
+	revenue, err := BuildRevenueReport(db, vendorID, r.Start, r.End)
+	if err != nil {
+		return summary, err
+	}
+	summary.Collected = revenue.Collected
+	summary.Refunded = revenue.Refunded
+	summary.NetRevenue = revenue.Net
+	return summary, nil
+}
+
+// Dimensi rincian pendapatan.
+const (
+	BreakdownMonth  = "month"
+	BreakdownEvent  = "event"
+	BreakdownType   = "type"
+	BreakdownMethod = "method"
+)
+
+// BreakdownRow adalah satu baris rincian pendapatan. Untuk dimensi method
+// hanya Collected dan Count yang terisi karena metode ada di setiap cicilan.
+type BreakdownRow struct {
+	Key            string  `json:"key"`
+	Label          string  `json:"label"`
+	Count          int64   `json:"count"`
+	Billed         float64 `json:"billed"`
+	Collected      float64 `json:"collected"`
+	Outstanding    float64 `json:"outstanding"`
+	CollectionRate float64 `json:"collection_rate"`
+}
+
+// BuildRevenueBreakdown mengelompokkan tagihan vendor per bulan, event atau
+// tipe, atau uang masuk per metode pembayaran.
+func BuildRevenueBreakdown(db *gorm.DB, vendorID uint, r ReportRange, by string) ([]BreakdownRow, error) {
+	rows := []BreakdownRow{}
+
+	if by == BreakdownMethod {
+		q := db.Model(&models.PaymentTransaction{}).
+			Select("method AS key, method AS label, COUNT(*) AS count, COALESCE(SUM(amount), 0) AS collected").
+			Where("vendor_id = ? AND status = ? AND method <> ?", vendorID, models.PaymentTransactionConfirmed, models.PaymentMethodCredit)
+		err := r.apply(q, "date").Group("method").Order("collected DESC").Scan(&rows).Error
+		return rows, err
+	}
+
+	var key, label string
+	q := db.Model(&models.Payment{})
+	switch by {
+	case BreakdownMonth:
+		key, label = "TO_CHAR(payments.date, 'YYYY-MM')", "TO_CHAR(payments.date, 'YYYY-MM')"
+	case BreakdownType:
+		key, label = "payments.type", "payments.type"
+	case BreakdownEvent:
+		key, label = "COALESCE(payments.event_id, 0)::text", "COALESCE(events.title, '')"
+		q = q.Joins("LEFT JOIN events ON events.id = payments.event_id")
+	default:
+		return rows, ErrUnknownBreakdown
+	}
+
+	q = q.Select(fmt.Sprintf(`%s AS key, %s AS label, COUNT(*) AS count,
+			COALESCE(SUM(payments.amount), 0) AS billed,
+			COALESCE(SUM(LEAST(payments.paid_amount, payments.amount)), 0) AS collected,
+			COALESCE(SUM(GREATEST(payments.amount - payments.paid_amount, 0)) FILTER (WHERE payments.status IN ?), 0) AS outstanding`,
+		key, label), openPaymentStatuses).
+		Where("payments.vendor_id = ? AND payments.status IN ?", vendorID, billedPaymentStatuses)
+	q = r.apply(q, "payments.date").Group(key + ", " + label)
+	if by == BreakdownMonth {
+		q = q.Order("key ASC")
+	} else {
+		q = q.Order("billed DESC")
+	}
+	if err := q.Scan(&rows).Error; err != nil {
+		return rows, err
+	}
+
+	for i := range rows {
+		rows[i].CollectionRate = percent(rows[i].Collected, rows[i].Billed)
+		if by == BreakdownEvent && rows[i].Key == "0" {
+			rows[i].Label = "Tanpa event"
+		}
+	}
+	return rows, nil
+}
+
+// Debtor adalah pemain dengan sisa tagihan terbesar.
+type Debtor struct {
+	UserID       uint    `json:"user_id"`
+	UserName     string  `json:"user_name"`
+	PaymentCount int64   `json:"payment_count"`
+	Outstanding  float64 `json:"outstanding"`
+	OldestDue    string  `json:"oldest_due"`
+}
+
+// BuildTopDebtors mengurutkan pemain berdasarkan sisa tagihan terbuka.
+func BuildTopDebtors(db *gorm.DB, vendorID uint, r ReportRange, limit int) ([]Debtor, error) {
+	debtors := []Debtor{}
+	if limit <= 0 || limit > 100 {
+		limit = 10
+	}
+	q := db.Model(&models.Payment{}).
+		Select(`payments.user_id, COALESCE(MAX(users.name), MAX(payments.user_name)) AS user_name,
+			COUNT(*) AS payment_count,
+			SUM(payments.amount - payments.paid_amount) AS outstanding,
+			MIN(`+dueDateExpr+`)::text AS oldest_due`).
+		Joins("LEFT JOIN users ON users.id = payments.user_id").
+		Where("payments.vendor_id = ? AND payments.status IN ? AND payments.amount > payments.paid_amount", vendorID, openPaymentStatuses)
+	err := r.apply(q, "payments.date").
+		Group("payments.user_id").
+		Order("outstanding DESC").
+		Limit(limit).
+		Scan(&debtors).Error
+	return debtors, err
+}
+
+// dueDateExpr adalah tanggal jatuh tempo tagihan; tagihan tanpa due_date
+// dihitung dari tanggal tagihan atau tanggal dibuat.
+const dueDateExpr = `COALESCE(payments.due_date, payments.date, payments.created_at::date)`
+
+// AgingBucket adalah kelompok umur piutang berdasarkan hari lewat jatuh tempo.
+type AgingBucket struct {
+	Bucket       string  `json:"bucket"`
+	PaymentCount int64   `json:"payment_count"`
+	Outstanding  float64 `json:"outstanding"`
+}
+
+type AgingReport struct {
+	AsOf        string        `json:"as_of"`
+	Buckets     []AgingBucket `json:"buckets"`
+	Outstanding float64       `json:"outstanding"`
+}
+
+var agingBuckets = []string{"current", "0-30", "31-60", "61-90", "90+"}
+
+// BuildAgingReport mengelompokkan piutang terbuka per umur (hari lewat jatuh
+// tempo) pada tanggal asOf. Tagihan yang belum jatuh tempo masuk "current".
+func BuildAgingReport(db *gorm.DB, vendorID uint, r ReportRange, asOf time.Time) (AgingReport, error) {
+	report := AgingReport{AsOf: asOf.Format("2006-01-02"), Buckets: []AgingBucket{}}
+
+	age := "(?::date - " + dueDateExpr + ")"
+	bucket := `CASE
+		WHEN ` + age + ` < 0 THEN 'current'
+		WHEN ` + age + ` <= 30 THEN '0-30'
+		WHEN ` + age + ` <= 60 THEN '31-60'
+		WHEN ` + age + ` <= 90 THEN '61-90'
+		ELSE '90+' END`
+	asOfDate := report.AsOf
+	var rows []AgingBucket
+	q := db.Model(&models.Payment{}).
+		Select(bucket+` AS bucket, COUNT(*) AS payment_count, SUM(payments.amount - payments.paid_amount) AS outstanding`,
+			asOfDate, asOfDate, asOfDate, asOfDate).
+		Where("payments.vendor_id = ? AND payments.status IN ? AND payments.amount > payments.paid_amount", vendorID, openPaymentStatuses)
+	if err := r.apply(q, "payments.date").Group("bucket").Scan(&rows).Error; err != nil {
+		return report, err
+	}
+
+	byBucket := map[string]AgingBucket{}
+	for _, row := range rows {
+		byBucket[row.Bucket] = row
+	}
+	for _, name := range agingBuckets {
+		row := byBucket[name]
+		row.Bucket = name
+		report.Buckets = append(report.Buckets, row)
+		report.Outstanding += row.Outstanding
+	}
+	return report, nil
+}
+
+// percent menghitung part/total dalam persen dengan dua angka desimal.
+func percent(part, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(part/total*10000) / 100
+}
+
+// PercentChange adalah perubahan dari previous ke current dalam persen. Nil
+// bila previous nol.
+func PercentChange(current, previous float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	change := math.Round((current-previous)/math.Abs(previous)*10000) / 100
+	return &change
+}
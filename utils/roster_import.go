@@ -0,0 +1,362 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"ssb_api/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Sama dengan validasi email di Register.
+var rosterEmailRegex = regexp.MustCompile(`^[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}$`)
+
+// Masa berlaku tautan undangan pemain hasil import.
+const InvitationTTL = 14 * 24 * time.Hour
+
+// Jumlah baris maksimal dalam satu file import.
+const MaxRosterRows = 1000
+
+var (
+	ErrRosterHeader   = errors.New("roster header row not found, name and email columns are required")
+	ErrRosterEmpty    = errors.New("roster file has no player rows")
+	ErrRosterTooLarge = fmt.Errorf("roster file has more than %d rows", MaxRosterRows)
+)
+
+// Nama header yang dikenali per kolom, alternatif dipisah "|". Telepon dicek
+// sebelum nomor punggung supaya "nomor hp" tidak terbaca sebagai nomor.
+var rosterColumns = []struct {
+	field   string
+	columns string
+}{
+	{"name", "nama|name|nama lengkap"},
+	{"email", "email|e-mail"},
+	{"phone", "telepon|phone|no hp|nomor hp|no telepon|nomor telepon|whatsapp|hp"},
+	{"gender", "jenis kelamin|gender|kelamin"},
+	{"birth_date", "tanggal lahir|tgl lahir|birth date|birth_date|birthdate"},
+	{"position", "posisi|position"},
+	{"foot", "kaki|foot|kaki dominan"},
+	{"number", "nomor punggung|no punggung|jersey|number|nomor"},
+	{"age_category", "kategori usia|kategori|age category|age_category"},
+	{"address", "alamat|address"},
+}
+
+// Singkatan posisi yang umum dipakai di spreadsheet klub.
+var positionAliases = map[string]string{
+	"gk": "Kiper", "cb": "Bek Tengah", "rb": "Bek Kanan", "lb": "Bek Kiri",
+	"dm": "Gelandang Bertahan", "cdm": "Gelandang Bertahan", "cm": "Gelandang Tengah",
+	"am": "Gelandang Serang", "cam": "Gelandang Serang", "rw": "Sayap Kanan", "lw": "Sayap Kiri",
+	"st": "Penyerang", "cf": "Penyerang", "striker": "Penyerang", "goalkeeper": "Kiper",
+}
+
+// ParseRoster membaca file roster dan memvalidasi format setiap baris.
+// Duplikat dengan data yang sudah ada dicek terpisah lewat
+// CheckRosterDuplicates.
+func ParseRoster(raw []byte, format string) ([]models.RosterImportRow, error) {
+	rows, err := ReadSpreadsheet(raw, format)
+	if err != nil {
+		return nil, err
+	}
+
+	header, cols, ok := findRosterHeader(rows)
+	if !ok {
+		return nil, ErrRosterHeader
+	}
+
+	var result []models.RosterImportRow
+	for i, row := range rows[header+1:] {
+		if isBlankRow(row) {
+			continue
+		}
+		if len(result) == MaxRosterRows {
+			return nil, ErrRosterTooLarge
+		}
+		get := func(field string) string {
+			if col, ok := cols[field]; ok {
+				return cell(row, col)
+			}
+			return ""
+		}
+		result = append(result, parseRosterRow(header+i+2, get))
+	}
+	if len(result) == 0 {
+		return nil, ErrRosterEmpty
+	}
+	return result, nil
+}
+
+func findRosterHeader(rows [][]string) (int, map[string]int, bool) {
+	// Header boleh didahului beberapa baris judul
+	for i := 0; i < len(rows) && i < 10; i++ {
+		cols := map[string]int{}
+		for j, name := range rows[i] {
+			name = strings.ToLower(strings.TrimSpace(name))
+			for _, c := range rosterColumns {
+				if _, taken := cols[c.field]; !taken && matchesColumn(name, c.columns) {
+					cols[c.field] = j
+					break
+				}
+			}
+		}
+		_, hasName := cols["name"]
+		_, hasEmail := cols["email"]
+		if hasName && hasEmail {
+			return i, cols, true
+		}
+	}
+	return 0, nil, false
+}
+
+func parseRosterRow(line int, get func(string) string) models.RosterImportRow {
+	row := models.RosterImportRow{
+		Line:        line,
+		Name:        get("name"),
+		Email:       strings.ToLower(get("email")),
+		Phone:       normalizePhone(get("phone")),
+		AgeCategory: get("age_category"),
+		Address:     get("address"),
+	}
+	var errs []string
+
+	if row.Name == "" {
+		errs = append(errs, "name is required")
+	}
+	if row.Email == "" {
+		errs = append(errs, "email is required")
+	} else if !rosterEmailRegex.MatchString(row.Email) {
+		errs = append(errs, "invalid email format")
+	}
+	// Kolom phone unik di tabel users, jadi telepon kosong tidak bisa dipakai
+	// lebih dari satu pemain
+	if row.Phone == "" {
+		errs = append(errs, "phone is required")
+	}
+
+	if v := get("gender"); v != "" {
+		switch strings.ToLower(v) {
+		case "laki-laki", "laki laki", "l", "pria", "male", "m":
+			row.Gender = "Laki-laki"
+		case "perempuan", "p", "wanita", "female", "f":
+			row.Gender = "Perempuan"
+		default:
+			errs = append(errs, "gender must be Laki-laki or Perempuan")
+		}
+	}
+
+	if v := get("birth_date"); v != "" {
+		date, ok := parseBirthDate(v)
+		switch {
+		case !ok:
+			errs = append(errs, "birth date must use YYYY-MM-DD format")
+		case date.After(time.Now()):
+			errs = append(errs, "birth date is in the future")
+		default:
+			row.BirthDate = date.Format("2006-01-02")
+		}
+	}
+
+	if v := get("position"); v != "" {
+		if position, ok := normalizePosition(v); ok {
+			row.Position = position
+		} else {
+			errs = append(errs, fmt.Sprintf("unknown position %q", v))
+		}
+	}
+
+	if v := get("foot"); v != "" {
+		switch strings.ToLower(v) {
+		case "kanan", "right", "r":
+			row.Foot = "Kanan"
+		case "kiri", "left", "l":
+			row.Foot = "Kiri"
+		default:
+			errs = append(errs, "foot must be Kanan or Kiri")
+		}
+	}
+
+	if v := get("number"); v != "" {
+		// Angka dari XLSX bisa tertulis "10.0"
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || n != float64(int(n)) || n < 1 || n > 99 {
+			errs = append(errs, "jersey number must be between 1 and 99")
+		} else {
+			row.Number = int(n)
+		}
+	}
+
+	row.Errors = strings.Join(errs, "; ")
+	return row
+}
+
+// CheckRosterDuplicates menandai baris dengan email atau telepon yang sudah
+// terdaftar, nomor punggung yang sudah dipakai pemain lain di vendor, atau
+// yang kembar dengan baris sebelumnya di file yang sama.
+func CheckRosterDuplicates(db *gorm.DB, vendorID uint, rows []models.RosterImportRow) error {
+	var emails, phones []string
+	var numbers []int
+	for _, r := range rows {
+		emails = append(emails, r.Email)
+		phones = append(phones, r.Phone)
+		if r.Number > 0 {
+			numbers = append(numbers, r.Number)
+		}
+	}
+
+	var existing []models.User
+	if err := db.Select("email", "phone").
+		Where("LOWER(email) IN ? OR phone IN ?", emails, phones).Find(&existing).Error; err != nil {
+		return err
+	}
+	takenEmail, takenPhone := map[string]bool{}, map[string]bool{}
+	for _, u := range existing {
+		takenEmail[strings.ToLower(u.Email)] = true
+		if u.Phone != "" {
+			takenPhone[u.Phone] = true
+		}
+	}
+
+	takenNumber := map[int]bool{}
+	if len(numbers) > 0 {
+		var used []int
+		if err := db.Model(&models.User{}).Where("vendor_id = ? AND number IN ?", vendorID, numbers).
+			Pluck("number", &used).Error; err != nil {
+			return err
+		}
+		for _, n := range used {
+			takenNumber[n] = true
+		}
+	}
+
+	seenEmail, seenPhone, seenNumber := map[string]int{}, map[string]int{}, map[int]int{}
+	for i := range rows {
+		r := &rows[i]
+		var errs []string
+		if r.Email != "" {
+			if line, ok := seenEmail[r.Email]; ok {
+				errs = append(errs, fmt.Sprintf("duplicate email with row %d", line))
+			} else {
+				seenEmail[r.Email] = r.Line
+				if takenEmail[r.Email] {
+					errs = append(errs, "email already registered")
+				}
+			}
+		}
+		if r.Phone != "" {
+			if line, ok := seenPhone[r.Phone]; ok {
+				errs = append(errs, fmt.Sprintf("duplicate phone with row %d", line))
+			} else {
+				seenPhone[r.Phone] = r.Line
+				if takenPhone[r.Phone] {
+					errs = append(errs, "phone already registered")
+				}
+			}
+		}
+		if r.Number > 0 {
+			if line, ok := seenNumber[r.Number]; ok {
+				errs = append(errs, fmt.Sprintf("jersey number %d also used in row %d", r.Number, line))
+			} else {
+				seenNumber[r.Number] = r.Line
+				if takenNumber[r.Number] {
+					errs = append(errs, fmt.Sprintf("jersey number %d already used by another player", r.Number))
+				}
+			}
+		}
+		if len(errs) > 0 {
+			if r.Errors != "" {
+				errs = append([]string{r.Errors}, errs...)
+			}
+			r.Errors = strings.Join(errs, "; ")
+		}
+	}
+	return nil
+}
+
+// NewInvitation membuat undangan untuk user beserta token mentahnya. Token
+// hanya dikembalikan sekali, database menyimpan hash-nya.
+func NewInvitation(user models.User) (models.UserInvitation, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return models.UserInvitation{}, "", err
+	}
+	token := hex.EncodeToString(buf)
+	return models.UserInvitation{
+		UserID:    user.ID,
+		VendorID:  user.VendorID,
+		TokenHash: HashInvitationToken(token),
+		ExpiresAt: time.Now().Add(InvitationTTL),
+	}, token, nil
+}
+
+func HashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// InvitationLink adalah halaman frontend untuk membuat password.
+func InvitationLink(token string) string {
+	return strings.TrimRight(os.Getenv("BASE_URL_F"), "/") + "/invitation/" + token
+}
+
+// parseBirthDate menerima YYYY-MM-DD, DD/MM/YYYY, atau nomor seri tanggal
+// dari sel XLSX.
+func parseBirthDate(text string) (time.Time, bool) {
+	for _, layout := range []string{"2006-01-02", "02/01/2006", "2/1/2006"} {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t, true
+		}
+	}
+	if !strings.ContainsAny(text, "-/") {
+		return SpreadsheetDate(text)
+	}
+	return time.Time{}, false
+}
+
+func normalizePosition(text string) (string, bool) {
+	key := strings.ToLower(strings.TrimSpace(text))
+	if position, ok := positionAliases[key]; ok {
+		return position, true
+	}
+	for _, position := range models.PlayerPositions {
+		if strings.ToLower(position) == key {
+			return position, true
+		}
+	}
+	return "", false
+}
+
+// normalizePhone membuang spasi dan tanda baca, "+62" dan "62" diubah ke
+// awalan "0" supaya nomor yang sama tidak lolos cek duplikat.
+func normalizePhone(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	phone := b.String()
+	switch {
+	case strings.HasPrefix(phone, "62"):
+		phone = "0" + phone[2:]
+	case strings.HasPrefix(phone, "8"):
+		// Sel angka di Excel membuang nol di depan
+		phone = "0" + phone
+	}
+	return phone
+}
+
+func isBlankRow(row []string) bool {
+	for _, v := range row {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
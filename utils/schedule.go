@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"errors"
+	"ssb_api/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrScheduleRequired = errors.New("start_at or date is required")
+	ErrInvalidSchedule  = errors.New("end time must be after start time")
+	ErrInvalidTimezone  = errors.New("invalid timezone, use an IANA name such as Asia/Jakarta, Asia/Makassar or Asia/Jayapura")
+)
+
+// ScheduleInput adalah jadwal dari request. start_at dan end_at (ISO-8601)
+// diutamakan, date, time dan end_time format lama masih diterima.
+type ScheduleInput struct {
+	StartAt string
+	EndAt   string
+	Date    string
+	Time    string
+	EndTime string
+}
+
+// Empty berarti request tidak mengirim jadwal.
+func (in ScheduleInput) Empty() bool {
+	return in.StartAt == "" && in.Date == ""
+}
+
+// Resolve menghitung waktu mulai dan selesai. Waktu tanpa offset dianggap
+// jam lokal loc, tanpa jam selesai dianggap berlangsung DefaultEventDuration.
+func (in ScheduleInput) Resolve(loc *time.Location) (time.Time, time.Time, error) {
+	var start, end time.Time
+	var err error
+	switch {
+	case in.StartAt != "":
+		start, err = models.ParseTimestamp(in.StartAt, loc)
+	case in.Date != "":
+		start, err = models.ParseLocalDateTime(in.Date, in.Time, loc)
+	default:
+		err = ErrScheduleRequired
+	}
+	if err != nil {
+		return start, end, err
+	}
+	start = start.In(loc)
+
+	switch {
+	case in.EndAt != "":
+		end, err = models.ParseTimestamp(in.EndAt, loc)
+	case in.EndTime != "":
+		end, err = models.ParseLocalDateTime(start.Format(models.DateLayout), in.EndTime, loc)
+	default:
+		end = start.Add(models.DefaultEventDuration)
+	}
+	if err != nil {
+		return start, end, err
+	}
+	if !end.After(start) {
+		return start, end, ErrInvalidSchedule
+	}
+	return start, end.In(loc), nil
+}
+
+// ValidateTimezone memeriksa nama zona waktu IANA vendor.
+func ValidateTimezone(name string) error {
+	if name == "" {
+		return ErrInvalidTimezone
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return ErrInvalidTimezone
+	}
+	return nil
+}
+
+// VendorLocation memuat zona waktu vendor, DefaultTimezone bila vendor
+// tidak ditemukan.
+func VendorLocation(db *gorm.DB, vendorID uint) *time.Location {
+	var vendor models.Vendor
+	db.Select("id", "timezone").First(&vendor, vendorID)
+	return vendor.Location()
+}
+
+// LocalDayRange mengubah rentang tanggal [from, to] menjadi rentang waktu
+// [start, end) di zona waktu loc.
+func LocalDayRange(from, to models.Date, loc *time.Location) (time.Time, time.Time) {
+	start, _ := from.Time(loc)
+	end, _ := to.Time(loc)
+	return start, end.AddDate(0, 0, 1)
+}
+
+// SeriesSchedule menghitung jadwal pertemuan series pada tanggal date.
+func SeriesSchedule(series models.EventSeries, date string, loc *time.Location) (time.Time, time.Time, error) {
+	start, err := models.ParseLocalDateTime(date, series.Time, loc)
+	if err != nil {
+		return start, start, err
+	}
+	duration := models.DefaultEventDuration
+	if series.DurationMinutes > 0 {
+		duration = time.Duration(series.DurationMinutes) * time.Minute
+	}
+	return start, start.Add(duration), nil
+}
+
+// ReanchorVendorSchedules memindahkan event dan match vendor yang belum
+// lewat ke zona waktu baru dengan jam lokal yang sama, misal saat vendor
+// mengganti WIB menjadi WITA.
+func ReanchorVendorSchedules(tx *gorm.DB, vendorID uint, from, to *time.Location) error {
+	var events []models.Event
+	if err := tx.Where("vendor_id = ? AND is_finish = ? AND start_at IS NOT NULL", vendorID, false).
+		Find(&events).Error; err != nil {
+		return err
+	}
+	for i := range events {
+		e := &events[i]
+		start, end := reanchorSchedule(e.StartAt, e.EndAt, from, to)
+		e.SetSchedule(start, end, to)
+		if err := tx.Model(e).Select("start_at", "end_at", "date", "time").Updates(e).Error; err != nil {
+			return err
+		}
+	}
+
+	var matches []models.Match
+	if err := tx.Where("vendor_id = ? AND start_at >= ?", vendorID, time.Now()).Find(&matches).Error; err != nil {
+		return err
+	}
+	for i := range matches {
+		m := &matches[i]
+		start, end := reanchorSchedule(m.StartAt, m.EndAt, from, to)
+		m.SetSchedule(start, end, to)
+		if err := tx.Model(m).Select("start_at", "end_at", "date").Updates(m).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reanchorSchedule membaca ulang jam lokal di zona from sebagai jam di zona to.
+func reanchorSchedule(startAt, endAt *time.Time, from, to *time.Location) (time.Time, time.Time) {
+	local := startAt.In(from)
+	start := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(), 0, to)
+	duration := models.DefaultEventDuration
+	if endAt != nil && endAt.After(*startAt) {
+		duration = endAt.Sub(*startAt)
+	}
+	return start, start.Add(duration)
+}
@@ -0,0 +1,235 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Batas ukuran sheet Excel, sekaligus mencegah referensi sel palsu membuat
+// slice raksasa.
+const (
+	xlsxMaxRows    = 1048576
+	xlsxMaxColumns = 16384
+)
+
+var ErrInvalidSpreadsheet = errors.New("file is not a valid CSV or XLSX spreadsheet")
+
+// SpreadsheetFormat menebak format file dari nama file, dengan cadangan isi
+// file (XLSX selalu berupa zip yang diawali "PK").
+func SpreadsheetFormat(filename string, raw []byte) string {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".xlsx":
+		return ExportFormatXLSX
+	case ".csv":
+		return ExportFormatCSV
+	}
+	if bytes.HasPrefix(raw, []byte("PK\x03\x04")) {
+		return ExportFormatXLSX
+	}
+	return ExportFormatCSV
+}
+
+// ReadSpreadsheet membaca seluruh baris sheet pertama. Baris kosong tetap
+// dikembalikan supaya nomor baris sama dengan yang terlihat di Excel.
+func ReadSpreadsheet(raw []byte, format string) ([][]string, error) {
+	switch format {
+	case ExportFormatCSV:
+		raw = bytes.TrimPrefix(raw, []byte("\xef\xbb\xbf"))
+		reader := csv.NewReader(bytes.NewReader(raw))
+		reader.Comma = detectDelimiter(raw)
+		reader.FieldsPerRecord = -1
+		reader.LazyQuotes = true
+		return reader.ReadAll()
+	case ExportFormatXLSX:
+		return readXLSX(raw)
+	}
+	return nil, ErrUnknownExportFormat
+}
+
+// SpreadsheetDate mengubah nomor seri tanggal Excel (misal 45292) menjadi
+// tanggal. Sel tanggal di XLSX disimpan sebagai angka tersebut.
+func SpreadsheetDate(text string) (time.Time, bool) {
+	serial, err := strconv.ParseFloat(text, 64)
+	if err != nil || serial < 1 || serial > 2958465 {
+		return time.Time{}, false
+	}
+	return time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).AddDate(0, 0, int(serial)), true
+}
+
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+// String menggabungkan teks biasa dan rich text (beberapa run berformat).
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+type xlsxSheetData struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func readXLSX(raw []byte) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		return nil, ErrInvalidSpreadsheet
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var shared []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		var sst struct {
+			Items []xlsxText `xml:"si"`
+		}
+		if err := decodeZipXML(f, &sst); err != nil {
+			return nil, err
+		}
+		for _, item := range sst.Items {
+			shared = append(shared, item.String())
+		}
+	}
+
+	sheet, ok := files[firstSheetPath(files)]
+	if !ok {
+		return nil, ErrInvalidSpreadsheet
+	}
+	var data xlsxSheetData
+	if err := decodeZipXML(sheet, &data); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, r := range data.Rows {
+		index := len(rows)
+		if r.R > 0 {
+			index = r.R - 1
+		}
+		if index >= xlsxMaxRows {
+			return nil, ErrInvalidSpreadsheet
+		}
+		for len(rows) <= index {
+			rows = append(rows, nil)
+		}
+
+		var row []string
+		for _, c := range r.Cells {
+			col := len(row)
+			if c.Ref != "" {
+				col = xlsxColumnIndex(c.Ref)
+			}
+			if col < 0 || col >= xlsxMaxColumns {
+				continue
+			}
+			for len(row) <= col {
+				row = append(row, "")
+			}
+
+			switch c.Type {
+			case "s":
+				if i, err := strconv.Atoi(c.Value); err == nil && i >= 0 && i < len(shared) {
+					row[col] = shared[i]
+				}
+			case "inlineStr":
+				row[col] = c.Inline.String()
+			case "e":
+				// Sel berisi error formula (#N/A, #REF!) dianggap kosong
+			default:
+				row[col] = c.Value
+			}
+		}
+		rows[index] = row
+	}
+	return rows, nil
+}
+
+// firstSheetPath mencari file sheet pertama lewat workbook.xml dan
+// relasinya, dengan cadangan nama default xl/worksheets/sheet1.xml.
+func firstSheetPath(files map[string]*zip.File) string {
+	const fallback = "xl/worksheets/sheet1.xml"
+
+	var workbook struct {
+		Sheets []struct {
+			RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Items []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	wb, ok := files["xl/workbook.xml"]
+	if !ok || decodeZipXML(wb, &workbook) != nil || len(workbook.Sheets) == 0 {
+		return fallback
+	}
+	rf, ok := files["xl/_rels/workbook.xml.rels"]
+	if !ok || decodeZipXML(rf, &rels) != nil {
+		return fallback
+	}
+	for _, rel := range rels.Items {
+		if rel.ID != workbook.Sheets[0].RelID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/")
+		}
+		return path.Join("xl", rel.Target)
+	}
+	return fallback
+}
+
+func decodeZipXML(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(io.LimitReader(rc, 64<<20)).Decode(v); err != nil {
+		return ErrInvalidSpreadsheet
+	}
+	return nil
+}
+
+// xlsxColumnIndex mengubah referensi sel seperti "AB12" menjadi indeks kolom
+// mulai 0. Kebalikan dari xlsxColumn.
+func xlsxColumnIndex(ref string) int {
+	n := 0
+	for _, r := range strings.ToUpper(ref) {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		if n = n*26 + int(r-'A'+1); n > xlsxMaxColumns {
+			return -1
+		}
+	}
+	return n - 1
+}
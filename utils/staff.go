@@ -0,0 +1,385 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"ssb_api/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidStaff      = errors.New("staff must be distinct coaches of this vendor, role head_coach or assistant, with at most one head coach")
+	ErrStaffEventMissing = errors.New("training event not found")
+)
+
+// CoachTravelSpeedKmh adalah perkiraan kecepatan perjalanan pelatih antar
+// venue di dalam kota, dipakai untuk peringatan agenda.
+const CoachTravelSpeedKmh = 30.0
+
+// Jangkauan agenda pelatih: bawaan bila end_date kosong dan maksimalnya.
+const (
+	DefaultAgendaDays = 14
+	MaxAgendaDays     = 62
+)
+
+// SessionRef menunjuk satu sesi (event, training atau match) beserta
+// jadwalnya. EventID adalah event induk: sesi dengan event induk yang sama
+// dianggap satu kegiatan dan tidak saling bentrok.
+type SessionRef struct {
+	Type     string
+	ID       uint
+	VendorID uint
+	EventID  *uint
+	Start    *time.Time
+	End      *time.Time
+}
+
+// EventSession membuat SessionRef untuk event.
+func EventSession(e models.Event) SessionRef {
+	ref := SessionRef{Type: models.SessionEvent, ID: e.ID, VendorID: e.VendorID, EventID: &e.ID}
+	if !e.IsCancelled {
+		ref.Start, ref.End = sessionRange(e.StartAt, e.EndAt)
+	}
+	return ref
+}
+
+// MatchSession membuat SessionRef untuk match.
+func MatchSession(m models.Match) SessionRef {
+	ref := SessionRef{Type: models.SessionMatch, ID: m.ID, EventID: m.EventID}
+	if m.VendorID != nil {
+		ref.VendorID = *m.VendorID
+	}
+	ref.Start, ref.End = sessionRange(m.StartAt, m.EndAt)
+	return ref
+}
+
+// TrainingSession membuat SessionRef untuk training. Training tidak punya
+// jadwal sendiri, jadwalnya diambil dari event yang ditautkan.
+func TrainingSession(db *gorm.DB, t models.Training) (SessionRef, error) {
+	ref := SessionRef{Type: models.SessionTraining, ID: t.ID, EventID: t.EventID}
+	if t.VendorID != nil {
+		ref.VendorID = *t.VendorID
+	}
+	if t.EventID == nil {
+		return ref, nil
+	}
+	var event models.Event
+	if err := db.First(&event, *t.EventID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ref, ErrStaffEventMissing
+		}
+		return ref, err
+	}
+	if !event.IsCancelled {
+		ref.Start, ref.End = sessionRange(event.StartAt, event.EndAt)
+	}
+	return ref, nil
+}
+
+func sessionRange(start, end *time.Time) (*time.Time, *time.Time) {
+	if start == nil {
+		return nil, nil
+	}
+	if end == nil {
+		e := start.Add(models.DefaultEventDuration)
+		end = &e
+	}
+	return start, end
+}
+
+// StaffConflictError dikembalikan bila pelatih sudah bertugas di sesi lain
+// pada jam yang bentrok.
+type StaffConflictError struct {
+	Coach   string
+	Session StaffSession
+	Loc     *time.Location
+}
+
+func (e StaffConflictError) Error() string {
+	return fmt.Sprintf("%s is already assigned to %s %q from %s to %s", e.Coach, e.Session.Type, e.Session.Title,
+		e.Session.Start.In(e.Loc).Format("2006-01-02 15:04"), e.Session.End.In(e.Loc).Format("15:04"))
+}
+
+// SetStaff mengganti pelatih sesi. Pelatih harus berperan pelatih atau admin
+// di vendor yang sama dan tidak sedang bertugas di sesi lain pada jam yang
+// bentrok. Baris user pelatih dikunci supaya dua penugasan bersamaan tidak
+// sama-sama lolos.
+func SetStaff(tx *gorm.DB, ref SessionRef, staff []models.StaffAssignment, loc *time.Location) ([]models.StaffAssignment, error) {
+	heads := 0
+	seen := map[uint]bool{}
+	var userIDs []uint
+	for _, s := range staff {
+		if s.UserID == 0 || seen[s.UserID] {
+			return nil, ErrInvalidStaff
+		}
+		switch s.Role {
+		case models.StaffHeadCoach:
+			heads++
+		case models.StaffAssistant:
+		default:
+			return nil, ErrInvalidStaff
+		}
+		seen[s.UserID] = true
+		userIDs = append(userIDs, s.UserID)
+	}
+	if heads > 1 {
+		return nil, ErrInvalidStaff
+	}
+
+	coaches := map[uint]models.User{}
+	if len(userIDs) > 0 {
+		var users []models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ? AND vendor_id = ? AND role IN ?", userIDs, ref.VendorID, []string{models.RolePelatih, models.RoleAdmin}).
+			Order("id ASC").Find(&users).Error; err != nil {
+			return nil, err
+		}
+		if len(users) != len(userIDs) {
+			return nil, ErrInvalidStaff
+		}
+		for _, u := range users {
+			coaches[u.ID] = u
+		}
+	}
+
+	assigned := make([]models.StaffAssignment, 0, len(staff))
+	for _, s := range staff {
+		assigned = append(assigned, models.StaffAssignment{
+			VendorID:    ref.VendorID,
+			SessionType: ref.Type,
+			SessionID:   ref.ID,
+			UserID:      s.UserID,
+			UserName:    coaches[s.UserID].Name,
+			Role:        s.Role,
+		})
+	}
+	if err := checkStaffConflicts(tx, ref, assigned, loc); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Where("session_type = ? AND session_id = ?", ref.Type, ref.ID).
+		Delete(&models.StaffAssignment{}).Error; err != nil {
+		return nil, err
+	}
+	if len(assigned) > 0 {
+		if err := tx.Create(&assigned).Error; err != nil {
+			return nil, err
+		}
+	}
+	return assigned, nil
+}
+
+// CheckSessionStaff memeriksa ulang pelatih sesi setelah jadwalnya berubah
+// dan mengembalikan daftar pelatihnya.
+func CheckSessionStaff(tx *gorm.DB, ref SessionRef, loc *time.Location) ([]models.StaffAssignment, error) {
+	staff, err := SessionStaff(tx, ref.Type, ref.ID)
+	if err != nil {
+		return nil, err
+	}
+	return staff, checkStaffConflicts(tx, ref, staff, loc)
+}
+
+// CheckEventStaff memeriksa ulang pelatih event dan training yang jadwalnya
+// mengikuti event tersebut, dipakai setelah event dipindah jam atau
+// dipulihkan. Hasilnya pelatih event itu sendiri.
+func CheckEventStaff(tx *gorm.DB, event models.Event, loc *time.Location) ([]models.StaffAssignment, error) {
+	ref := EventSession(event)
+	staff, err := CheckSessionStaff(tx, ref, loc)
+	if err != nil {
+		return nil, err
+	}
+	var trainings []models.Training
+	if err := tx.Where("event_id = ?", event.ID).Find(&trainings).Error; err != nil {
+		return nil, err
+	}
+	for _, t := range trainings {
+		ref := SessionRef{Type: models.SessionTraining, ID: t.ID, VendorID: event.VendorID, EventID: &event.ID, Start: ref.Start, End: ref.End}
+		if _, err := CheckSessionStaff(tx, ref, loc); err != nil {
+			return nil, err
+		}
+	}
+	return staff, nil
+}
+
+// SessionStaff mengambil pelatih satu sesi, head coach lebih dulu.
+func SessionStaff(db *gorm.DB, sessionType string, sessionID uint) ([]models.StaffAssignment, error) {
+	var staff []models.StaffAssignment
+	err := db.Where("session_type = ? AND session_id = ?", sessionType, sessionID).
+		Order("role DESC, id ASC").Find(&staff).Error
+	return staff, err
+}
+
+func checkStaffConflicts(tx *gorm.DB, ref SessionRef, staff []models.StaffAssignment, loc *time.Location) error {
+	if ref.Start == nil || len(staff) == 0 {
+		return nil
+	}
+	var userIDs []uint
+	names := map[uint]string{}
+	for _, s := range staff {
+		userIDs = append(userIDs, s.UserID)
+		names[s.UserID] = s.UserName
+	}
+	sessions, err := StaffSessions(tx, userIDs, *ref.Start, *ref.End)
+	if err != nil {
+		return err
+	}
+	for _, s := range sessions {
+		if s.Type == ref.Type && s.ID == ref.ID {
+			continue
+		}
+		if sameParentEvent(ref.EventID, s.EventID) {
+			continue
+		}
+		return StaffConflictError{Coach: names[s.UserID], Session: s, Loc: loc}
+	}
+	return nil
+}
+
+func sameParentEvent(a, b *uint) bool {
+	return a != nil && b != nil && *a == *b
+}
+
+// StaffSession adalah satu sesi yang ditugaskan ke seorang pelatih.
+type StaffSession struct {
+	Type          string    `json:"type"`
+	ID            uint      `json:"id"`
+	EventID       *uint     `json:"event_id,omitempty"`
+	UserID        uint      `json:"user_id"`
+	Role          string    `json:"role"`
+	Title         string    `json:"title"`
+	Start         time.Time `json:"start_at"`
+	End           time.Time `json:"end_at"`
+	Location      string    `json:"location"`
+	LocationPoint string    `json:"location_point,omitempty"`
+}
+
+// StaffSessions mengambil semua sesi pelatih yang beririsan dengan
+// [from, to), urut jam mulai. Event yang dibatalkan tidak ikut.
+func StaffSessions(db *gorm.DB, userIDs []uint, from, to time.Time) ([]StaffSession, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	fallback := int(models.DefaultEventDuration.Minutes())
+	var sessions []StaffSession
+
+	// Event
+	var events []StaffSession
+	if err := db.Model(&models.StaffAssignment{}).
+		Select(`'event' AS type, events.id AS id, events.id AS event_id, staff_assignments.user_id, staff_assignments.role,
+			events.title, events.start_at AS start, COALESCE(events.end_at, events.start_at + ? * INTERVAL '1 minute') AS "end",
+			events.location, events.location_point`, fallback).
+		Joins("JOIN events ON events.id = staff_assignments.session_id AND events.deleted_at IS NULL").
+		Where("staff_assignments.session_type = ? AND staff_assignments.user_id IN ? AND events.is_cancelled = ?", models.SessionEvent, userIDs, false).
+		Where("events.start_at < ? AND COALESCE(events.end_at, events.start_at + ? * INTERVAL '1 minute') > ?", to, fallback, from).
+		Scan(&events).Error; err != nil {
+		return nil, err
+	}
+	sessions = append(sessions, events...)
+
+	// Training, jadwal dari event yang ditautkan
+	var trainings []StaffSession
+	if err := db.Model(&models.StaffAssignment{}).
+		Select(`'training' AS type, trainings.id AS id, trainings.event_id, staff_assignments.user_id, staff_assignments.role,
+			events.title, events.start_at AS start, COALESCE(events.end_at, events.start_at + ? * INTERVAL '1 minute') AS "end",
+			events.location, events.location_point`, fallback).
+		Joins("JOIN trainings ON trainings.id = staff_assignments.session_id AND trainings.deleted_at IS NULL").
+		Joins("JOIN events ON events.id = trainings.event_id AND events.deleted_at IS NULL").
+		Where("staff_assignments.session_type = ? AND staff_assignments.user_id IN ? AND events.is_cancelled = ?", models.SessionTraining, userIDs, false).
+		Where("events.start_at < ? AND COALESCE(events.end_at, events.start_at + ? * INTERVAL '1 minute') > ?", to, fallback, from).
+		Scan(&trainings).Error; err != nil {
+		return nil, err
+	}
+	sessions = append(sessions, trainings...)
+
+	// Match, titik lokasi dari event induk bila ada
+	var matches []StaffSession
+	if err := db.Model(&models.StaffAssignment{}).
+		Select(`'match' AS type, matches.id AS id, matches.event_id, staff_assignments.user_id, staff_assignments.role,
+			matches.title, matches.start_at AS start, COALESCE(matches.end_at, matches.start_at + ? * INTERVAL '1 minute') AS "end",
+			matches.location, COALESCE(events.location_point, '') AS location_point`, fallback).
+		Joins("JOIN matches ON matches.id = staff_assignments.session_id AND matches.deleted_at IS NULL").
+		Joins("LEFT JOIN events ON events.id = matches.event_id AND events.deleted_at IS NULL").
+		Where("staff_assignments.session_type = ? AND staff_assignments.user_id IN ?", models.SessionMatch, userIDs).
+		Where("matches.start_at < ? AND COALESCE(matches.end_at, matches.start_at + ? * INTERVAL '1 minute') > ?", to, fallback, from).
+		Scan(&matches).Error; err != nil {
+		return nil, err
+	}
+	sessions = append(sessions, matches...)
+
+	sort.SliceStable(sessions, func(i, j int) bool {
+		if !sessions[i].Start.Equal(sessions[j].Start) {
+			return sessions[i].Start.Before(sessions[j].Start)
+		}
+		return sessions[i].Type < sessions[j].Type
+	})
+	return sessions, nil
+}
+
+// Jenis peringatan agenda.
+const (
+	AgendaWarningOverlap = "overlap" // dua sesi bentrok, biasanya data lama sebelum validasi
+	AgendaWarningTravel  = "travel"  // jeda terlalu singkat untuk pindah venue
+)
+
+// AgendaWarning menandai dua sesi berurutan yang bermasalah.
+type AgendaWarning struct {
+	Kind          string       `json:"kind"`
+	From          StaffSession `json:"from"`
+	To            StaffSession `json:"to"`
+	GapMinutes    int          `json:"gap_minutes"`
+	DistanceKm    float64      `json:"distance_km,omitempty"`
+	TravelMinutes int          `json:"travel_minutes,omitempty"`
+	Message       string       `json:"message"`
+}
+
+// AgendaWarnings memeriksa sesi berurutan seorang pelatih (urut jam mulai).
+// Sesi dengan jeda lebih singkat dari perkiraan waktu tempuh antar venue
+// (CoachTravelSpeedKmh) diberi peringatan. Sesi tanpa titik lokasi
+// dilewati.
+func AgendaWarnings(sessions []StaffSession) []AgendaWarning {
+	warnings := []AgendaWarning{}
+	for i := 1; i < len(sessions); i++ {
+		prev, next := sessions[i-1], sessions[i]
+		if sameParentEvent(prev.EventID, next.EventID) {
+			continue
+		}
+		gap := next.Start.Sub(prev.End)
+		if gap < 0 {
+			warnings = append(warnings, AgendaWarning{
+				Kind:       AgendaWarningOverlap,
+				From:       prev,
+				To:         next,
+				GapMinutes: int(gap.Minutes()),
+				Message:    fmt.Sprintf("%q overlaps with %q", prev.Title, next.Title),
+			})
+			continue
+		}
+
+		lat1, lng1, ok1 := ParseLocationPoint(prev.LocationPoint)
+		lat2, lng2, ok2 := ParseLocationPoint(next.LocationPoint)
+		if !ok1 || !ok2 {
+			continue
+		}
+		km := HaversineDistance(lat1, lng1, lat2, lng2) / 1000
+		travel := time.Duration(km / CoachTravelSpeedKmh * float64(time.Hour))
+		if travel <= gap {
+			continue
+		}
+		warnings = append(warnings, AgendaWarning{
+			Kind:          AgendaWarningTravel,
+			From:          prev,
+			To:            next,
+			GapMinutes:    int(gap.Minutes()),
+			DistanceKm:    math.Round(km*10) / 10,
+			TravelMinutes: int(math.Ceil(travel.Minutes())),
+			Message: fmt.Sprintf("%.1f km between %q and %q needs about %d minutes, only %d minutes apart",
+				km, prev.Title, next.Title, int(math.Ceil(travel.Minutes())), int(gap.Minutes())),
+		})
+	}
+	return warnings
+}
@@ -0,0 +1,286 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"ssb_api/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrVenueNotFound       = errors.New("venue not found")
+	ErrPitchNotFound       = errors.New("pitch not found at this venue")
+	ErrVenueClosed         = errors.New("event is outside the venue opening hours")
+	ErrInvalidOpeningHours = errors.New("opening hours need a weekday 0-6 and open before close (HH:MM)")
+)
+
+// MaxAvailabilityDays membatasi rentang tanggal endpoint ketersediaan.
+const MaxAvailabilityDays = 31
+
+// PitchConflictError dikembalikan bila lapangan sudah dipesan event lain
+// pada jam yang bentrok.
+type PitchConflictError struct {
+	Event models.Event
+	Loc   *time.Location
+}
+
+func (e PitchConflictError) Error() string {
+	start, end := bookingRange(e.Event)
+	return fmt.Sprintf("pitch is already booked by %q from %s to %s", e.Event.Title,
+		start.In(e.Loc).Format("2006-01-02 15:04"), end.In(e.Loc).Format("15:04"))
+}
+
+// NormalizeOpeningHours memeriksa jam buka dan menyeragamkan formatnya ke
+// HH:MM, diurutkan per hari lalu jam buka.
+func NormalizeOpeningHours(hours []models.OpeningHours) ([]models.OpeningHours, error) {
+	normalized := make([]models.OpeningHours, 0, len(hours))
+	for _, h := range hours {
+		opensAt, errOpen := models.ParseClock(h.Open)
+		closesAt, errClose := models.ParseClock(h.Close)
+		if h.Weekday < 0 || h.Weekday > 6 || errOpen != nil || errClose != nil || closesAt <= opensAt {
+			return nil, ErrInvalidOpeningHours
+		}
+		normalized = append(normalized, models.OpeningHours{Weekday: h.Weekday, Open: opensAt, Close: closesAt})
+	}
+	sort.Slice(normalized, func(i, j int) bool {
+		if normalized[i].Weekday != normalized[j].Weekday {
+			return normalized[i].Weekday < normalized[j].Weekday
+		}
+		return normalized[i].Open < normalized[j].Open
+	})
+	return normalized, nil
+}
+
+// ApplyVenue memeriksa venue dan lapangan event lalu mengisi Location dan
+// LocationPoint yang kosong dari venue. Lapangan tanpa venue memakai venue
+// lapangan tersebut.
+func ApplyVenue(tx *gorm.DB, event *models.Event) error {
+	if event.PitchID != nil && event.VenueID == nil {
+		var pitch models.Pitch
+		if err := tx.Where("vendor_id = ?", event.VendorID).First(&pitch, *event.PitchID).Error; err != nil {
+			return ErrPitchNotFound
+		}
+		event.VenueID = &pitch.VenueID
+	}
+	if event.VenueID == nil {
+		return nil
+	}
+
+	var venue models.Venue
+	if err := tx.Where("vendor_id = ?", event.VendorID).First(&venue, *event.VenueID).Error; err != nil {
+		return ErrVenueNotFound
+	}
+	location := venue.Name
+	if event.PitchID != nil {
+		var pitch models.Pitch
+		if err := tx.Where("venue_id = ?", venue.ID).First(&pitch, *event.PitchID).Error; err != nil {
+			return ErrPitchNotFound
+		}
+		location += " - " + pitch.Name
+	}
+	if event.Location == "" {
+		event.Location = location
+	}
+	if event.LocationPoint == "" {
+		event.LocationPoint = venue.LocationPoint
+	}
+	return nil
+}
+
+// CheckPitchBooking menolak event yang di luar jam buka venue atau bentrok
+// dengan event lain di lapangan yang sama. Baris lapangan dikunci sampai
+// transaksi selesai supaya dua pemesanan bersamaan tidak sama-sama lolos.
+// Event yang dibatalkan tidak memesan lapangan.
+func CheckPitchBooking(tx *gorm.DB, event models.Event, loc *time.Location) error {
+	if event.PitchID == nil || event.StartAt == nil || event.IsCancelled {
+		return nil
+	}
+	var pitch models.Pitch
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&pitch, *event.PitchID).Error; err != nil {
+		return ErrPitchNotFound
+	}
+	var venue models.Venue
+	if err := tx.First(&venue, pitch.VenueID).Error; err != nil {
+		return ErrVenueNotFound
+	}
+
+	start, end := bookingRange(event)
+	if !venueOpen(venue.OpeningHours, start, end, loc) {
+		return ErrVenueClosed
+	}
+
+	var conflict models.Event
+	err := tx.Where("pitch_id = ? AND id <> ? AND is_cancelled = ?", pitch.ID, event.ID, false).
+		Where("start_at < ? AND COALESCE(end_at, start_at + ? * INTERVAL '1 minute') > ?",
+			end, int(models.DefaultEventDuration.Minutes()), start).
+		Order("start_at ASC").First(&conflict).Error
+	switch {
+	case err == nil:
+		return PitchConflictError{Event: conflict, Loc: loc}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil
+	default:
+		return err
+	}
+}
+
+// bookingRange adalah jam pemakaian lapangan oleh event.
+func bookingRange(e models.Event) (time.Time, time.Time) {
+	if e.StartAt == nil {
+		return time.Time{}, time.Time{}
+	}
+	end := e.StartAt.Add(models.DefaultEventDuration)
+	if e.EndAt != nil {
+		end = *e.EndAt
+	}
+	return *e.StartAt, end
+}
+
+// venueOpen cek apakah [start, end) masuk dalam satu rentang jam buka pada
+// hari yang sama.
+func venueOpen(hours []models.OpeningHours, start, end time.Time, loc *time.Location) bool {
+	if len(hours) == 0 {
+		return true
+	}
+	day := start.In(loc)
+	for _, interval := range openIntervals(hours, day, loc) {
+		if !start.Before(interval.Start) && !end.After(interval.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// TimeSlot adalah rentang waktu [Start, End).
+type TimeSlot struct {
+	Start time.Time `json:"start_at"`
+	End   time.Time `json:"end_at"`
+}
+
+// openIntervals adalah jam buka venue pada tanggal day di zona loc. Venue
+// tanpa jam buka dianggap buka sepanjang hari.
+func openIntervals(hours []models.OpeningHours, day time.Time, loc *time.Location) []TimeSlot {
+	date := day.In(loc).Format(models.DateLayout)
+	if len(hours) == 0 {
+		start, _ := time.ParseInLocation(models.DateLayout, date, loc)
+		return []TimeSlot{{Start: start, End: start.AddDate(0, 0, 1)}}
+	}
+	slots := []TimeSlot{}
+	weekday := int(day.In(loc).Weekday())
+	for _, h := range hours {
+		if h.Weekday != weekday {
+			continue
+		}
+		opensAt, errOpen := models.ParseLocalDateTime(date, h.Open, loc)
+		closesAt, errClose := models.ParseLocalDateTime(date, h.Close, loc)
+		if errOpen != nil || errClose != nil || !closesAt.After(opensAt) {
+			continue
+		}
+		slots = append(slots, TimeSlot{Start: opensAt, End: closesAt})
+	}
+	return slots
+}
+
+// PitchBooking adalah event yang memakai lapangan.
+type PitchBooking struct {
+	EventID uint      `json:"event_id"`
+	Title   string    `json:"title"`
+	Start   time.Time `json:"start_at"`
+	End     time.Time `json:"end_at"`
+}
+
+// PitchDay adalah ketersediaan satu lapangan pada satu tanggal.
+type PitchDay struct {
+	Date   models.Date    `json:"date"`
+	Open   []TimeSlot     `json:"open"`
+	Free   []TimeSlot     `json:"free"`
+	Booked []PitchBooking `json:"booked"`
+}
+
+// PitchAvailability adalah ketersediaan satu lapangan per tanggal.
+type PitchAvailability struct {
+	PitchID uint       `json:"pitch_id"`
+	Name    string     `json:"name"`
+	Days    []PitchDay `json:"days"`
+}
+
+// VenueAvailability menghitung slot kosong tiap lapangan venue untuk
+// tanggal from sampai to, yaitu jam buka dikurangi event yang sudah memesan.
+// Slot yang lebih pendek dari minDuration tidak ditampilkan.
+func VenueAvailability(db *gorm.DB, venue models.Venue, pitches []models.Pitch, from, to models.Date, loc *time.Location, minDuration time.Duration) ([]PitchAvailability, error) {
+	rangeStart, rangeEnd := LocalDayRange(from, to, loc)
+	var pitchIDs []uint
+	for _, p := range pitches {
+		pitchIDs = append(pitchIDs, p.ID)
+	}
+	var events []models.Event
+	if len(pitchIDs) > 0 {
+		if err := db.Where("pitch_id IN ? AND is_cancelled = ? AND start_at < ? AND COALESCE(end_at, start_at + ? * INTERVAL '1 minute') > ?",
+			pitchIDs, false, rangeEnd, int(models.DefaultEventDuration.Minutes()), rangeStart).
+			Order("start_at ASC").Find(&events).Error; err != nil {
+			return nil, err
+		}
+	}
+	byPitch := map[uint][]models.Event{}
+	for _, e := range events {
+		byPitch[*e.PitchID] = append(byPitch[*e.PitchID], e)
+	}
+
+	result := make([]PitchAvailability, 0, len(pitches))
+	for _, p := range pitches {
+		availability := PitchAvailability{PitchID: p.ID, Name: p.Name}
+		for day := rangeStart; day.Before(rangeEnd); day = day.AddDate(0, 0, 1) {
+			dayEnd := day.AddDate(0, 0, 1)
+			pd := PitchDay{
+				Date:   models.DateOf(day),
+				Open:   openIntervals(venue.OpeningHours, day, loc),
+				Free:   []TimeSlot{},
+				Booked: []PitchBooking{},
+			}
+			var busy []TimeSlot
+			for _, e := range byPitch[p.ID] {
+				start, end := bookingRange(e)
+				if !start.Before(dayEnd) || !end.After(day) {
+					continue
+				}
+				pd.Booked = append(pd.Booked, PitchBooking{EventID: e.ID, Title: e.Title, Start: start.In(loc), End: end.In(loc)})
+				busy = append(busy, TimeSlot{Start: start, End: end})
+			}
+			for _, slot := range subtractSlots(pd.Open, busy) {
+				if slot.End.Sub(slot.Start) >= minDuration {
+					pd.Free = append(pd.Free, slot)
+				}
+			}
+			availability.Days = append(availability.Days, pd)
+		}
+		result = append(result, availability)
+	}
+	return result, nil
+}
+
+// subtractSlots mengurangi rentang busy (urut jam mulai) dari rentang open.
+func subtractSlots(open, busy []TimeSlot) []TimeSlot {
+	var free []TimeSlot
+	for _, o := range open {
+		cursor := o.Start
+		for _, b := range busy {
+			if !b.End.After(cursor) || !b.Start.Before(o.End) {
+				continue
+			}
+			if b.Start.After(cursor) {
+				free = append(free, TimeSlot{Start: cursor, End: b.Start})
+			}
+			if b.End.After(cursor) {
+				cursor = b.End
+			}
+		}
+		if cursor.Before(o.End) {
+			free = append(free, TimeSlot{Start: cursor, End: o.End})
+		}
+	}
+	return free
+}